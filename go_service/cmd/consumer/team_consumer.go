@@ -6,8 +6,11 @@ import (
 	"log"
 	"os"
 
+	"go_service/internal/database"
 	"go_service/internal/kafka"
 	"go_service/internal/redisclient"
+	"go_service/internal/repositories"
+	"go_service/internal/services"
 
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
@@ -26,12 +29,20 @@ func main() {
 		Protocol: 2, // Connection protocol
 	})
 	teamCache := redisclient.NewTeamCache(redis_client)
+	identityStore := redisclient.NewIdentityStore(redis_client)
+
+	// The activity feed is stored alongside the teams; the server migrates the schema
+	db, err := database.Open(database.CreateDSN())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	teams := repositories.NewTeamRepository(db)
 
 	consumer, err := kafka.NewConsumer(
 		os.Getenv("BOOTSTRAP_HOST"), // bootstrap servers
 		os.Getenv("KAFKA_USERNAME"), // username
 		os.Getenv("KAFKA_PASSWORD"), // password
-		"team.activity",             // topic
+		kafka.TeamTopic,             // topic
 	)
 	if err != nil {
 		log.Fatalf("Failed to create Kafka consumer: %v", err)
@@ -39,8 +50,12 @@ func main() {
 	defer consumer.Close()
 
 	// Register event handlers
+	consumer.RegisterHandler(kafka.AllEvents, createActivityHandler(teams))
 	consumer.RegisterHandler(kafka.EventMemberAdded, createMemberAddedHandler(teamCache))
 	consumer.RegisterHandler(kafka.EventMemberRemoved, createMemberRemovedHandler(teamCache))
+	consumer.RegisterHandler(kafka.EventTeamArchived, createTeamClosedHandler(teamCache))
+	consumer.RegisterHandler(kafka.EventTeamDeleted, createTeamClosedHandler(teamCache))
+	consumer.RegisterHandler(kafka.EventUserRoleChanged, createUserRoleChangedHandler(identityStore))
 
 	// Start consuming events
 	fmt.Println("Starting to consume team events...")
@@ -48,6 +63,22 @@ func main() {
 }
 
 // Factory functions that return handlers
+
+// createActivityHandler persists every team's events to its activity feed
+func createActivityHandler(teams *repositories.TeamRepository) func(kafka.TeamEvent) error {
+	return func(event kafka.TeamEvent) error {
+		// User events such as USER_ROLE_CHANGED belong to no team's feed
+		if event.TeamID == 0 {
+			return nil
+		}
+		if err := teams.RecordActivity(services.TeamActivityFromEvent(event)); err != nil {
+			log.Printf("Error recording activity for event %s on team %d: %v", event.EventType, event.TeamID, err)
+			return err
+		}
+		return nil
+	}
+}
+
 func createMemberAddedHandler(teamCache *redisclient.TeamCache) func(kafka.TeamEvent) error {
 	return func(event kafka.TeamEvent) error {
 		fmt.Printf("[%s] Member added: TeamID=%d, Member=%s, AddedBy=%s\n",
@@ -97,3 +128,45 @@ func createMemberRemovedHandler(teamCache *redisclient.TeamCache) func(kafka.Tea
 		return nil
 	}
 }
+
+// createTeamClosedHandler drops the cached roster of an archived or deleted team
+func createTeamClosedHandler(teamCache *redisclient.TeamCache) func(kafka.TeamEvent) error {
+	return func(event kafka.TeamEvent) error {
+		fmt.Printf("[%s] Team closed (%s): TeamID=%d, By=%s\n",
+			event.Timestamp, event.EventType, event.TeamID, event.PerformedBy)
+
+		if teamCache != nil {
+			ctx := context.Background()
+
+			if err := teamCache.InvalidateMembers(ctx, event.TeamID); err != nil {
+				log.Printf("Error invalidating Redis cache for team %d: %v", event.TeamID, err)
+				return err
+			}
+
+			log.Printf("Successfully invalidated Redis cache for team %d", event.TeamID)
+		}
+
+		return nil
+	}
+}
+
+func createUserRoleChangedHandler(identityStore *redisclient.IdentityStore) func(kafka.TeamEvent) error {
+	return func(event kafka.TeamEvent) error {
+		fmt.Printf("[%s] User role changed: User=%s, ChangedBy=%s\n",
+			event.Timestamp, event.TargetUserID, event.PerformedBy)
+
+		// Drop the cached identity everywhere so the new role is picked up
+		if identityStore != nil {
+			ctx := context.Background()
+
+			if err := identityStore.Invalidate(ctx, event.TargetUserID.String()); err != nil {
+				log.Printf("Error invalidating cached identity for user %s: %v", event.TargetUserID, err)
+				return err
+			}
+
+			log.Printf("Successfully invalidated cached identity for user %s", event.TargetUserID)
+		}
+
+		return nil
+	}
+}
@@ -12,9 +12,10 @@ import (
 	"go_service/internal/database"
 	"go_service/internal/kafka"
 	"go_service/internal/redisclient"
+	"go_service/internal/services"
 
 	// "go_service/internal/logger"
-	// "go_service/internal/middleware"
+	"go_service/internal/middleware"
 	"go_service/internal/router"
 
 	"github.com/gin-gonic/gin"
@@ -46,23 +47,68 @@ func main() {
 	})
 	teamCache := redisclient.NewTeamCache(redis_client)
 
+	// Background workers stop when the server shuts down
+	appCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// One user directory shared by every handler. USER_DIRECTORY=postgres reads
+	// the Users table directly so the Node user-service is not needed; the
+	// default GraphQL client is guarded by retries, a circuit breaker and a
+	// concurrency cap.
+	var users services.UserDirectory
+	if os.Getenv("USER_DIRECTORY") == "postgres" {
+		log.Println("Using Postgres user directory")
+		users = services.NewPostgresUserDirectory(db)
+	} else {
+		users = services.NewResilientUserDirectory(
+			services.NewUserService(services.UserDirectoryConfigFromEnv()),
+			services.ResilienceConfigFromEnv(),
+		)
+	}
+
+	// Identity cache: in-process LRU, plus Redis unless disabled
+	var identityStore *redisclient.IdentityStore
+	if os.Getenv("IDENTITY_CACHE_REDIS") != "false" {
+		identityStore = redisclient.NewIdentityStore(redis_client)
+	}
+	identities := services.NewIdentityCache(users, identityStore, services.IdentityCacheConfigFromEnv())
+	go identities.Listen(appCtx)
+
+	// Token revocation list shared with every instance through Redis
+	revocations := services.NewRevocationService(redisclient.NewRevocationStore(redis_client), services.RevocationConfigFromEnv())
+	go revocations.Listen(appCtx)
+
+	// Rate limits shared across instances through Redis
+	var rateLimiter *middleware.RateLimiter
+	if os.Getenv("RATE_LIMIT_ENABLED") != "false" {
+		rateLimiter = middleware.NewRateLimiter(redisclient.NewRateLimitStore(redis_client), middleware.RateLimitPoliciesFromEnv())
+	}
+
 	// Initialize Kafka producer
 	kafkaProducer, err := kafka.NewProducer(
 		os.Getenv("BOOTSTRAP_HOST"), // bootstrap servers
 		os.Getenv("KAFKA_USERNAME"), // username
 		os.Getenv("KAFKA_PASSWORD"), // password
-		"team.activity",             // topic
+		kafka.TeamTopic,             // topic
 	)
 	if err != nil {
 		log.Fatalf("Failed to create Kafka producer: %v", err)
 	}
 	defer kafkaProducer.Close()
 
+	// Events are written to the outbox with the changes they describe and
+	// published from there; OUTBOX_RELAY_ENABLED=false leaves publishing to
+	// other instances
+	if os.Getenv("OUTBOX_RELAY_ENABLED") != "false" {
+		relay := services.NewOutboxRelay(db, kafkaProducer, services.OutboxRelayConfigFromEnv())
+		go relay.Run(appCtx)
+	}
+
 	// Setup Gin router
 	r := gin.Default()
 	// middleware.SetupPrometheus(r)
 	// r.Use(middleware.LoggerMiddleware())
-	router.SetupRouter(r, db, kafkaProducer, teamCache)
+	router.SetupRouter(r, db, teamCache, users, identities, revocations, rateLimiter)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -91,6 +137,7 @@ func main() {
 	// Block until a signal is received
 	<-quit
 	log.Println("Shutting down server...")
+	stopWorkers()
 
 	// Create a deadline context for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
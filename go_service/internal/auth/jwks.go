@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrKeyNotFound is returned when no key in the set matches the token's kid
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// JWK is a single JSON Web Key as published in a JWKS document
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set document
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// VerificationKey is a decoded JWK together with the algorithm it is
+// restricted to
+type VerificationKey struct {
+	Key crypto.PublicKey
+	// Alg is the JWK's alg member; empty allows any algorithm for the key type
+	Alg string
+}
+
+// PublicKey converts the JWK into a crypto.PublicKey usable for verification
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URL(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decodeBase64URL(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve: %s", k.Crv)
+		}
+		x, err := decodeBase64URL(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := decodeBase64URL(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC point is not on curve")
+		}
+		return key, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve: %s", k.Crv)
+		}
+		x, err := decodeBase64URL(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Ed25519 key: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key length")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// ParseJWKS parses a JWKS document into a map of kid to verification key.
+// Keys that are not meant for signatures or cannot be decoded are skipped.
+func ParseJWKS(data []byte) (map[string]VerificationKey, error) {
+	var doc JWKS
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]VerificationKey, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// One bad key must not take down the others during a rotation
+			continue
+		}
+		keys[jwk.Kid] = VerificationKey{Key: key, Alg: jwk.Alg}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no usable signing keys")
+	}
+
+	return keys, nil
+}
+
+// KeySet holds the public keys of a JWKS document and reloads it from its
+// source when it gets stale or a token references an unknown kid
+type KeySet struct {
+	mu          sync.RWMutex
+	keys        map[string]VerificationKey
+	fetch       func() ([]byte, error)
+	lastRefresh time.Time
+	// lastAttempt is when a fetch last started, whether it succeeded or not
+	lastAttempt time.Time
+	// refreshes folds concurrent reloads into one fetch
+	refreshes singleflight.Group
+
+	// refreshInterval is how long a loaded document is trusted
+	refreshInterval time.Duration
+	// minRefreshInterval is the least time between two fetch attempts, so
+	// neither unknown kids nor a failing source turn every token into a fetch
+	minRefreshInterval time.Duration
+}
+
+// NewFileKeySet creates a key set backed by a JWKS file on disk
+func NewFileKeySet(path string, refreshInterval time.Duration) (*KeySet, error) {
+	return newKeySet(func() ([]byte, error) {
+		return os.ReadFile(path)
+	}, refreshInterval)
+}
+
+// NewURLKeySet creates a key set backed by a JWKS document served over HTTP
+func NewURLKeySet(url string, client *http.Client, refreshInterval time.Duration) (*KeySet, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	return newKeySet(func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status fetching JWKS: %d", resp.StatusCode)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}, refreshInterval)
+}
+
+func newKeySet(fetch func() ([]byte, error), refreshInterval time.Duration) (*KeySet, error) {
+	if refreshInterval <= 0 {
+		refreshInterval = 10 * time.Minute
+	}
+
+	ks := &KeySet{
+		fetch:              fetch,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: 30 * time.Second,
+	}
+	if err := ks.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// Refresh reloads the key set from its source
+func (ks *KeySet) Refresh() error {
+	ks.mu.Lock()
+	ks.lastAttempt = time.Now()
+	ks.mu.Unlock()
+
+	data, err := ks.fetch()
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.lastRefresh = time.Now()
+	ks.mu.Unlock()
+
+	return nil
+}
+
+// reload refreshes the set unless an attempt was made within
+// minRefreshInterval, reporting whether it fetched. Concurrent callers wait
+// on a single fetch.
+func (ks *KeySet) reload() (bool, error) {
+	fetched, err, _ := ks.refreshes.Do("", func() (interface{}, error) {
+		ks.mu.RLock()
+		due := time.Since(ks.lastAttempt) >= ks.minRefreshInterval
+		ks.mu.RUnlock()
+		if !due {
+			return false, nil
+		}
+		return true, ks.Refresh()
+	})
+	return fetched.(bool), err
+}
+
+// Key returns the key registered under kid, reloading the set if it is stale
+// or the kid is unknown so newly rotated keys are picked up without a restart.
+// While reloads are throttled an unknown kid fails without a fetch.
+func (ks *KeySet) Key(kid string) (VerificationKey, error) {
+	ks.mu.RLock()
+	key, ok := ks.keys[kid]
+	age := time.Since(ks.lastRefresh)
+	ks.mu.RUnlock()
+
+	if ok && age < ks.refreshInterval {
+		return key, nil
+	}
+
+	if _, err := ks.reload(); err != nil {
+		// Keep serving the last known keys if the source is unavailable
+		if ok {
+			return key, nil
+		}
+		return VerificationKey{}, err
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if key, ok := ks.keys[kid]; ok {
+		return key, nil
+	}
+
+	return VerificationKey{}, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+}
+
+// Keys returns every active key, used when a token carries no kid. A stale
+// set is reloaded first, subject to the same throttle as Key.
+func (ks *KeySet) Keys() []VerificationKey {
+	ks.mu.RLock()
+	stale := time.Since(ks.lastRefresh) >= ks.refreshInterval
+	ks.mu.RUnlock()
+
+	if stale {
+		// On failure the last known keys are served
+		ks.reload()
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]VerificationKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty value")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -1,9 +1,15 @@
 package auth
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"errors"
 	"fmt"
+	"log"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -15,33 +21,213 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// ValidateToken validates JWT token from Node.js service
-func ValidateToken(tokenString string) (*Claims, error) {
-	// Use ACCESS_TOKEN_SECRET to match Node.js service
-	secret := os.Getenv("ACCESS_TOKEN_SECRET")
-	if secret == "" {
-		return nil, errors.New("ACCESS_TOKEN_SECRET not set")
+// Config controls which tokens the Validator accepts
+type Config struct {
+	// HMACSecret verifies HS* tokens minted by the Node.js service
+	HMACSecret string
+	// JWKSFile and JWKSURL point at a JWKS document with the asymmetric keys
+	JWKSFile string
+	JWKSURL  string
+	// JWKSRefreshInterval is how long a loaded JWKS document is trusted
+	JWKSRefreshInterval time.Duration
+	// Issuer and Audience are enforced on JWKS-signed tokens when set; the
+	// Node.js HS* tokens carry neither
+	Issuer   string
+	Audience string
+	// ClockSkew is the leeway applied to exp, nbf and iat
+	ClockSkew time.Duration
+}
+
+// ConfigFromEnv builds a Config from environment variables
+func ConfigFromEnv() Config {
+	cfg := Config{
+		// Use ACCESS_TOKEN_SECRET to match Node.js service
+		HMACSecret:          os.Getenv("ACCESS_TOKEN_SECRET"),
+		JWKSFile:            os.Getenv("JWT_JWKS_FILE"),
+		JWKSURL:             os.Getenv("JWT_JWKS_URL"),
+		JWKSRefreshInterval: 10 * time.Minute,
+		Issuer:              os.Getenv("JWT_ISSUER"),
+		Audience:            os.Getenv("JWT_AUDIENCE"),
+		ClockSkew:           30 * time.Second,
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	if v := os.Getenv("JWT_CLOCK_SKEW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.ClockSkew = d
+		} else {
+			log.Printf("Invalid JWT_CLOCK_SKEW %q, using %s", v, cfg.ClockSkew)
 		}
-		return []byte(secret), nil
-	})
+	}
+	if v := os.Getenv("JWT_JWKS_REFRESH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.JWKSRefreshInterval = d
+		} else {
+			log.Printf("Invalid JWT_JWKS_REFRESH_INTERVAL %q, using %s", v, cfg.JWKSRefreshInterval)
+		}
+	}
+
+	return cfg
+}
+
+// Validator verifies access tokens against an HMAC secret and/or a JWKS
+type Validator struct {
+	cfg    Config
+	keys   *KeySet
+	parser *jwt.Parser
+	// issued checks the issuer and audience of JWKS-signed tokens, nil when
+	// neither is configured
+	issued *jwt.Validator
+}
+
+// NewValidator creates a validator, loading the JWKS if one is configured
+func NewValidator(cfg Config) (*Validator, error) {
+	v := &Validator{cfg: cfg}
+
+	var err error
+	switch {
+	case cfg.JWKSFile != "":
+		v.keys, err = NewFileKeySet(cfg.JWKSFile, cfg.JWKSRefreshInterval)
+	case cfg.JWKSURL != "":
+		v.keys, err = NewURLKeySet(cfg.JWKSURL, nil, cfg.JWKSRefreshInterval)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if v.keys == nil && cfg.HMACSecret == "" {
+		return nil, errors.New("no token verification keys configured: set ACCESS_TOKEN_SECRET or JWT_JWKS_FILE/JWT_JWKS_URL")
+	}
+
+	methods := []string{}
+	if cfg.HMACSecret != "" {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if v.keys != nil {
+		methods = append(methods, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA")
+	}
+
+	v.parser = jwt.NewParser(
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(cfg.ClockSkew),
+		jwt.WithIssuedAt(),
+	)
 
+	issued := []jwt.ParserOption{jwt.WithLeeway(cfg.ClockSkew)}
+	if cfg.Issuer != "" {
+		issued = append(issued, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		issued = append(issued, jwt.WithAudience(cfg.Audience))
+	}
+	if len(issued) > 1 {
+		v.issued = jwt.NewValidator(issued...)
+	}
+
+	return v, nil
+}
+
+// Validate parses and verifies a token, returning its claims
+func (v *Validator) Validate(tokenString string) (*Claims, error) {
+	token, err := v.parser.ParseWithClaims(tokenString, &Claims{}, v.keyFunc)
 	if err != nil {
-		fmt.Printf("DEBUG: JWT parse error: %v\n", err)
 		return nil, err
 	}
 
 	claims, ok := token.Claims.(*Claims)
 	if !ok || !token.Valid {
-		fmt.Printf("DEBUG: Invalid token claims or token not valid\n")
 		return nil, errors.New("invalid token")
 	}
 
-	fmt.Printf("DEBUG: Successfully parsed token for user: %s\n", claims.UserID)
+	if _, hmac := token.Method.(*jwt.SigningMethodHMAC); !hmac && v.issued != nil {
+		if err := v.issued.Validate(claims); err != nil {
+			return nil, err
+		}
+	}
+
+	if claims.UserID == uuid.Nil {
+		// Tokens from other issuers may carry the user in sub instead of userId
+		userID, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			return nil, errors.New("token has no user identifier")
+		}
+		claims.UserID = userID
+	}
+
 	return claims, nil
 }
+
+// keyFunc picks the verification key for a token based on its alg and kid
+func (v *Validator) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if v.cfg.HMACSecret == "" {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(v.cfg.HMACSecret), nil
+	}
+
+	if v.keys == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		key, err := v.keys.Key(kid)
+		if err != nil {
+			return nil, err
+		}
+		if !keyMatchesMethod(key, token.Method) {
+			return nil, fmt.Errorf("key %q cannot verify %v tokens", kid, token.Header["alg"])
+		}
+		return key.Key, nil
+	}
+
+	// Without a kid, try every active key that can verify the token
+	set := jwt.VerificationKeySet{}
+	for _, key := range v.keys.Keys() {
+		if keyMatchesMethod(key, token.Method) {
+			set.Keys = append(set.Keys, key.Key)
+		}
+	}
+	if len(set.Keys) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return set, nil
+}
+
+// keyMatchesMethod guards against algorithm confusion, both between key types
+// and with the alg a JWK is pinned to
+func keyMatchesMethod(key VerificationKey, method jwt.SigningMethod) bool {
+	if key.Alg != "" && key.Alg != method.Alg() {
+		return false
+	}
+
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := key.Key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		_, ok := key.Key.(*ecdsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodEd25519:
+		_, ok := key.Key.(ed25519.PublicKey)
+		return ok
+	}
+	return false
+}
+
+var (
+	defaultValidator     *Validator
+	defaultValidatorErr  error
+	defaultValidatorOnce sync.Once
+)
+
+// ValidateToken validates JWT token from Node.js service or any issuer in the configured JWKS
+func ValidateToken(tokenString string) (*Claims, error) {
+	defaultValidatorOnce.Do(func() {
+		defaultValidator, defaultValidatorErr = NewValidator(ConfigFromEnv())
+	})
+	if defaultValidatorErr != nil {
+		return nil, defaultValidatorErr
+	}
+
+	return defaultValidator.Validate(tokenString)
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func jwkFor(t *testing.T, kid string, key crypto.PublicKey) JWK {
+	t.Helper()
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kid: kid, Kty: "RSA", Use: "sig", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return JWK{Kid: kid, Kty: "EC", Crv: "P-256", X: b64(k.X.FillBytes(make([]byte, 32))), Y: b64(k.Y.FillBytes(make([]byte, 32)))}
+	case ed25519.PublicKey:
+		return JWK{Kid: kid, Kty: "OKP", Crv: "Ed25519", X: b64(k)}
+	}
+	t.Fatalf("unsupported key %T", key)
+	return JWK{}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return s
+}
+
+func TestValidatorJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rotatedKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	pinned := jwkFor(t, "rsa-pinned", &rsaKey.PublicKey)
+	pinned.Alg = "RS256"
+
+	var doc atomic.Value
+	doc.Store(JWKS{Keys: []JWK{
+		jwkFor(t, "rsa-1", &rsaKey.PublicKey),
+		jwkFor(t, "ec-1", &ecKey.PublicKey),
+		jwkFor(t, "ed-1", edPub),
+		pinned,
+	}})
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(doc.Load())
+	}))
+	defer stub.Close()
+
+	v, err := NewValidator(Config{
+		HMACSecret: "node-secret",
+		JWKSURL:    stub.URL,
+		Issuer:     "https://issuer.test",
+		Audience:   "go_service",
+		ClockSkew:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewValidator: %v", err)
+	}
+	v.keys.minRefreshInterval = 0
+
+	userID := uuid.New()
+	now := time.Now()
+	claims := func(mod func(*Claims)) Claims {
+		c := Claims{UserID: userID, RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://issuer.test",
+			Audience:  jwt.ClaimStrings{"go_service"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		}}
+		if mod != nil {
+			mod(&c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{
+		{"RS256", func() string { return sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(nil)) }, false},
+		{"ES256", func() string { return sign(t, jwt.SigningMethodES256, "ec-1", ecKey, claims(nil)) }, false},
+		{"EdDSA", func() string { return sign(t, jwt.SigningMethodEdDSA, "ed-1", edKey, claims(nil)) }, false},
+		{"no kid", func() string { return sign(t, jwt.SigningMethodRS256, "", rsaKey, claims(nil)) }, false},
+		{"HS256 from node", func() string { return sign(t, jwt.SigningMethodHS256, "", []byte("node-secret"), claims(nil)) }, false},
+		{"HS256 without issuer or audience", func() string {
+			return sign(t, jwt.SigningMethodHS256, "", []byte("node-secret"), claims(func(c *Claims) { c.Issuer, c.Audience = "", nil }))
+		}, false},
+		{"RS256 without issuer", func() string {
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c *Claims) { c.Issuer = "" }))
+		}, true},
+		{"unknown kid", func() string { return sign(t, jwt.SigningMethodRS256, "nope", rsaKey, claims(nil)) }, true},
+		{"kid of wrong key type", func() string { return sign(t, jwt.SigningMethodES256, "rsa-1", ecKey, claims(nil)) }, true},
+		{"alg the key is pinned to", func() string { return sign(t, jwt.SigningMethodRS256, "rsa-pinned", rsaKey, claims(nil)) }, false},
+		{"alg other than the key's", func() string { return sign(t, jwt.SigningMethodPS256, "rsa-pinned", rsaKey, claims(nil)) }, true},
+		{"wrong issuer", func() string {
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c *Claims) { c.Issuer = "evil" }))
+		}, true},
+		{"wrong audience", func() string {
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c *Claims) { c.Audience = jwt.ClaimStrings{"other"} }))
+		}, true},
+		{"nbf within skew", func() string {
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c *Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(30 * time.Second)) }))
+		}, false},
+		{"nbf beyond skew", func() string {
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c *Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(5 * time.Minute)) }))
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Validate(tt.token())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.UserID != userID {
+				t.Fatalf("user id = %s, want %s", got.UserID, userID)
+			}
+		})
+	}
+
+	// Rotation: publish a new key next to the old one and both must validate
+	doc.Store(JWKS{Keys: []JWK{
+		jwkFor(t, "rsa-1", &rsaKey.PublicKey),
+		jwkFor(t, "rsa-2", &rotatedKey.PublicKey),
+	}})
+	for kid, key := range map[string]*rsa.PrivateKey{"rsa-1": rsaKey, "rsa-2": rotatedKey} {
+		if _, err := v.Validate(sign(t, jwt.SigningMethodRS256, kid, key, claims(nil))); err != nil {
+			t.Fatalf("rotated key %s rejected: %v", kid, err)
+		}
+	}
+}
+
+// TestKeySetCoalescesRefresh checks that tokens arriving together with an
+// unknown kid trigger one JWKS fetch between them
+func TestKeySetCoalescesRefresh(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	doc, _ := json.Marshal(JWKS{Keys: []JWK{jwkFor(t, "rsa-1", &rsaKey.PublicKey)}})
+
+	var fetches int32
+	release := make(chan struct{})
+	ks, err := newKeySet(func() ([]byte, error) {
+		// The first fetch is the initial load; later ones wait to overlap
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			<-release
+		}
+		return doc, nil
+	}, time.Hour)
+	if err != nil {
+		t.Fatalf("newKeySet: %v", err)
+	}
+	ks.minRefreshInterval = 0
+
+	const callers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ks.Key("rsa-2")
+		}()
+	}
+	for atomic.LoadInt32(&fetches) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&fetches) - 1; got != 1 {
+		t.Errorf("%d callers caused %d refreshes, want 1", callers, got)
+	}
+}
+
+// TestKeySetThrottlesFailedRefresh checks that while the JWKS source is down
+// unknown kids fail without a fetch each, and known keys keep being served
+func TestKeySetThrottlesFailedRefresh(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	doc, _ := json.Marshal(JWKS{Keys: []JWK{jwkFor(t, "rsa-1", &rsaKey.PublicKey)}})
+
+	var fetches int32
+	ks, err := newKeySet(func() ([]byte, error) {
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			return nil, errors.New("source down")
+		}
+		return doc, nil
+	}, time.Hour)
+	if err != nil {
+		t.Fatalf("newKeySet: %v", err)
+	}
+	ks.minRefreshInterval = time.Hour
+	// Let the first lookup through, as if the throttle window had passed
+	ks.lastAttempt = time.Time{}
+
+	for i := 0; i < 10; i++ {
+		if _, err := ks.Key("rsa-2"); err == nil {
+			t.Fatal("unknown kid resolved")
+		}
+	}
+	if got := atomic.LoadInt32(&fetches) - 1; got != 1 {
+		t.Errorf("10 lookups during an outage caused %d fetches, want 1", got)
+	}
+
+	// A stale set keeps serving its keys without fetching again
+	ks.lastRefresh = time.Time{}
+	if _, err := ks.Key("rsa-1"); err != nil {
+		t.Errorf("known key rejected during an outage: %v", err)
+	}
+	if got := atomic.LoadInt32(&fetches) - 1; got != 1 {
+		t.Errorf("stale lookup fetched again, %d fetches", got)
+	}
+}
+
+// TestKeySetKeysRefreshesStale checks that the kid-less path picks up a
+// rotated document once the set is stale
+func TestKeySetKeysRefreshesStale(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var doc atomic.Value
+	doc.Store(JWKS{Keys: []JWK{jwkFor(t, "rsa-1", &oldKey.PublicKey)}})
+	ks, err := newKeySet(func() ([]byte, error) {
+		return json.Marshal(doc.Load())
+	}, time.Hour)
+	if err != nil {
+		t.Fatalf("newKeySet: %v", err)
+	}
+	ks.minRefreshInterval = 0
+
+	doc.Store(JWKS{Keys: []JWK{jwkFor(t, "rsa-2", &newKey.PublicKey)}})
+	if keys := ks.Keys(); !keys[0].Key.(*rsa.PublicKey).Equal(&oldKey.PublicKey) {
+		t.Fatal("fresh set was reloaded")
+	}
+
+	ks.lastRefresh = time.Time{}
+	keys := ks.Keys()
+	if len(keys) != 1 || !keys[0].Key.(*rsa.PublicKey).Equal(&newKey.PublicKey) {
+		t.Fatal("stale set was not reloaded")
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+// Scopes that can be granted to personal access tokens and service accounts
+const (
+	ScopeTeamsRead   = "teams:read"
+	ScopeTeamsWrite  = "teams:write"
+	ScopeAssetsRead  = "assets:read"
+	ScopeAssetsWrite = "assets:write"
+	ScopeImportUsers = "import:users"
+	ScopeAdmin       = "admin"
+)
+
+// KnownScopes lists every scope a token may carry
+var KnownScopes = []string{
+	ScopeTeamsRead,
+	ScopeTeamsWrite,
+	ScopeAssetsRead,
+	ScopeAssetsWrite,
+	ScopeImportUsers,
+	ScopeAdmin,
+}
+
+// IsKnownScope reports whether scope is one of KnownScopes
+func IsKnownScope(scope string) bool {
+	for _, known := range KnownScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether granted contains scope
+func HasScope(granted []string, scope string) bool {
+	for _, s := range granted {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
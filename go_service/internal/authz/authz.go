@@ -0,0 +1,203 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Subject is the caller an authorization decision is made for
+type Subject struct {
+	UserID uuid.UUID
+	Role   string
+}
+
+// IsManager reports whether the subject holds the global MANAGER role
+func (s Subject) IsManager() bool {
+	return strings.EqualFold(s.Role, "MANAGER")
+}
+
+// Action is something a subject wants to do to a resource
+type Action string
+
+const (
+	ActionTeamCreate        Action = "team:create"
+	ActionTeamView          Action = "team:view"
+	ActionTeamUpdate        Action = "team:update"
+	ActionTeamArchive       Action = "team:archive"
+	ActionTeamDelete        Action = "team:delete"
+	ActionTeamManageMembers Action = "team:manage_members"
+	ActionTeamManageLeaders Action = "team:manage_leaders"
+	ActionTeamViewAssets    Action = "team:view_assets"
+	// ActionTeamViewDetails covers a team's details, quotas, history and
+	// activity, which only its members and managers see; ActionTeamView
+	// covers the roster any signed-in user may list
+	ActionTeamViewDetails Action = "team:view_details"
+	// ActionTeamCreateFolder covers creating a folder owned by the team
+	ActionTeamCreateFolder Action = "team:create_folder"
+	// ActionTeamManageHierarchy covers attaching a team to a parent team and detaching it
+	ActionTeamManageHierarchy Action = "team:manage_hierarchy"
+	// ActionTeamTransferOwnership covers handing the team to a new owner
+	ActionTeamTransferOwnership Action = "team:transfer_ownership"
+	// ActionTeamManageQuota covers overriding the team's quota limits
+	ActionTeamManageQuota Action = "team:manage_quota"
+	// ActionTeamManageFields covers defining and removing custom team fields
+	ActionTeamManageFields Action = "team:manage_fields"
+	// ActionTeamExport covers exporting every team as CSV
+	ActionTeamExport Action = "team:export"
+
+	ActionUserViewAssets Action = "user:view_assets"
+	// ActionUserViewMemberships covers the teams a user is or was on
+	ActionUserViewMemberships Action = "user:view_memberships"
+
+	// ActionSystemAdmin covers identity, token and service-account administration
+	ActionSystemAdmin Action = "system:admin"
+
+	ActionFolderRead   Action = "folder:read"
+	ActionFolderWrite  Action = "folder:write"
+	ActionFolderDelete Action = "folder:delete"
+	ActionFolderShare  Action = "folder:share"
+	// ActionFolderCreateNote covers adding a note to a folder, which team
+	// members may do without being able to change the folder
+	ActionFolderCreateNote Action = "folder:create_note"
+
+	ActionNoteRead   Action = "note:read"
+	ActionNoteWrite  Action = "note:write"
+	ActionNoteDelete Action = "note:delete"
+	ActionNoteShare  Action = "note:share"
+)
+
+// ResourceKind identifies the type of a Resource
+type ResourceKind string
+
+const (
+	KindSystem ResourceKind = "system"
+	KindTeam   ResourceKind = "team"
+	KindUser   ResourceKind = "user"
+	KindFolder ResourceKind = "folder"
+	KindNote   ResourceKind = "note"
+)
+
+// Resource is the target of an action
+type Resource struct {
+	Kind     ResourceKind
+	TeamID   int
+	UserID   uuid.UUID
+	FolderID uuid.UUID
+	NoteID   uuid.UUID
+	OwnerID  uuid.UUID
+}
+
+// System is the resource for actions not tied to an existing object, such as creating a team
+func System() Resource {
+	return Resource{Kind: KindSystem}
+}
+
+// Team returns the resource for a team
+func Team(teamID int) Resource {
+	return Resource{Kind: KindTeam, TeamID: teamID}
+}
+
+// User returns the resource for a user's account and assets
+func User(userID uuid.UUID) Resource {
+	return Resource{Kind: KindUser, UserID: userID}
+}
+
+// Folder returns the resource for a folder. A team folder has no owner: its
+// creator reaches it through their roster role like everyone else.
+func Folder(folder models.Folder) Resource {
+	if folder.TeamID != nil {
+		return Resource{Kind: KindFolder, FolderID: folder.ID, TeamID: *folder.TeamID}
+	}
+	return Resource{Kind: KindFolder, FolderID: folder.ID, OwnerID: folder.OwnerID}
+}
+
+// Note returns the resource for a note; shares on its folder apply to it too
+func Note(note models.Note) Resource {
+	return Resource{Kind: KindNote, NoteID: note.ID, FolderID: note.FolderID, OwnerID: note.OwnerID}
+}
+
+func (r Resource) String() string {
+	switch r.Kind {
+	case KindTeam:
+		return fmt.Sprintf("team %d", r.TeamID)
+	case KindUser:
+		return fmt.Sprintf("user %s", r.UserID)
+	case KindFolder:
+		return fmt.Sprintf("folder %s", r.FolderID)
+	case KindNote:
+		return fmt.Sprintf("note %s", r.NoteID)
+	}
+	return string(r.Kind)
+}
+
+// Grant describes why an action was allowed
+type Grant struct {
+	// Via names the rule that allowed the action, e.g. "owner" or "folder_share"
+	Via         string
+	AccessLevel models.AccessLevel
+	SharedByID  uuid.UUID
+	// ShareTeamID is the team a share reached the subject through, 0 for a direct share
+	ShareTeamID int
+	// TeamRole is the roster role that granted a team_role decision
+	TeamRole models.RosterRole
+}
+
+// Decision is the outcome of Can
+type Decision struct {
+	Allowed bool
+	// Reason explains a denial
+	Reason string
+	// Grant explains an approval
+	Grant Grant
+	// Err is set when the decision could not be made, e.g. the store failed
+	Err error
+}
+
+func allow(grant Grant) Decision {
+	return Decision{Allowed: true, Grant: grant}
+}
+
+func deny(reason string) Decision {
+	return Decision{Reason: reason}
+}
+
+func failed(err error) Decision {
+	return Decision{Reason: "authorization check failed", Err: err}
+}
+
+// Engine evaluates the policy table against a Store
+type Engine struct {
+	store    Store
+	policies map[Action]Policy
+}
+
+// NewEngine creates an engine using the default policy table
+func NewEngine(store Store) *Engine {
+	return &Engine{store: store, policies: DefaultPolicies()}
+}
+
+// NewEngineWithPolicies creates an engine with a custom policy table
+func NewEngineWithPolicies(store Store, policies map[Action]Policy) *Engine {
+	return &Engine{store: store, policies: policies}
+}
+
+// Can decides whether subject may perform action on resource
+func (e *Engine) Can(subject Subject, action Action, resource Resource) Decision {
+	policy, ok := e.policies[action]
+	if !ok {
+		return deny(fmt.Sprintf("no policy for action %s", action))
+	}
+
+	for _, rule := range policy.AnyOf {
+		decision := rule(e.store, subject, resource)
+		if decision.Err != nil || decision.Allowed {
+			return decision
+		}
+	}
+
+	return deny(policy.Denial)
+}
@@ -0,0 +1,275 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type fakeStore struct {
+	members      map[int][]uuid.UUID
+	leaders      map[int][]uuid.UUID
+	owners       map[int]uuid.UUID
+	viewers      map[int][]uuid.UUID
+	parents      map[int]int
+	folderTeams  map[uuid.UUID]int
+	folderShares map[uuid.UUID]map[uuid.UUID]models.AccessLevel
+	noteShares   map[uuid.UUID]map[uuid.UUID]models.AccessLevel
+	err          error
+}
+
+func (s *fakeStore) TeamRole(teamID int, userID uuid.UUID) (models.RosterRole, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	if owner, ok := s.owners[teamID]; ok && owner == userID {
+		return models.RosterRoleOwner, nil
+	}
+	roles := map[models.RosterRole][]uuid.UUID{
+		models.RosterRoleLeader: s.leaders[teamID],
+		models.RosterRoleMember: s.members[teamID],
+		models.RosterRoleViewer: s.viewers[teamID],
+	}
+	for role, ids := range roles {
+		for _, id := range ids {
+			if id == userID {
+				return role, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func (s *fakeStore) IsAncestorTeamLeader(teamID int, userID uuid.UUID) (bool, error) {
+	for parent, ok := s.parents[teamID]; ok; parent, ok = s.parents[parent] {
+		if role, err := s.TeamRole(parent, userID); err != nil || role.IsLeader() {
+			return role.IsLeader(), err
+		}
+	}
+	return false, s.err
+}
+
+func (s *fakeStore) FolderTeam(folderID uuid.UUID) (int, error) {
+	return s.folderTeams[folderID], s.err
+}
+
+func (s *fakeStore) FolderShare(folderID, userID uuid.UUID) (*models.FolderShare, error) {
+	if level, ok := s.folderShares[folderID][userID]; ok {
+		return &models.FolderShare{FolderID: folderID, UserID: &userID, AccessLevel: level}, nil
+	}
+	return nil, s.err
+}
+
+func (s *fakeStore) NoteShare(noteID, userID uuid.UUID) (*models.NoteShare, error) {
+	if level, ok := s.noteShares[noteID][userID]; ok {
+		return &models.NoteShare{NoteID: noteID, UserID: &userID, AccessLevel: level}, nil
+	}
+	return nil, s.err
+}
+
+func TestPolicyTable(t *testing.T) {
+	var (
+		owner    = uuid.New()
+		leader   = uuid.New()
+		reader   = uuid.New()
+		writer   = uuid.New()
+		noteUser = uuid.New()
+		stranger = uuid.New()
+		// headOfDept leads team 1, the parent of team 7
+		headOfDept = uuid.New()
+		teamOwner  = uuid.New()
+		observer   = uuid.New()
+		teammate   = uuid.New()
+	)
+
+	folder := models.Folder{ID: uuid.New(), OwnerID: owner}
+	note := models.Note{ID: uuid.New(), FolderID: folder.ID, OwnerID: owner}
+	squad := 7
+	teamFolder := models.Folder{ID: uuid.New(), OwnerID: stranger, TeamID: &squad}
+	teamNote := models.Note{ID: uuid.New(), FolderID: teamFolder.ID, OwnerID: teammate}
+
+	store := &fakeStore{
+		members: map[int][]uuid.UUID{7: {reader, teammate}},
+		leaders: map[int][]uuid.UUID{7: {leader}, 1: {headOfDept}},
+		owners:  map[int]uuid.UUID{7: teamOwner},
+		viewers: map[int][]uuid.UUID{7: {observer}},
+		// 1 is a department, 7 a squad in it and 9 a sub-squad of 7
+		parents:     map[int]int{7: 1, 9: 7},
+		folderTeams: map[uuid.UUID]int{teamFolder.ID: squad},
+		folderShares: map[uuid.UUID]map[uuid.UUID]models.AccessLevel{
+			folder.ID: {reader: models.Read, writer: models.Write},
+		},
+		noteShares: map[uuid.UUID]map[uuid.UUID]models.AccessLevel{
+			note.ID: {noteUser: models.Write},
+		},
+	}
+	engine := NewEngine(store)
+
+	manager := func(id uuid.UUID) Subject { return Subject{UserID: id, Role: "MANAGER"} }
+	member := func(id uuid.UUID) Subject { return Subject{UserID: id, Role: "MEMBER"} }
+
+	tests := []struct {
+		name     string
+		subject  Subject
+		action   Action
+		resource Resource
+		allowed  bool
+		via      string
+	}{
+		{"manager creates team", manager(stranger), ActionTeamCreate, System(), true, "role"},
+		{"any user lists team members", member(stranger), ActionTeamView, Team(7), true, "authenticated"},
+		{"member views team", member(reader), ActionTeamViewDetails, Team(7), true, "team_member"},
+		{"leader views team", member(leader), ActionTeamViewDetails, Team(7), true, "team_member"},
+		{"manager views any team", manager(stranger), ActionTeamViewDetails, Team(7), true, "role"},
+		{"stranger cannot view team", member(stranger), ActionTeamViewDetails, Team(7), false, ""},
+		{"leader renames team", member(leader), ActionTeamUpdate, Team(7), true, "team_role"},
+		{"member cannot rename team", member(reader), ActionTeamUpdate, Team(7), false, ""},
+		{"manager archives any team", manager(stranger), ActionTeamArchive, Team(7), true, "role"},
+		{"leader cannot delete team", member(leader), ActionTeamDelete, Team(7), false, ""},
+		{"role is case-insensitive", Subject{UserID: stranger, Role: "manager"}, ActionTeamCreate, System(), true, "role"},
+		{"member cannot create team", member(stranger), ActionTeamCreate, System(), false, ""},
+		{"leader manages members", member(leader), ActionTeamManageMembers, Team(7), true, "team_role"},
+		{"leader of another team cannot", member(leader), ActionTeamManageMembers, Team(8), false, ""},
+		{"manager who is not leader cannot manage members", manager(stranger), ActionTeamManageMembers, Team(7), false, ""},
+		{"managing leaders needs role and leadership", manager(leader), ActionTeamManageLeaders, Team(7), true, "team_leader"},
+		{"member leader cannot manage leaders", member(leader), ActionTeamManageLeaders, Team(7), false, ""},
+		{"viewer views team", member(observer), ActionTeamViewDetails, Team(7), true, "team_member"},
+		{"viewer cannot rename team", member(observer), ActionTeamUpdate, Team(7), false, ""},
+		{"viewer cannot manage members", member(observer), ActionTeamManageMembers, Team(7), false, ""},
+		{"owner manages members", member(teamOwner), ActionTeamManageMembers, Team(7), true, "team_role"},
+		{"owner manages roles without the MANAGER role", member(teamOwner), ActionTeamManageLeaders, Team(7), true, "team_role"},
+		{"manager leading parent manages members", manager(headOfDept), ActionTeamManageMembers, Team(7), true, "parent_team_leader"},
+		{"leadership carries down every level", manager(headOfDept), ActionTeamUpdate, Team(9), true, "parent_team_leader"},
+		{"manager leading parent manages leaders", manager(headOfDept), ActionTeamManageLeaders, Team(9), true, "parent_team_leader"},
+		{"member leading parent inherits nothing", member(headOfDept), ActionTeamManageMembers, Team(7), false, ""},
+		{"leadership does not carry up", manager(leader), ActionTeamManageMembers, Team(1), false, ""},
+		{"owner transfers ownership", member(teamOwner), ActionTeamTransferOwnership, Team(7), true, "team_role"},
+		{"leader cannot transfer ownership", member(leader), ActionTeamTransferOwnership, Team(7), false, ""},
+		{"manager appoints an owner to any team", manager(stranger), ActionTeamTransferOwnership, Team(7), true, "role"},
+		{"manager reorganizes teams", manager(stranger), ActionTeamManageHierarchy, Team(7), true, "role"},
+		{"leader cannot reorganize teams", member(leader), ActionTeamManageHierarchy, Team(7), false, ""},
+		{"manager overrides team quotas", manager(stranger), ActionTeamManageQuota, Team(7), true, "role"},
+		{"owner cannot override team quotas", member(teamOwner), ActionTeamManageQuota, Team(7), false, ""},
+		{"manager defines team fields", manager(stranger), ActionTeamManageFields, System(), true, "role"},
+		{"member cannot define team fields", member(teamOwner), ActionTeamManageFields, System(), false, ""},
+		{"manager exports teams", manager(stranger), ActionTeamExport, System(), true, "role"},
+		{"member cannot export teams", member(teamOwner), ActionTeamExport, System(), false, ""},
+		{"manager views team assets", manager(stranger), ActionTeamViewAssets, Team(7), true, "role"},
+		{"member cannot view user assets", member(leader), ActionUserViewAssets, User(owner), false, ""},
+		{"user views own memberships", member(reader), ActionUserViewMemberships, User(reader), true, "self"},
+		{"member cannot view others' memberships", member(reader), ActionUserViewMemberships, User(leader), false, ""},
+		{"manager views any memberships", manager(stranger), ActionUserViewMemberships, User(reader), true, "role"},
+		{"manager administers", manager(stranger), ActionSystemAdmin, System(), true, "role"},
+		{"member cannot administer", member(stranger), ActionSystemAdmin, System(), false, ""},
+
+		{"owner reads folder", member(owner), ActionFolderRead, Folder(folder), true, "owner"},
+		{"reader reads folder", member(reader), ActionFolderRead, Folder(folder), true, "folder_share"},
+		{"reader cannot write folder", member(reader), ActionFolderWrite, Folder(folder), false, ""},
+		{"writer writes folder", member(writer), ActionFolderWrite, Folder(folder), true, "folder_share"},
+		{"writer cannot delete folder", member(writer), ActionFolderDelete, Folder(folder), false, ""},
+		{"writer cannot share folder", member(writer), ActionFolderShare, Folder(folder), false, ""},
+		{"stranger cannot read folder", manager(stranger), ActionFolderRead, Folder(folder), false, ""},
+
+		{"note share grants read", member(noteUser), ActionNoteRead, Note(note), true, "note_share"},
+		{"note share grants write", member(noteUser), ActionNoteWrite, Note(note), true, "note_share"},
+		{"note share does not grant delete", member(noteUser), ActionNoteDelete, Note(note), false, ""},
+		{"folder share is inherited by notes", member(reader), ActionNoteRead, Note(note), true, "folder_share"},
+		{"folder read share cannot write notes", member(reader), ActionNoteWrite, Note(note), false, ""},
+		{"folder write share deletes notes", member(writer), ActionNoteDelete, Note(note), true, "folder_share"},
+		{"only owner shares note", member(writer), ActionNoteShare, Note(note), false, ""},
+		{"stranger cannot read note", member(stranger), ActionNoteRead, Note(note), false, ""},
+
+		{"member creates team folder", member(reader), ActionTeamCreateFolder, Team(7), true, "team_role"},
+		{"viewer cannot create team folder", member(observer), ActionTeamCreateFolder, Team(7), false, ""},
+		{"viewer reads team folder", member(observer), ActionFolderRead, Folder(teamFolder), true, "team_folder"},
+		{"viewer cannot write team folder", member(observer), ActionFolderWrite, Folder(teamFolder), false, ""},
+		{"member cannot write team folder", member(reader), ActionFolderWrite, Folder(teamFolder), false, ""},
+		{"leader writes team folder", member(leader), ActionFolderWrite, Folder(teamFolder), true, "team_folder"},
+		{"member adds notes to team folder", member(reader), ActionFolderCreateNote, Folder(teamFolder), true, "team_folder"},
+		{"viewer cannot add notes to team folder", member(observer), ActionFolderCreateNote, Folder(teamFolder), false, ""},
+		{"member cannot delete team folder", member(reader), ActionFolderDelete, Folder(teamFolder), false, ""},
+		{"leader deletes team folder", member(leader), ActionFolderDelete, Folder(teamFolder), true, "team_folder"},
+		{"owner shares team folder", member(teamOwner), ActionFolderShare, Folder(teamFolder), true, "team_folder"},
+		{"creator off the team has no access", member(stranger), ActionFolderRead, Folder(teamFolder), false, ""},
+		{"team access reaches notes", member(observer), ActionNoteRead, Note(teamNote), true, "team_folder"},
+		{"member cannot edit a teammate's note", member(reader), ActionNoteWrite, Note(teamNote), false, ""},
+		{"member cannot delete a teammate's note", member(reader), ActionNoteDelete, Note(teamNote), false, ""},
+		{"member edits own team note", member(teammate), ActionNoteWrite, Note(teamNote), true, "owner"},
+		{"leader edits a teammate's note", member(leader), ActionNoteWrite, Note(teamNote), true, "team_folder"},
+		{"leader deletes a teammate's note", member(leader), ActionNoteDelete, Note(teamNote), true, "team_folder"},
+		{"personal folders get no team access", member(leader), ActionFolderRead, Folder(folder), false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Can(tt.subject, tt.action, tt.resource)
+			if decision.Err != nil {
+				t.Fatalf("unexpected error: %v", decision.Err)
+			}
+			if decision.Allowed != tt.allowed {
+				t.Fatalf("allowed = %v, want %v (reason: %s)", decision.Allowed, tt.allowed, decision.Reason)
+			}
+			if tt.allowed && decision.Grant.Via != tt.via {
+				t.Fatalf("granted via %q, want %q", decision.Grant.Via, tt.via)
+			}
+			if !tt.allowed && decision.Reason == "" {
+				t.Fatal("denial without a reason")
+			}
+		})
+	}
+}
+
+func TestStoreErrorFailsClosed(t *testing.T) {
+	engine := NewEngine(&fakeStore{err: errors.New("db down")})
+
+	decision := engine.Can(Subject{UserID: uuid.New()}, ActionTeamManageMembers, Team(1))
+	if decision.Allowed || decision.Err == nil {
+		t.Fatalf("expected failed decision, got %+v", decision)
+	}
+}
+
+func TestPickShare(t *testing.T) {
+	user := uuid.New()
+	team := 7
+	direct := func(level models.AccessLevel) shareGrant {
+		return shareGrant{UserID: &user, AccessLevel: level}
+	}
+	viaTeam := func(level models.AccessLevel, role models.RosterRole) shareGrant {
+		return shareGrant{TeamID: &team, AccessLevel: level, TeamRole: role}
+	}
+
+	tests := []struct {
+		name     string
+		grants   []shareGrant
+		level    models.AccessLevel
+		viaTeam  bool
+		notFound bool
+	}{
+		{"no shares", nil, "", false, true},
+		{"direct share", []shareGrant{direct(models.Read)}, models.Read, false, false},
+		{"team write beats direct read", []shareGrant{direct(models.Read), viaTeam(models.Write, models.RosterRoleMember)}, models.Write, true, false},
+		{"direct wins a tie", []shareGrant{viaTeam(models.Read, models.RosterRoleLeader), direct(models.Read)}, models.Read, false, false},
+		{"team viewers only read", []shareGrant{viaTeam(models.Write, models.RosterRoleViewer)}, models.Read, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := pickShare(tt.grants)
+			if tt.notFound {
+				if best != nil {
+					t.Fatalf("got %+v, want none", best)
+				}
+				return
+			}
+			if best == nil {
+				t.Fatal("got none")
+			}
+			if best.AccessLevel != tt.level || (best.TeamID != nil) != tt.viaTeam {
+				t.Fatalf("got level %s via team %v, want %s via team %v", best.AccessLevel, best.TeamID != nil, tt.level, tt.viaTeam)
+			}
+		})
+	}
+}
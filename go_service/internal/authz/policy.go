@@ -0,0 +1,321 @@
+package authz
+
+import (
+	"fmt"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Rule grants an action when its condition holds
+type Rule func(store Store, subject Subject, resource Resource) Decision
+
+// Policy allows an action if any of its rules allow it
+type Policy struct {
+	AnyOf []Rule
+	// Denial is the reason returned when no rule applies
+	Denial string
+}
+
+// DefaultPolicies is the policy table used by NewEngine
+func DefaultPolicies() map[Action]Policy {
+	return map[Action]Policy{
+		ActionTeamCreate: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+		ActionTeamView: {
+			AnyOf:  []Rule{Authenticated},
+			Denial: "requires authentication",
+		},
+		ActionTeamViewDetails: {
+			AnyOf:  []Rule{GlobalManager, TeamMember},
+			Denial: "requires membership of the team or the MANAGER role",
+		},
+		ActionTeamUpdate: {
+			AnyOf:  []Rule{TeamRoleCan(PermUpdateTeam), ParentTeamLeader},
+			Denial: "requires leadership of the team or, for managers, of a team above it",
+		},
+		// Archived teams have no roster, so restoring one cannot depend on leadership
+		ActionTeamArchive: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+		ActionTeamDelete: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+		ActionTeamManageMembers: {
+			AnyOf:  []Rule{TeamRoleCan(PermManageMembers), ParentTeamLeader},
+			Denial: "requires leadership of the team or, for managers, of a team above it",
+		},
+		ActionTeamManageLeaders: {
+			AnyOf:  []Rule{TeamRoleCan(PermManageRoles), AllOf(GlobalManager, TeamLeader), ParentTeamLeader},
+			Denial: "requires ownership of the team, or the MANAGER role and leadership of the team or a team above it",
+		},
+		// Managers may appoint an owner to any team, including one whose last
+		// leader left under the escalate succession policy
+		ActionTeamTransferOwnership: {
+			AnyOf:  []Rule{TeamRoleCan(PermTransferOwnership), GlobalManager},
+			Denial: "requires ownership of the team or the MANAGER role",
+		},
+		ActionTeamManageQuota: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+		ActionTeamManageFields: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+		ActionTeamExport: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+		ActionTeamManageHierarchy: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+		ActionTeamCreateFolder: {
+			AnyOf:  []Rule{TeamRoleCan(PermContribute)},
+			Denial: "requires a team role that can contribute",
+		},
+		ActionTeamViewAssets: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+		ActionUserViewAssets: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+		ActionUserViewMemberships: {
+			AnyOf:  []Rule{Self, GlobalManager},
+			Denial: "requires being the user or the MANAGER role",
+		},
+		ActionSystemAdmin: {
+			AnyOf:  []Rule{GlobalManager},
+			Denial: "requires the MANAGER role",
+		},
+
+		ActionFolderRead: {
+			AnyOf:  []Rule{Owner, FolderShared(models.Read), TeamFolder(PermViewTeam)},
+			Denial: "requires ownership, a share of the folder or membership of the team that owns it",
+		},
+		ActionFolderWrite: {
+			AnyOf:  []Rule{Owner, FolderShared(models.Write), TeamFolder(PermManageAssets)},
+			Denial: "requires ownership, write access to the folder or leadership of the team that owns it",
+		},
+		ActionFolderDelete: {
+			AnyOf:  []Rule{Owner, TeamFolder(PermManageAssets)},
+			Denial: "requires ownership of the folder or leadership of the team that owns it",
+		},
+		ActionFolderShare: {
+			AnyOf:  []Rule{Owner, TeamFolder(PermManageAssets)},
+			Denial: "requires ownership of the folder or leadership of the team that owns it",
+		},
+		ActionFolderCreateNote: {
+			AnyOf:  []Rule{Owner, FolderShared(models.Write), TeamFolder(PermContribute)},
+			Denial: "requires ownership, write access to the folder or a contributing role on its team",
+		},
+
+		ActionNoteRead: {
+			AnyOf:  []Rule{Owner, NoteShared(models.Read), FolderShared(models.Read), TeamFolder(PermViewTeam)},
+			Denial: "requires ownership or a share of the note or its folder",
+		},
+		ActionNoteWrite: {
+			AnyOf:  []Rule{Owner, NoteShared(models.Write), FolderShared(models.Write), TeamFolder(PermManageAssets)},
+			Denial: "requires ownership or write access to the note or its folder, or leadership of the team that owns it",
+		},
+		ActionNoteDelete: {
+			AnyOf:  []Rule{Owner, FolderShared(models.Write), TeamFolder(PermManageAssets)},
+			Denial: "requires ownership of the note, write access to its folder or leadership of the team that owns it",
+		},
+		ActionNoteShare: {
+			AnyOf:  []Rule{Owner, TeamFolder(PermManageAssets)},
+			Denial: "requires ownership of the note or leadership of the team that owns its folder",
+		},
+	}
+}
+
+// Authenticated allows any authenticated subject
+func Authenticated(_ Store, _ Subject, _ Resource) Decision {
+	return allow(Grant{Via: "authenticated"})
+}
+
+// GlobalManager allows subjects with the MANAGER role
+func GlobalManager(_ Store, subject Subject, _ Resource) Decision {
+	if subject.IsManager() {
+		return allow(Grant{Via: "role"})
+	}
+	return deny("requires the MANAGER role")
+}
+
+// Self allows users acting on their own account
+func Self(_ Store, subject Subject, resource Resource) Decision {
+	if resource.Kind == KindUser && resource.UserID == subject.UserID {
+		return allow(Grant{Via: "self"})
+	}
+	return deny("requires being the user")
+}
+
+// TeamMember allows anyone on the resource's team roster, viewers included
+func TeamMember(store Store, subject Subject, resource Resource) Decision {
+	if resource.TeamID == 0 {
+		return deny("resource has no team")
+	}
+	role, err := store.TeamRole(resource.TeamID, subject.UserID)
+	if err != nil {
+		return failed(err)
+	}
+	if role != "" {
+		return allow(Grant{Via: "team_member"})
+	}
+	return deny("requires membership of the team")
+}
+
+// TeamLeader allows the owner and leaders of the resource's team
+func TeamLeader(store Store, subject Subject, resource Resource) Decision {
+	if resource.TeamID == 0 {
+		return deny("resource has no team")
+	}
+	role, err := store.TeamRole(resource.TeamID, subject.UserID)
+	if err != nil {
+		return failed(err)
+	}
+	if role.IsLeader() {
+		return allow(Grant{Via: "team_leader"})
+	}
+	return deny("requires leadership of the team")
+}
+
+// TeamRoleCan allows users whose role on the resource's team carries perm
+func TeamRoleCan(perm TeamPermission) Rule {
+	return func(store Store, subject Subject, resource Resource) Decision {
+		if resource.TeamID == 0 {
+			return deny("resource has no team")
+		}
+		role, err := store.TeamRole(resource.TeamID, subject.UserID)
+		if err != nil {
+			return failed(err)
+		}
+		if RoleHas(role, perm) {
+			return allow(Grant{Via: "team_role", TeamRole: role})
+		}
+		return deny(fmt.Sprintf("requires a team role with the %s permission", perm))
+	}
+}
+
+// ParentTeamLeader allows managers who lead a team above the resource's team;
+// leadership of a department carries down to its squads
+func ParentTeamLeader(store Store, subject Subject, resource Resource) Decision {
+	if resource.TeamID == 0 {
+		return deny("resource has no team")
+	}
+	if !subject.IsManager() {
+		return deny("requires the MANAGER role")
+	}
+	leader, err := store.IsAncestorTeamLeader(resource.TeamID, subject.UserID)
+	if err != nil {
+		return failed(err)
+	}
+	if leader {
+		return allow(Grant{Via: "parent_team_leader"})
+	}
+	return deny("requires leadership of a parent team")
+}
+
+// Owner allows the owner of a folder or note
+func Owner(_ Store, subject Subject, resource Resource) Decision {
+	if resource.OwnerID != uuid.Nil && resource.OwnerID == subject.UserID {
+		return allow(Grant{Via: "owner", AccessLevel: models.Write})
+	}
+	return deny("requires ownership")
+}
+
+// FolderShared allows subjects the resource's folder is shared with at level or above
+func FolderShared(level models.AccessLevel) Rule {
+	return func(store Store, subject Subject, resource Resource) Decision {
+		share, err := store.FolderShare(resource.FolderID, subject.UserID)
+		if err != nil {
+			return failed(err)
+		}
+		if share != nil && satisfies(share.AccessLevel, level) {
+			return allow(Grant{Via: "folder_share", AccessLevel: share.AccessLevel, SharedByID: share.SharedByID, ShareTeamID: shareTeam(share.TeamID)})
+		}
+		return deny("folder is not shared with the user at the required level")
+	}
+}
+
+// NoteShared allows subjects the note is shared with at level or above
+func NoteShared(level models.AccessLevel) Rule {
+	return func(store Store, subject Subject, resource Resource) Decision {
+		share, err := store.NoteShare(resource.NoteID, subject.UserID)
+		if err != nil {
+			return failed(err)
+		}
+		if share != nil && satisfies(share.AccessLevel, level) {
+			return allow(Grant{Via: "note_share", AccessLevel: share.AccessLevel, SharedByID: share.SharedByID, ShareTeamID: shareTeam(share.TeamID)})
+		}
+		return deny("note is not shared with the user at the required level")
+	}
+}
+
+// TeamFolder allows users whose role on the team owning the resource's folder
+// carries perm. Members of a team that can contribute get write access, the
+// rest read access; it follows the roster with no shares to keep in step.
+func TeamFolder(perm TeamPermission) Rule {
+	return func(store Store, subject Subject, resource Resource) Decision {
+		teamID := resource.TeamID
+		if teamID == 0 && resource.FolderID != uuid.Nil {
+			var err error
+			if teamID, err = store.FolderTeam(resource.FolderID); err != nil {
+				return failed(err)
+			}
+		}
+		if teamID == 0 {
+			return deny("folder does not belong to a team")
+		}
+
+		role, err := store.TeamRole(teamID, subject.UserID)
+		if err != nil {
+			return failed(err)
+		}
+		if !RoleHas(role, perm) {
+			return deny(fmt.Sprintf("requires a role on the folder's team with the %s permission", perm))
+		}
+		level := models.Read
+		if RoleHas(role, PermContribute) {
+			level = models.Write
+		}
+		return allow(Grant{Via: "team_folder", AccessLevel: level, TeamRole: role})
+	}
+}
+
+func shareTeam(teamID *int) int {
+	if teamID == nil {
+		return 0
+	}
+	return *teamID
+}
+
+// AllOf allows only when every rule allows; the last grant is reported
+func AllOf(rules ...Rule) Rule {
+	return func(store Store, subject Subject, resource Resource) Decision {
+		var decision Decision
+		for _, rule := range rules {
+			decision = rule(store, subject, resource)
+			if decision.Err != nil || !decision.Allowed {
+				return decision
+			}
+		}
+		return decision
+	}
+}
+
+// satisfies reports whether granted access covers the required level
+func satisfies(granted, required models.AccessLevel) bool {
+	if required == models.Read {
+		return granted == models.Read || granted == models.Write
+	}
+	return granted == required
+}
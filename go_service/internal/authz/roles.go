@@ -0,0 +1,41 @@
+package authz
+
+import "go_service/internal/models"
+
+// TeamPermission is something a roster role allows on its own team
+type TeamPermission string
+
+const (
+	// PermViewTeam covers the team's details, roster and activity
+	PermViewTeam TeamPermission = "view_team"
+	// PermContribute covers adding to the team's shared work
+	PermContribute TeamPermission = "contribute"
+	// PermUpdateTeam covers the team's name and settings
+	PermUpdateTeam TeamPermission = "update_team"
+	// PermManageMembers covers adding and removing members, invitations and join requests
+	PermManageMembers TeamPermission = "manage_members"
+	// PermManageAssets covers deleting and sharing the team's folders
+	PermManageAssets TeamPermission = "manage_assets"
+	// PermManageRoles covers changing the roles of others on the roster
+	PermManageRoles TeamPermission = "manage_roles"
+	// PermTransferOwnership covers handing the team to another owner
+	PermTransferOwnership TeamPermission = "transfer_ownership"
+)
+
+// RolePermissions is the permission set of each roster role
+var RolePermissions = map[models.RosterRole][]TeamPermission{
+	models.RosterRoleOwner:  {PermViewTeam, PermContribute, PermUpdateTeam, PermManageMembers, PermManageAssets, PermManageRoles, PermTransferOwnership},
+	models.RosterRoleLeader: {PermViewTeam, PermContribute, PermUpdateTeam, PermManageMembers, PermManageAssets},
+	models.RosterRoleMember: {PermViewTeam, PermContribute},
+	models.RosterRoleViewer: {PermViewTeam},
+}
+
+// RoleHas reports whether role carries perm; users not on the roster have no permissions
+func RoleHas(role models.RosterRole, perm TeamPermission) bool {
+	for _, p := range RolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package authz
+
+import (
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Store provides the facts policies are evaluated against
+type Store interface {
+	// TeamRole returns userID's role on the roster of teamID, or "" if they are not on it
+	TeamRole(teamID int, userID uuid.UUID) (models.RosterRole, error)
+	// IsAncestorTeamLeader reports whether userID leads any team above teamID
+	IsAncestorTeamLeader(teamID int, userID uuid.UUID) (bool, error)
+	// FolderTeam returns the team that owns folderID, or 0 for a personal folder
+	FolderTeam(folderID uuid.UUID) (int, error)
+	// FolderShare returns the share of folderID that gives userID the most
+	// access, directly or through a team they are on, or nil if there is none
+	FolderShare(folderID, userID uuid.UUID) (*models.FolderShare, error)
+	// NoteShare is FolderShare for noteID
+	NoteShare(noteID, userID uuid.UUID) (*models.NoteShare, error)
+}
+
+// GormStore reads rosters and shares from the database
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore creates a Store backed by db
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) TeamRole(teamID int, userID uuid.UUID) (models.RosterRole, error) {
+	var rosters []models.Roster
+	err := s.db.Where("\"teamId\" = ? AND \"userId\" = ?", teamID, userID).Limit(1).Find(&rosters).Error
+	if err != nil || len(rosters) == 0 {
+		return "", err
+	}
+	return rosters[0].Role, nil
+}
+
+func (s *GormStore) IsAncestorTeamLeader(teamID int, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := s.db.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT "parentTeamId" AS id, 1 AS depth FROM "Teams" WHERE "teamId" = ?
+			UNION ALL
+			SELECT t."parentTeamId", a.depth + 1 FROM "Teams" t
+			JOIN ancestors a ON t."teamId" = a.id
+			WHERE a.depth < 64
+		)
+		SELECT COUNT(*) FROM "Rosters" r JOIN ancestors a ON r."teamId" = a.id
+		WHERE r."userId" = ? AND r."isLeader"`, teamID, userID).
+		Scan(&count).Error
+	return count > 0, err
+}
+
+func (s *GormStore) FolderTeam(folderID uuid.UUID) (int, error) {
+	var folders []models.Folder
+	err := s.db.Select("id", "team_id").Where("id = ?", folderID).Limit(1).Find(&folders).Error
+	if err != nil || len(folders) == 0 || folders[0].TeamID == nil {
+		return 0, err
+	}
+	return *folders[0].TeamID, nil
+}
+
+func (s *GormStore) FolderShare(folderID, userID uuid.UUID) (*models.FolderShare, error) {
+	grant, err := s.bestShare("folder_shares", "folder_id", folderID, userID)
+	if err != nil || grant == nil {
+		return nil, err
+	}
+	return &models.FolderShare{FolderID: folderID, UserID: grant.UserID, TeamID: grant.TeamID, AccessLevel: grant.AccessLevel, SharedByID: grant.SharedByID}, nil
+}
+
+func (s *GormStore) NoteShare(noteID, userID uuid.UUID) (*models.NoteShare, error) {
+	grant, err := s.bestShare("note_shares", "note_id", noteID, userID)
+	if err != nil || grant == nil {
+		return nil, err
+	}
+	return &models.NoteShare{NoteID: noteID, UserID: grant.UserID, TeamID: grant.TeamID, AccessLevel: grant.AccessLevel, SharedByID: grant.SharedByID}, nil
+}
+
+// shareGrant is a share row that reaches a user, with their roster role on
+// the team when the share is a team share
+type shareGrant struct {
+	UserID      *uuid.UUID
+	TeamID      *int
+	AccessLevel models.AccessLevel
+	SharedByID  uuid.UUID
+	TeamRole    models.RosterRole
+}
+
+// bestShare reads the shares of an asset with userID or any team they are on;
+// team membership comes from the roster as it is now
+func (s *GormStore) bestShare(table, assetColumn string, assetID, userID uuid.UUID) (*shareGrant, error) {
+	var grants []shareGrant
+	err := s.db.Raw(`SELECT s.user_id, s.team_id, s.access_level, s.shared_by_id, r.role AS team_role
+		FROM `+table+` s
+		LEFT JOIN "Rosters" r ON r."teamId" = s.team_id AND r."userId" = ?
+		WHERE s.`+assetColumn+` = ? AND (s.user_id = ? OR r."rosterId" IS NOT NULL)`,
+		userID, assetID, userID).
+		Scan(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+	return pickShare(grants), nil
+}
+
+// pickShare returns the grant giving the most access, preferring a direct
+// share over a team share of the same level. Team viewers only ever read.
+func pickShare(grants []shareGrant) *shareGrant {
+	var best *shareGrant
+	for i := range grants {
+		grant := grants[i]
+		if grant.TeamID != nil && !RoleHas(grant.TeamRole, PermContribute) {
+			grant.AccessLevel = models.Read
+		}
+		switch {
+		case best == nil,
+			grant.AccessLevel == models.Write && best.AccessLevel != models.Write,
+			grant.AccessLevel == best.AccessLevel && grant.TeamID == nil && best.TeamID != nil:
+			best = &grant
+		}
+	}
+	return best
+}
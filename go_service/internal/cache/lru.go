@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a fixed-size, concurrency-safe least-recently-used cache.
+// Entries remember when they were stored so callers can apply their own
+// freshness rules (TTL, max-stale) on read.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key      K
+	value    V
+	storedAt time.Time
+}
+
+// NewLRU creates an LRU cache holding at most capacity entries
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &LRU[K, V]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[K]*list.Element, capacity),
+	}
+}
+
+// Get returns the value for key and the time it was stored
+func (c *LRU[K, V]) Get(key K) (V, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, time.Time{}, false
+	}
+
+	c.ll.MoveToFront(el)
+	e := el.Value.(*entry[K, V])
+	return e.value, e.storedAt, true
+}
+
+// Set stores value under key, evicting the least recently used entry if full
+func (c *LRU[K, V]) Set(key K, value V, storedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		e := el.Value.(*entry[K, V])
+		e.value = value
+		e.storedAt = storedAt
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&entry[K, V]{key: key, value: value, storedAt: storedAt})
+
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry[K, V]).key)
+	}
+}
+
+// Delete removes key from the cache
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of cached entries
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
@@ -19,19 +19,192 @@ func CreateDSN() string {
 	)
 }
 
-func Connect(dsn string) (*gorm.DB, error) {
+// Open connects to the database without touching its schema. The API server
+// owns the schema, so other processes such as the consumer use Open rather
+// than Connect to avoid migrating concurrently with it.
+func Open(dsn string) (*gorm.DB, error) {
 	DB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	return DB, nil
+}
+
+// Connect opens the database and brings its schema up to date
+func Connect(dsn string) (*gorm.DB, error) {
+	DB, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
 
-	err = DB.AutoMigrate(&models.Team{}, &models.Roster{}, &models.Folder{}, &models.Note{}, &models.FolderShare{}, &models.NoteShare{})
+	err = DB.AutoMigrate(&models.Team{}, &models.Roster{}, &models.ArchivedRoster{}, &models.TeamInvitation{}, &models.TeamJoinRequest{}, &models.MembershipInterval{}, &models.TeamQuota{}, &models.TeamActivity{}, &models.TeamLabel{}, &models.TeamFieldDefinition{}, &models.TeamFieldValue{}, &models.OutboxEvent{}, &models.Folder{}, &models.Note{}, &models.FolderShare{}, &models.NoteShare{}, &models.ServiceAccount{}, &models.APIToken{}, &models.ImpersonationAudit{})
 
 	if err != nil {
 
 		return nil, fmt.Errorf("migration failed: %w", err)
 	}
 
+	if err := migrateRosterRoles(DB); err != nil {
+		return nil, fmt.Errorf("roster role migration failed: %w", err)
+	}
+
+	if err := reconcileMembershipHistory(DB); err != nil {
+		return nil, fmt.Errorf("membership history migration failed: %w", err)
+	}
+
+	if err := migrateSharePrincipals(DB); err != nil {
+		return nil, fmt.Errorf("share principal migration failed: %w", err)
+	}
+
+	if err := migrateTeamParents(DB); err != nil {
+		return nil, fmt.Errorf("team parent migration failed: %w", err)
+	}
+
 	return DB, nil
 }
+
+// migrationLockID is the Postgres advisory lock that keeps servers starting
+// together from applying the same one-time migration twice
+const migrationLockID = 7_301_417
+
+// runOnce applies a one-time data migration and records it in
+// schema_migrations, so later starts skip it
+func runOnce(db *gorm.DB, name string, statements []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+			return err
+		}
+		err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			name VARCHAR(100) PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW())`).Error
+		if err != nil {
+			return err
+		}
+
+		var applied int64
+		if err := tx.Table("schema_migrations").Where("name = ?", name).Count(&applied).Error; err != nil {
+			return err
+		}
+		if applied > 0 {
+			return nil
+		}
+
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec("INSERT INTO schema_migrations (name) VALUES (?)", name).Error
+	})
+}
+
+// migrateRosterRoles derives roster roles from the isLeader column, which
+// predates them, and gives every team with leaders an owner: its
+// longest-standing leader. It runs once; from then on SetRole keeps role and
+// isLeader in step. The unique indexes allow one owner per team and one
+// roster entry per user and team.
+func migrateRosterRoles(db *gorm.DB) error {
+	err := runOnce(db, "roster_roles", []string{
+		`UPDATE "Rosters" SET role = 'leader' WHERE "isLeader" AND role NOT IN ('owner', 'leader')`,
+		`UPDATE "Rosters" SET role = 'member' WHERE NOT "isLeader" AND role IN ('owner', 'leader')`,
+		`UPDATE "Rosters" SET role = 'owner' WHERE "rosterId" IN (
+			SELECT MIN("rosterId") FROM "Rosters" WHERE "isLeader"
+			GROUP BY "teamId" HAVING NOT BOOL_OR(role = 'owner'))`,
+
+		`UPDATE archived_rosters SET role = 'leader' WHERE is_leader AND role NOT IN ('owner', 'leader')`,
+		`UPDATE archived_rosters SET role = 'member' WHERE NOT is_leader AND role IN ('owner', 'leader')`,
+		`UPDATE archived_rosters SET role = 'owner' WHERE id IN (
+			SELECT MIN(id) FROM archived_rosters WHERE is_leader
+			GROUP BY team_id HAVING NOT BOOL_OR(role = 'owner'))`,
+	})
+	if err != nil {
+		return err
+	}
+	if err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS rosters_one_owner ON "Rosters" ("teamId") WHERE role = 'owner'`).Error; err != nil {
+		return err
+	}
+
+	// Members could be added to archived teams and came back twice on restore.
+	// Keep each user's owner entry, else their first, and one open interval
+	// per role.
+	err = runOnce(db, "roster_duplicates", []string{
+		`DELETE FROM "Rosters" r USING "Rosters" k
+			WHERE r."teamId" = k."teamId" AND r."userId" = k."userId" AND r."rosterId" <> k."rosterId"
+			AND ((k.role = 'owner') > (r.role = 'owner')
+				OR ((k.role = 'owner') = (r.role = 'owner') AND k."rosterId" < r."rosterId"))`,
+		`DELETE FROM membership_intervals m USING membership_intervals k
+			WHERE m.team_id = k.team_id AND m.user_id = k.user_id AND m.role = k.role
+			AND m.ended_at IS NULL AND k.ended_at IS NULL AND m.id > k.id`,
+	})
+	if err != nil {
+		return err
+	}
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS rosters_team_user ON "Rosters" ("teamId", "userId")`).Error
+}
+
+// reconcileMembershipHistory backfills membership intervals for the roster
+// that predates the history: each entry gets an interval starting at the
+// first run, so history before it is unknown. It runs once; from then on the
+// repository opens and closes intervals as the roster changes. Open intervals
+// that no longer match the roster are ended, as role_changed when the user is
+// still on the team under another role and as left otherwise.
+func reconcileMembershipHistory(db *gorm.DB) error {
+	return runOnce(db, "membership_history", []string{
+		`UPDATE membership_intervals m SET ended_at = NOW(),
+			end_reason = CASE WHEN EXISTS (SELECT 1 FROM "Rosters" r
+				WHERE r."teamId" = m.team_id AND r."userId" = m.user_id) THEN 'role_changed' ELSE 'left' END
+			WHERE m.ended_at IS NULL AND NOT EXISTS (SELECT 1 FROM "Rosters" r
+				WHERE r."teamId" = m.team_id AND r."userId" = m.user_id AND r.role = m.role)`,
+		`INSERT INTO membership_intervals (team_id, user_id, role, started_at)
+			SELECT r."teamId", r."userId", r.role, NOW() FROM "Rosters" r
+			WHERE NOT EXISTS (SELECT 1 FROM membership_intervals m
+				WHERE m.team_id = r."teamId" AND m.user_id = r."userId" AND m.role = r.role AND m.ended_at IS NULL)`,
+	})
+}
+
+// migrateSharePrincipals lets folder and note shares name a team instead of a
+// user. user_id was NOT NULL before team shares existed; the check constraint
+// keeps every share naming exactly one of the two.
+func migrateSharePrincipals(db *gorm.DB) error {
+	var statements []string
+	for _, table := range []string{"folder_shares", "note_shares"} {
+		statements = append(statements,
+			fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN user_id DROP NOT NULL`, table),
+			fmt.Sprintf(`DO $$ BEGIN
+				IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '%[1]s_one_principal') THEN
+					ALTER TABLE %[1]s ADD CONSTRAINT %[1]s_one_principal CHECK ((user_id IS NULL) <> (team_id IS NULL));
+				END IF;
+			END $$`, table),
+		)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateTeamParents makes parentTeamId a foreign key to "Teams". Parents
+// that were deleted before the key existed are cleared first, leaving their
+// sub-teams at the top level. Deleting a team that still has sub-teams is
+// refused; DeleteTeam moves them up to its own parent first.
+func migrateTeamParents(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`UPDATE "Teams" t SET "parentTeamId" = NULL
+			WHERE t."parentTeamId" IS NOT NULL AND NOT EXISTS (
+				SELECT 1 FROM "Teams" p WHERE p."teamId" = t."parentTeamId")`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'teams_parent_team_fkey') THEN
+				ALTER TABLE "Teams" ADD CONSTRAINT teams_parent_team_fkey
+					FOREIGN KEY ("parentTeamId") REFERENCES "Teams" ("teamId") ON DELETE RESTRICT;
+			END IF;
+		END $$`).Error
+	})
+}
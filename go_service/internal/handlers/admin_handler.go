@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_service/internal/authz"
+	"go_service/internal/services"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AdminHandler struct {
+	db             *gorm.DB
+	identities     *services.IdentityCache
+	revocations    *services.RevocationService
+	impersonations *services.ImpersonationService
+	teams          *services.TeamService
+	authz          *authz.Engine
+}
+
+func NewAdminHandler(db *gorm.DB, identities *services.IdentityCache, revocations *services.RevocationService, impersonations *services.ImpersonationService, teams *services.TeamService, engine *authz.Engine) *AdminHandler {
+	return &AdminHandler{
+		db:             db,
+		identities:     identities,
+		revocations:    revocations,
+		impersonations: impersonations,
+		teams:          teams,
+		authz:          engine,
+	}
+}
+
+// InvalidateUserIdentity drops a user's cached identity so a role change takes
+// effect immediately, and emits USER_ROLE_CHANGED so the consumers drop it too
+// (only managers)
+func (h *AdminHandler) InvalidateUserIdentity(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can invalidate user identities"); !ok {
+		return
+	}
+	subject, _ := currentSubject(c)
+
+	userIDStr := c.Param("userId")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		log.Printf("Invalid user ID format: %s", userIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid user ID format", ""))
+		return
+	}
+
+	if err := services.RecordRoleChange(h.db.WithContext(c.Request.Context()), userID, subject.UserID); err != nil {
+		log.Printf("Failed to record role change for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to invalidate user identity", ""))
+		return
+	}
+	if err := h.identities.Invalidate(c.Request.Context(), userID.String()); err != nil {
+		log.Printf("Failed to invalidate identity for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to invalidate user identity", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("User identity invalidated successfully", gin.H{
+		"userId": userID,
+	}))
+}
+
+// RevokeToken revokes a single access token by its jti (only managers)
+func (h *AdminHandler) RevokeToken(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can revoke tokens"); !ok {
+		return
+	}
+
+	var req struct {
+		TokenID   string    `json:"jti" binding:"required"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
+		return
+	}
+
+	if err := h.revocations.RevokeToken(c.Request.Context(), req.TokenID, req.ExpiresAt); err != nil {
+		log.Printf("Failed to revoke token %s: %v", req.TokenID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to revoke token", err.Error()))
+		return
+	}
+
+	performedBy, _ := c.Get("user_id")
+	log.Printf("Token %s revoked by %v", req.TokenID, performedBy)
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Token revoked successfully", gin.H{
+		"jti": req.TokenID,
+	}))
+}
+
+// RevokeUserTokens invalidates every token issued to a user before a point in time,
+// used for account disablement and "log out everywhere" (only managers)
+func (h *AdminHandler) RevokeUserTokens(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can revoke user tokens"); !ok {
+		return
+	}
+
+	userIDStr := c.Param("userId")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		log.Printf("Invalid user ID format: %s", userIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid user ID format", ""))
+		return
+	}
+
+	var req struct {
+		Before *time.Time `json:"before"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("Invalid request body: %v", err)
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
+			return
+		}
+	}
+
+	before := time.Now()
+	if req.Before != nil {
+		before = *req.Before
+	}
+
+	if err := h.revocations.RevokeUserTokens(c.Request.Context(), userID.String(), before); err != nil {
+		log.Printf("Failed to revoke tokens for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to revoke user tokens", err.Error()))
+		return
+	}
+
+	performedBy, _ := c.Get("user_id")
+	log.Printf("Tokens issued to user %s before %s revoked by %v", userID, before.Format(time.RFC3339), performedBy)
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("User tokens revoked successfully", gin.H{
+		"userId": userID,
+		"before": before.Truncate(time.Second),
+	}))
+}
+
+// RemoveUserFromTeams takes a deactivated user off every team roster. Teams
+// they owned or led alone are handed on by the succession policy (only managers)
+func (h *AdminHandler) RemoveUserFromTeams(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can remove users from all teams"); !ok {
+		return
+	}
+
+	userIDStr := c.Param("userId")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		log.Printf("Invalid user ID format: %s", userIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid user ID format", ""))
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	departures, err := h.teams.RemoveFromAllTeams(userID, subject.UserID)
+	if err != nil {
+		log.Printf("Failed to remove user %s from their teams: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to remove user from teams", err.Error()))
+		return
+	}
+
+	removed := 0
+	for _, departure := range departures {
+		if departure.Error == "" {
+			removed++
+		}
+	}
+	log.Printf("User %s removed from %d of %d teams by %s", userID, removed, len(departures), subject.UserID)
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("User removed from teams", gin.H{
+		"userId":  userID,
+		"removed": removed,
+		"teams":   departures,
+	}))
+}
+
+// ListImpersonations returns the impersonation audit trail, newest first,
+// optionally filtered by actorId, targetId, since (RFC 3339) and limit (only managers)
+func (h *AdminHandler) ListImpersonations(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can view the impersonation audit"); !ok {
+		return
+	}
+	if h.impersonations == nil {
+		c.JSON(http.StatusNotFound, responses.NewErrorResponse("Impersonation is disabled", ""))
+		return
+	}
+
+	var filter services.ImpersonationFilter
+	for param, target := range map[string]*uuid.UUID{"actorId": &filter.ActorID, "targetId": &filter.TargetID} {
+		if value := c.Query(param); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid "+param+" format", ""))
+				return
+			}
+			*target = id
+		}
+	}
+	if value := c.Query("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid since format, expected RFC 3339", ""))
+			return
+		}
+		filter.Since = since
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid limit", ""))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.impersonations.ListAudit(filter)
+	if err != nil {
+		log.Printf("Failed to list impersonation audit: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list impersonation audit", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Impersonation audit retrieved successfully", entries))
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"go_service/internal/auth"
+	"go_service/internal/services"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	revocations *services.RevocationService
+}
+
+func NewAuthHandler(revocations *services.RevocationService) *AuthHandler {
+	return &AuthHandler{
+		revocations: revocations,
+	}
+}
+
+// Logout revokes the caller's current token. Tokens without a jti (as minted
+// by the Node.js service) cannot be revoked individually and are refused
+// rather than ending every session of the user.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	value, exists := c.Get("token_claims")
+	if !exists {
+		log.Println("Unauthorized attempt to logout: missing token claims")
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return
+	}
+	claims := value.(*auth.Claims)
+
+	if claims.ID == "" {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("This token cannot be logged out", "it has no jti to revoke"))
+		return
+	}
+
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	if err := h.revocations.RevokeToken(c.Request.Context(), claims.ID, expiresAt); err != nil {
+		log.Printf("Failed to revoke token %s on logout: %v", claims.ID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to logout", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Logged out successfully", gin.H{
+		"jti": claims.ID,
+	}))
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"go_service/internal/authz"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// currentSubject builds the authorization subject for the authenticated caller
+func currentSubject(c *gin.Context) (authz.Subject, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return authz.Subject{}, false
+	}
+	role, _ := c.Get("role")
+	roleStr, _ := role.(string)
+
+	return authz.Subject{UserID: userID.(uuid.UUID), Role: roleStr}, true
+}
+
+// authorize asks the policy engine whether the caller may perform action on
+// resource. On denial it writes the response, using denial as the error
+// message and the engine's reason as details, and returns false.
+func authorize(c *gin.Context, engine *authz.Engine, action authz.Action, resource authz.Resource, denial string) (authz.Decision, bool) {
+	subject, ok := currentSubject(c)
+	if !ok {
+		log.Printf("Unauthorized attempt to %s %s: missing user_id", action, resource)
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return authz.Decision{}, false
+	}
+
+	decision := engine.Can(subject, action, resource)
+	if decision.Err != nil {
+		log.Printf("Authorization check %s on %s failed for user %s: %v", action, resource, subject.UserID, decision.Err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify permissions", ""))
+		return decision, false
+	}
+
+	if !decision.Allowed {
+		log.Printf("User %s denied %s on %s: %s", subject.UserID, action, resource, decision.Reason)
+		c.JSON(http.StatusForbidden, responses.NewErrorResponse(denial, decision.Reason))
+		return decision, false
+	}
+
+	return decision, true
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
+	"go_service/internal/authz"
+	"go_service/internal/kafka"
 	"go_service/internal/models"
+	"go_service/internal/services"
 	"go_service/pkg/responses"
 
 	"github.com/gin-gonic/gin"
@@ -13,14 +18,17 @@ import (
 )
 
 type FolderHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	authz  *authz.Engine
+	quotas *services.QuotaService
 }
 
-func NewFolderHandler(db *gorm.DB) *FolderHandler {
-	return &FolderHandler{db: db}
+func NewFolderHandler(db *gorm.DB, engine *authz.Engine, quotas *services.QuotaService) *FolderHandler {
+	return &FolderHandler{db: db, authz: engine, quotas: quotas}
 }
 
-// CreateFolder creates a new folder for the authenticated user
+// CreateFolder creates a new folder for the authenticated user, or for a team
+// when teamId is given (members whose role can contribute)
 func (h *FolderHandler) CreateFolder(c *gin.Context) {
 	// Get current user ID from context
 	currentUserID, exists := c.Get("user_id")
@@ -33,6 +41,7 @@ func (h *FolderHandler) CreateFolder(c *gin.Context) {
 	// Parse request body
 	var req struct {
 		FolderName string `json:"folderName" binding:"required"`
+		TeamID     *int   `json:"teamId"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -41,15 +50,52 @@ func (h *FolderHandler) CreateFolder(c *gin.Context) {
 		return
 	}
 
+	var details map[string]string
+	if req.TeamID != nil {
+		var team models.Team
+		if err := h.db.First(&team, *req.TeamID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, responses.NewErrorResponse("Team not found", ""))
+				return
+			}
+			log.Printf("Database error when finding team: %v", err)
+			c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify team", ""))
+			return
+		}
+		if team.ArchivedAt != nil {
+			c.JSON(http.StatusConflict, responses.NewErrorResponse("Team is archived", "restore the team first"))
+			return
+		}
+		if _, ok := authorize(c, h.authz, authz.ActionTeamCreateFolder, authz.Team(team.ID), "Only team members who can contribute can create team folders"); !ok {
+			return
+		}
+		details = map[string]string{"teamId": strconv.Itoa(team.ID)}
+	}
+
 	// Create folder object
 	folder := models.Folder{
 		ID:         uuid.New(),
 		FolderName: req.FolderName,
 		OwnerID:    currentUserID.(uuid.UUID),
+		TeamID:     req.TeamID,
 	}
 
 	// Save to database
-	if err := h.db.Create(&folder).Error; err != nil {
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// Members of a team may only own so many folders
+		if err := h.quotas.CheckOwnedAssets(tx, folder.OwnerID, services.QuotaFoldersPerMember); err != nil {
+			return err
+		}
+		if err := tx.Create(&folder).Error; err != nil {
+			return err
+		}
+		return services.AddAssetEvent(tx, kafka.NewFolderEvent(kafka.EventFolderCreated, folder.ID, folder.OwnerID, folder.OwnerID, details))
+	})
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		respondQuotaExceeded(c, err)
+		return
+	}
+	if err != nil {
 		log.Printf("Failed to create folder: %v", err)
 		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to create folder", ""))
 		return
@@ -61,7 +107,7 @@ func (h *FolderHandler) CreateFolder(c *gin.Context) {
 // GetFolderDetails retrieves details of a specific folder
 func (h *FolderHandler) GetFolderDetails(c *gin.Context) {
 	// Get current user ID from context
-	currentUserID, exists := c.Get("user_id")
+	_, exists := c.Get("user_id")
 	if !exists {
 		log.Println("Unauthorized attempt to access folder: missing user_id")
 		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
@@ -91,25 +137,21 @@ func (h *FolderHandler) GetFolderDetails(c *gin.Context) {
 	}
 
 	// Check ownership or sharing permissions
-	if folder.OwnerID != currentUserID.(uuid.UUID) {
-		// Check if folder is shared with the user
-		var folderShare models.FolderShare
-		if err := h.db.Where("folder_id = ? AND user_id = ?", folderID, currentUserID).First(&folderShare).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				log.Printf("User %s attempted to access folder %s without permission", currentUserID, folderID)
-				c.JSON(http.StatusForbidden, responses.NewErrorResponse("You don't have permission to access this folder", ""))
-				return
-			}
-			log.Printf("Database error when checking folder access: %v", err)
-			c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify folder access permission", ""))
-			return
-		}
+	decision, ok := authorize(c, h.authz, authz.ActionFolderRead, authz.Folder(folder), "You don't have permission to access this folder")
+	if !ok {
+		return
+	}
+	if decision.Grant.Via != "owner" && decision.Grant.Via != "team_folder" {
 		// Include sharing info in response
-		c.JSON(http.StatusOK, responses.NewSuccessResponse("Folder details retrieved successfully", gin.H{
+		data := gin.H{
 			"folder":      folder,
-			"accessLevel": folderShare.AccessLevel,
-			"sharedBy":    folderShare.SharedByID,
-		}))
+			"accessLevel": decision.Grant.AccessLevel,
+			"sharedBy":    decision.Grant.SharedByID,
+		}
+		if decision.Grant.ShareTeamID != 0 {
+			data["sharedWithTeam"] = decision.Grant.ShareTeamID
+		}
+		c.JSON(http.StatusOK, responses.NewSuccessResponse("Folder details retrieved successfully", data))
 		return
 	}
 
@@ -125,11 +167,56 @@ func (h *FolderHandler) GetFolderDetails(c *gin.Context) {
 		log.Printf("Error fetching sharing info for folder %s: %v", folderID, err)
 	}
 
-	c.JSON(http.StatusOK, responses.NewSuccessResponse("Folder details retrieved successfully", gin.H{
+	data := gin.H{
 		"folder": folder,
 		"notes":  notes,
 		"shared": len(shares) > 0,
 		"shares": shares,
+	}
+	if decision.Grant.Via == "team_folder" {
+		data["accessLevel"] = decision.Grant.AccessLevel
+		data["teamRole"] = decision.Grant.TeamRole
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Folder details retrieved successfully", data))
+}
+
+// ListFolders lists the caller's personal folders, or with ?teamId= the
+// folders that belong to a team (team members and managers)
+func (h *FolderHandler) ListFolders(c *gin.Context) {
+	subject, ok := currentSubject(c)
+	if !ok {
+		log.Println("Unauthorized attempt to list folders: missing user_id")
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return
+	}
+
+	query := h.db.Order("folder_name")
+	teamIDStr := c.Query("teamId")
+	if teamIDStr != "" {
+		teamID, err := strconv.ParseUint(teamIDStr, 10, 31)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid team ID format", ""))
+			return
+		}
+		if _, ok := authorize(c, h.authz, authz.ActionTeamViewDetails, authz.Team(int(teamID)), "You don't have permission to view this team"); !ok {
+			return
+		}
+		query = query.Where("team_id = ?", teamID)
+	} else {
+		query = query.Where("owner_id = ? AND team_id IS NULL", subject.UserID)
+	}
+
+	folders := []models.Folder{}
+	if err := query.Find(&folders).Error; err != nil {
+		log.Printf("Failed to list folders: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list folders", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Folders retrieved successfully", gin.H{
+		"folders": folders,
+		"count":   len(folders),
 	}))
 }
 
@@ -177,25 +264,23 @@ func (h *FolderHandler) UpdateFolder(c *gin.Context) {
 	}
 
 	// Check ownership or write permission
-	if folder.OwnerID != currentUserID.(uuid.UUID) {
-		// Check if user has write permission
-		var folderShare models.FolderShare
-		if err := h.db.Where("folder_id = ? AND user_id = ? AND access_level = ?",
-			folderID, currentUserID, models.Write).First(&folderShare).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				log.Printf("User %s attempted to update folder %s without permission", currentUserID, folderID)
-				c.JSON(http.StatusForbidden, responses.NewErrorResponse("You don't have permission to update this folder", ""))
-				return
-			}
-			log.Printf("Database error when checking folder write permission: %v", err)
-			c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify folder write permission", ""))
-			return
-		}
+	if _, ok := authorize(c, h.authz, authz.ActionFolderWrite, authz.Folder(folder), "You don't have permission to update this folder"); !ok {
+		return
 	}
 
 	// Update folder
+	previousName := folder.FolderName
 	folder.FolderName = req.FolderName
-	if err := h.db.Save(&folder).Error; err != nil {
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&folder).Error; err != nil {
+			return err
+		}
+		return services.AddAssetEvent(tx, kafka.NewFolderEvent(kafka.EventFolderUpdated, folder.ID, folder.OwnerID, currentUserID.(uuid.UUID), map[string]string{
+			"previousName": previousName,
+			"folderName":   folder.FolderName,
+		}))
+	})
+	if err != nil {
 		log.Printf("Failed to update folder: %v", err)
 		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to update folder", ""))
 		return
@@ -236,15 +321,13 @@ func (h *FolderHandler) DeleteFolder(c *gin.Context) {
 		return
 	}
 
-	// Only the owner can delete a folder
-	if folder.OwnerID != currentUserID.(uuid.UUID) {
-		log.Printf("User %s attempted to delete folder %s without ownership", currentUserID, folderID)
-		c.JSON(http.StatusForbidden, responses.NewErrorResponse("Only the owner can delete this folder", ""))
+	// Only the owner, or a leader of the team that owns it, can delete a folder
+	if _, ok := authorize(c, h.authz, authz.ActionFolderDelete, authz.Folder(folder), "Only the owner can delete this folder"); !ok {
 		return
 	}
 
 	// Begin transaction for cascading delete
-	tx := h.db.Begin()
+	tx := h.db.WithContext(c.Request.Context()).Begin()
 
 	// Delete shares first
 	if err := tx.Where("folder_id = ?", folderID).Delete(&models.FolderShare{}).Error; err != nil {
@@ -289,12 +372,27 @@ func (h *FolderHandler) DeleteFolder(c *gin.Context) {
 		return
 	}
 
-	tx.Commit()
+	event := kafka.NewFolderEvent(kafka.EventFolderDeleted, folder.ID, folder.OwnerID, currentUserID.(uuid.UUID), map[string]string{
+		"folderName": folder.FolderName,
+		"noteCount":  strconv.Itoa(len(notesInFolder)),
+	})
+	if err := services.AddAssetEvent(tx, event); err != nil {
+		tx.Rollback()
+		log.Printf("Failed to record folder deletion: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to delete folder", ""))
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Failed to commit folder deletion: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to delete folder", ""))
+		return
+	}
 
 	c.JSON(http.StatusOK, responses.NewSuccessResponse("Folder and all its contents deleted successfully", nil))
 }
 
-// ShareFolder shares a folder with another user
+// ShareFolder shares a folder with another user or with every member of a team
 func (h *FolderHandler) ShareFolder(c *gin.Context) {
 	// Get current user ID from context
 	currentUserID, exists := c.Get("user_id")
@@ -315,7 +413,7 @@ func (h *FolderHandler) ShareFolder(c *gin.Context) {
 
 	// Parse request body
 	var req struct {
-		UserID      uuid.UUID          `json:"userId" binding:"required"`
+		sharePrincipal
 		AccessLevel models.AccessLevel `json:"accessLevel" binding:"required"`
 	}
 
@@ -324,6 +422,9 @@ func (h *FolderHandler) ShareFolder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
 		return
 	}
+	if !req.validate(c, h.db) {
+		return
+	}
 
 	// Validate access level
 	if req.AccessLevel != models.Read && req.AccessLevel != models.Write {
@@ -345,18 +446,22 @@ func (h *FolderHandler) ShareFolder(c *gin.Context) {
 	}
 
 	// Check if user is the owner
-	if folder.OwnerID != currentUserID.(uuid.UUID) {
-		log.Printf("User %s attempted to share folder %s without ownership", currentUserID, folderID)
-		c.JSON(http.StatusForbidden, responses.NewErrorResponse("Only the owner can share this folder", ""))
+	if _, ok := authorize(c, h.authz, authz.ActionFolderShare, authz.Folder(folder), "Only the owner can share this folder"); !ok {
 		return
 	}
 
-	// Check if already shared with this user
+	// Check if already shared with this user or team
 	var existingShare models.FolderShare
-	if err := h.db.Where("folder_id = ? AND user_id = ?", folderID, req.UserID).First(&existingShare).Error; err == nil {
+	if err := req.where(h.db.Where("folder_id = ?", folderID)).First(&existingShare).Error; err == nil {
 		// Update existing share
 		existingShare.AccessLevel = req.AccessLevel
-		if err := h.db.Save(&existingShare).Error; err != nil {
+		err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&existingShare).Error; err != nil {
+				return err
+			}
+			return services.AddAssetEvent(tx, folderShareEvent(kafka.EventFolderShared, folder, existingShare, currentUserID.(uuid.UUID)))
+		})
+		if err != nil {
 			log.Printf("Failed to update share: %v", err)
 			c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to update share", ""))
 			return
@@ -375,11 +480,18 @@ func (h *FolderHandler) ShareFolder(c *gin.Context) {
 		ID:          uuid.New(),
 		FolderID:    folderID,
 		UserID:      req.UserID,
+		TeamID:      req.TeamID,
 		AccessLevel: req.AccessLevel,
 		SharedByID:  currentUserID.(uuid.UUID),
 	}
 
-	if err := h.db.Create(&share).Error; err != nil {
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&share).Error; err != nil {
+			return err
+		}
+		return services.AddAssetEvent(tx, folderShareEvent(kafka.EventFolderShared, folder, share, currentUserID.(uuid.UUID)))
+	})
+	if err != nil {
 		log.Printf("Failed to create share: %v", err)
 		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to share folder", ""))
 		return
@@ -392,7 +504,8 @@ func (h *FolderHandler) ShareFolder(c *gin.Context) {
 	c.JSON(http.StatusCreated, responses.NewSuccessResponse("Folder shared successfully", share))
 }
 
-// RevokeSharing revokes folder sharing for a specific user
+// RevokeSharing revokes folder sharing for a specific user, or for a team on
+// the team route
 func (h *FolderHandler) RevokeSharing(c *gin.Context) {
 	// Get current user ID from context
 	currentUserID, exists := c.Get("user_id")
@@ -411,12 +524,9 @@ func (h *FolderHandler) RevokeSharing(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	userIDStr := c.Param("userId")
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		log.Printf("Invalid user ID format: %s", userIDStr)
-		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid user ID format", ""))
+	// Parse the user or team the folder is shared with
+	principal, ok := principalFromParams(c)
+	if !ok {
 		return
 	}
 
@@ -434,17 +544,15 @@ func (h *FolderHandler) RevokeSharing(c *gin.Context) {
 	}
 
 	// Only the owner can revoke sharing
-	if folder.OwnerID != currentUserID.(uuid.UUID) {
-		log.Printf("User %s attempted to revoke sharing for folder %s without ownership", currentUserID, folderID)
-		c.JSON(http.StatusForbidden, responses.NewErrorResponse("Only the owner can revoke sharing", ""))
+	if _, ok := authorize(c, h.authz, authz.ActionFolderShare, authz.Folder(folder), "Only the owner can revoke sharing"); !ok {
 		return
 	}
 
 	// Find share
 	var share models.FolderShare
-	if err := h.db.Where("folder_id = ? AND user_id = ?", folderID, userID).First(&share).Error; err != nil {
+	if err := principal.where(h.db.Where("folder_id = ?", folderID)).First(&share).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Share not found for folder %s and user %s", folderID, userID)
+			log.Printf("Share not found for folder %s and %s", folderID, principal)
 			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Sharing not found", ""))
 			return
 		}
@@ -454,7 +562,13 @@ func (h *FolderHandler) RevokeSharing(c *gin.Context) {
 	}
 
 	// Delete share
-	if err := h.db.Delete(&share).Error; err != nil {
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&share).Error; err != nil {
+			return err
+		}
+		return services.AddAssetEvent(tx, folderShareEvent(kafka.EventFolderShareRevoked, folder, share, currentUserID.(uuid.UUID)))
+	})
+	if err != nil {
 		log.Printf("Failed to delete share: %v", err)
 		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to revoke sharing", ""))
 		return
@@ -462,3 +576,11 @@ func (h *FolderHandler) RevokeSharing(c *gin.Context) {
 
 	c.JSON(http.StatusOK, responses.NewSuccessResponse("Folder sharing revoked successfully", nil))
 }
+
+// folderShareEvent describes a change to who a folder is shared with
+func folderShareEvent(eventType string, folder models.Folder, share models.FolderShare, performedBy uuid.UUID) kafka.AssetEvent {
+	event := kafka.NewFolderEvent(eventType, folder.ID, folder.OwnerID, performedBy, map[string]string{
+		"accessLevel": string(share.AccessLevel),
+	})
+	return withPrincipal(event, share.UserID, share.TeamID)
+}
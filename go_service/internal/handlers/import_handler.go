@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bufio"
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
@@ -17,12 +18,12 @@ import (
 )
 
 type ImportHandler struct {
-	userService *services.UserService
+	userService services.UserDirectory
 }
 
-func NewImportHandler() *ImportHandler {
+func NewImportHandler(users services.UserDirectory) *ImportHandler {
 	return &ImportHandler{
-		userService: services.NewUserService(),
+		userService: users,
 	}
 }
 
@@ -111,7 +112,7 @@ func (h *ImportHandler) ImportUsers(c *gin.Context) {
 	// Start worker pool
 	for i := 0; i < maxWorkers; i++ {
 		wg.Add(1)
-		go h.worker(jobs, results, &wg)
+		go h.worker(c.Request.Context(), jobs, results, &wg)
 	}
 
 	// Producer: Read CSV rows and send to workers
@@ -170,7 +171,7 @@ func (h *ImportHandler) ImportUsers(c *gin.Context) {
 }
 
 // worker processes user creation jobs
-func (h *ImportHandler) worker(jobs <-chan []string, results chan<- ImportResult, wg *sync.WaitGroup) {
+func (h *ImportHandler) worker(ctx context.Context, jobs <-chan []string, results chan<- ImportResult, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for record := range jobs {
@@ -199,7 +200,7 @@ func (h *ImportHandler) worker(jobs <-chan []string, results chan<- ImportResult
 		}
 
 		// Call user service to create user
-		resp, err := h.userService.CreateUser(username, email, password, role)
+		user, err := h.userService.CreateUser(ctx, username, email, password, role)
 		if err != nil {
 			results <- ImportResult{
 				Username: username,
@@ -216,7 +217,7 @@ func (h *ImportHandler) worker(jobs <-chan []string, results chan<- ImportResult
 			Success:  true,
 		}
 
-		log.Printf("Created user: %s (%s) with ID: %s", username, email, resp.User.ID)
+		log.Printf("Created user: %s (%s) with ID: %s", username, email, user.ID)
 	}
 }
 
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"go_service/internal/authz"
+	"go_service/internal/models"
+	"go_service/internal/services"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InvitationHandler struct {
+	invitations *services.InvitationService
+	userService services.UserDirectory
+	authz       *authz.Engine
+}
+
+func NewInvitationHandler(invitations *services.InvitationService, users services.UserDirectory, engine *authz.Engine) *InvitationHandler {
+	return &InvitationHandler{
+		invitations: invitations,
+		userService: users,
+		authz:       engine,
+	}
+}
+
+// invitationResponse is an invitation plus its token, returned only when a token is issued
+type invitationResponse struct {
+	*models.TeamInvitation
+	Token string `json:"token"`
+}
+
+// CreateInvitation invites a user to the team by user ID or email (team leaders only)
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageMembers, authz.Team(teamID), "Only team leaders can invite members"); !ok {
+		return
+	}
+
+	var req struct {
+		UserID *uuid.UUID `json:"userId"`
+		Email  string     `json:"email" binding:"omitempty,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format:", err.Error()))
+		return
+	}
+	if (req.UserID == nil) == (req.Email == "") {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Provide exactly one of userId or email", ""))
+		return
+	}
+
+	email := req.Email
+	if req.UserID != nil {
+		user, err := h.userService.GetUserByID(c.Request.Context(), req.UserID.String())
+		if err != nil {
+			if errors.Is(err, services.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, responses.NewErrorResponse("User not found", ""))
+				return
+			}
+			log.Printf("Failed to look up invitee %s: %v", req.UserID, err)
+			c.JSON(http.StatusServiceUnavailable, responses.NewErrorResponse("User service unavailable", ""))
+			return
+		}
+		email = user.Email
+	}
+
+	subject, _ := currentSubject(c)
+	invitation, token, err := h.invitations.WithContext(c.Request.Context()).Invite(teamID, req.UserID, email, subject.UserID)
+	if err != nil {
+		respondInvitationError(c, "create", err)
+		return
+	}
+
+	log.Printf("User %s invited %s to team %d", subject.UserID, invitationTarget(invitation), teamID)
+	c.JSON(http.StatusCreated, responses.NewSuccessResponse("Invitation created successfully", invitationResponse{invitation, token}))
+}
+
+// ListTeamInvitations lists a team's invitations, optionally filtered by ?status= (team leaders only)
+func (h *InvitationHandler) ListTeamInvitations(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageMembers, authz.Team(teamID), "Only team leaders can view invitations"); !ok {
+		return
+	}
+
+	status := models.InvitationStatus(c.Query("status"))
+	switch status {
+	case "", models.InvitationPending, models.InvitationAccepted, models.InvitationDeclined, models.InvitationCancelled, models.InvitationExpired:
+	default:
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid status", ""))
+		return
+	}
+
+	invitations, err := h.invitations.ListTeamInvitations(teamID, status)
+	if err != nil {
+		log.Printf("Failed to list invitations for team %d: %v", teamID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list invitations", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Invitations retrieved successfully", invitations))
+}
+
+// ResendInvitation issues a fresh token and expiry for a pending or expired invitation (team leaders only)
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	teamID, invitationID, ok := h.teamInvitationParams(c, "You don't have permission to resend invitations")
+	if !ok {
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	invitation, token, err := h.invitations.WithContext(c.Request.Context()).Resend(teamID, invitationID, subject.UserID)
+	if err != nil {
+		respondInvitationError(c, "resend", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Invitation resent successfully", invitationResponse{invitation, token}))
+}
+
+// CancelInvitation withdraws a pending invitation (team leaders only)
+func (h *InvitationHandler) CancelInvitation(c *gin.Context) {
+	teamID, invitationID, ok := h.teamInvitationParams(c, "You don't have permission to cancel invitations")
+	if !ok {
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	invitation, err := h.invitations.WithContext(c.Request.Context()).Cancel(teamID, invitationID, subject.UserID)
+	if err != nil {
+		respondInvitationError(c, "cancel", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Invitation cancelled successfully", invitation))
+}
+
+// ListMyInvitations lists the pending invitations addressed to the caller
+func (h *InvitationHandler) ListMyInvitations(c *gin.Context) {
+	invitee, ok := h.currentInvitee(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.invitations.ListPending(invitee)
+	if err != nil {
+		log.Printf("Failed to list invitations for user %s: %v", invitee.UserID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list invitations", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Invitations retrieved successfully", invitations))
+}
+
+// AcceptInvitation adds the caller to the team of an invitation addressed to them
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	h.respond(c, true)
+}
+
+// DeclineInvitation turns down an invitation addressed to the caller
+func (h *InvitationHandler) DeclineInvitation(c *gin.Context) {
+	h.respond(c, false)
+}
+
+func (h *InvitationHandler) respond(c *gin.Context, accept bool) {
+	invitationID, err := uuid.Parse(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid invitation ID format", ""))
+		return
+	}
+
+	invitee, ok := h.currentInvitee(c)
+	if !ok {
+		return
+	}
+
+	invitation, err := h.invitations.WithContext(c.Request.Context()).Respond(invitationID, invitee, accept)
+	if err != nil {
+		respondInvitationError(c, "answer", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse(respondedMessage(accept), invitation))
+}
+
+// RedeemInvitation accepts, or with "decline": true declines, the invitation a token was issued for
+func (h *InvitationHandler) RedeemInvitation(c *gin.Context) {
+	var req struct {
+		Token   string `json:"token" binding:"required"`
+		Decline bool   `json:"decline"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format:", err.Error()))
+		return
+	}
+
+	invitee, ok := h.currentInvitee(c)
+	if !ok {
+		return
+	}
+
+	invitation, err := h.invitations.WithContext(c.Request.Context()).Redeem(strings.TrimSpace(req.Token), invitee, !req.Decline)
+	if err != nil {
+		respondInvitationError(c, "answer", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse(respondedMessage(!req.Decline), invitation))
+}
+
+// teamInvitationParams parses :teamId and :invitationId and checks the caller leads the team
+func (h *InvitationHandler) teamInvitationParams(c *gin.Context, denial string) (int, uuid.UUID, bool) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return 0, uuid.Nil, false
+	}
+
+	invitationID, err := uuid.Parse(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid invitation ID format", ""))
+		return 0, uuid.Nil, false
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageMembers, authz.Team(teamID), denial); !ok {
+		return 0, uuid.Nil, false
+	}
+
+	return teamID, invitationID, true
+}
+
+// currentInvitee identifies the caller by user ID and the email the user directory has for them
+func (h *InvitationHandler) currentInvitee(c *gin.Context) (services.Invitee, bool) {
+	subject, ok := currentSubject(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return services.Invitee{}, false
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), subject.UserID.String())
+	if err != nil {
+		log.Printf("Failed to look up user %s for invitations: %v", subject.UserID, err)
+		c.JSON(http.StatusServiceUnavailable, responses.NewErrorResponse("User service unavailable", ""))
+		return services.Invitee{}, false
+	}
+
+	return services.Invitee{UserID: subject.UserID, Email: user.Email}, true
+}
+
+func respondedMessage(accept bool) string {
+	if accept {
+		return "Invitation accepted successfully"
+	}
+	return "Invitation declined successfully"
+}
+
+func invitationTarget(invitation *models.TeamInvitation) string {
+	if invitation.InviteeID != nil {
+		return invitation.InviteeID.String()
+	}
+	return invitation.Email
+}
+
+// respondInvitationError maps InvitationService errors to responses
+func respondInvitationError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, responses.NewErrorResponse("Invitation not found", ""))
+	case errors.Is(err, services.ErrNotInvitee):
+		// Indistinguishable from a missing invitation so IDs and tokens can't be probed
+		c.JSON(http.StatusNotFound, responses.NewErrorResponse("Invitation not found", ""))
+	case errors.Is(err, services.ErrInvitationExpired):
+		c.JSON(http.StatusGone, responses.NewErrorResponse("Invitation has expired", "ask a team leader to resend it"))
+	case errors.Is(err, services.ErrInvitationNotPending):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Invitation is no longer pending", ""))
+	case errors.Is(err, services.ErrInvitationExists):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("A pending invitation already exists for this user", ""))
+	case errors.Is(err, services.ErrAlreadyMember):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("User is already a member of this team", ""))
+	case errors.Is(err, services.ErrQuotaExceeded):
+		respondQuotaExceeded(c, err)
+	case errors.Is(err, services.ErrTeamArchived):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Team is archived", ""))
+	default:
+		log.Printf("Failed to %s invitation: %v", action, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to "+action+" invitation", ""))
+	}
+}
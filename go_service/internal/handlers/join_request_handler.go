@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"go_service/internal/authz"
+	"go_service/internal/models"
+	"go_service/internal/services"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type JoinRequestHandler struct {
+	requests *services.JoinRequestService
+	authz    *authz.Engine
+}
+
+func NewJoinRequestHandler(requests *services.JoinRequestService, engine *authz.Engine) *JoinRequestHandler {
+	return &JoinRequestHandler{
+		requests: requests,
+		authz:    engine,
+	}
+}
+
+// JoinTeam joins an open team, or asks to join a team whose leaders approve new members
+func (h *JoinRequestHandler) JoinTeam(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	subject, ok := currentSubject(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return
+	}
+
+	var req struct {
+		Message string `json:"message" binding:"max=500"`
+	}
+	// The body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format:", err.Error()))
+			return
+		}
+	}
+
+	request, err := h.requests.WithContext(c.Request.Context()).Join(teamID, subject.UserID, strings.TrimSpace(req.Message))
+	if err != nil {
+		respondJoinRequestError(c, "join", err)
+		return
+	}
+
+	if request == nil {
+		log.Printf("User %s joined open team %d", subject.UserID, teamID)
+		c.JSON(http.StatusCreated, responses.NewSuccessResponse("Joined team successfully", gin.H{
+			"teamId": teamID,
+			"userId": subject.UserID,
+		}))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, responses.NewSuccessResponse("Join request submitted", request))
+}
+
+// ListJoinRequests lists a team's join requests, optionally filtered by ?status= (team leaders only)
+func (h *JoinRequestHandler) ListJoinRequests(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageMembers, authz.Team(teamID), "Only team leaders can view join requests"); !ok {
+		return
+	}
+
+	status := models.JoinRequestStatus(c.Query("status"))
+	switch status {
+	case "", models.JoinRequestPending, models.JoinRequestApproved, models.JoinRequestRejected, models.JoinRequestCancelled:
+	default:
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid status", ""))
+		return
+	}
+
+	requests, err := h.requests.ListTeamRequests(teamID, status)
+	if err != nil {
+		log.Printf("Failed to list join requests for team %d: %v", teamID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list join requests", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Join requests retrieved successfully", requests))
+}
+
+// ListMyJoinRequests lists the join requests the caller has made
+func (h *JoinRequestHandler) ListMyJoinRequests(c *gin.Context) {
+	subject, ok := currentSubject(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return
+	}
+
+	requests, err := h.requests.ListUserRequests(subject.UserID)
+	if err != nil {
+		log.Printf("Failed to list join requests of user %s: %v", subject.UserID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list join requests", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Join requests retrieved successfully", requests))
+}
+
+// ApproveJoinRequest adds the requester to the team (team leaders only)
+func (h *JoinRequestHandler) ApproveJoinRequest(c *gin.Context) {
+	h.decide(c, true)
+}
+
+// RejectJoinRequest turns a join request down; a reason is required (team leaders only)
+func (h *JoinRequestHandler) RejectJoinRequest(c *gin.Context) {
+	h.decide(c, false)
+}
+
+func (h *JoinRequestHandler) decide(c *gin.Context, approve bool) {
+	teamID, requestID, ok := parseJoinRequestParams(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageMembers, authz.Team(teamID), "Only team leaders can decide on join requests"); !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"max=500"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format:", err.Error()))
+			return
+		}
+	}
+	reason := strings.TrimSpace(req.Reason)
+
+	subject, _ := currentSubject(c)
+	var request *models.TeamJoinRequest
+	var err error
+	if approve {
+		request, err = h.requests.WithContext(c.Request.Context()).Approve(teamID, requestID, subject.UserID, reason)
+	} else {
+		request, err = h.requests.WithContext(c.Request.Context()).Reject(teamID, requestID, subject.UserID, reason)
+	}
+	if err != nil {
+		respondJoinRequestError(c, "decide", err)
+		return
+	}
+
+	message := "Join request rejected"
+	if approve {
+		message = "Join request approved"
+	}
+	c.JSON(http.StatusOK, responses.NewSuccessResponse(message, request))
+}
+
+// CancelJoinRequest withdraws the caller's own pending join request
+func (h *JoinRequestHandler) CancelJoinRequest(c *gin.Context) {
+	teamID, requestID, ok := parseJoinRequestParams(c)
+	if !ok {
+		return
+	}
+
+	subject, ok := currentSubject(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return
+	}
+
+	request, err := h.requests.WithContext(c.Request.Context()).Cancel(teamID, requestID, subject.UserID)
+	if err != nil {
+		respondJoinRequestError(c, "cancel", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Join request cancelled", request))
+}
+
+func parseJoinRequestParams(c *gin.Context) (int, uuid.UUID, bool) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return 0, uuid.Nil, false
+	}
+
+	requestID, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid join request ID format", ""))
+		return 0, uuid.Nil, false
+	}
+
+	return teamID, requestID, true
+}
+
+// respondJoinRequestError maps JoinRequestService errors to responses
+func respondJoinRequestError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, responses.NewErrorResponse("Team or join request not found", ""))
+	case errors.Is(err, services.ErrTeamInviteOnly):
+		c.JSON(http.StatusForbidden, responses.NewErrorResponse("This team is invite-only", "ask a team leader for an invitation"))
+	case errors.Is(err, services.ErrJoinRequestExists):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("You already have a pending request to join this team", ""))
+	case errors.Is(err, services.ErrRejectReasonRequired):
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("A reason is required to reject a join request", ""))
+	case errors.Is(err, services.ErrJoinRequestNotPending):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Join request is no longer pending", ""))
+	case errors.Is(err, services.ErrAlreadyMember):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("User is already a member of this team", ""))
+	case errors.Is(err, services.ErrQuotaExceeded):
+		respondQuotaExceeded(c, err)
+	case errors.Is(err, services.ErrTeamArchived):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Team is archived", ""))
+	default:
+		log.Printf("Failed to %s join request: %v", action, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to "+action+" join request", ""))
+	}
+}
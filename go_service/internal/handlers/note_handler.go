@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
+	"go_service/internal/authz"
+	"go_service/internal/kafka"
 	"go_service/internal/models"
+	"go_service/internal/services"
 	"go_service/pkg/responses"
 
 	"github.com/gin-gonic/gin"
@@ -13,11 +17,13 @@ import (
 )
 
 type NoteHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	authz  *authz.Engine
+	quotas *services.QuotaService
 }
 
-func NewNoteHandler(db *gorm.DB) *NoteHandler {
-	return &NoteHandler{db: db}
+func NewNoteHandler(db *gorm.DB, engine *authz.Engine, quotas *services.QuotaService) *NoteHandler {
+	return &NoteHandler{db: db, authz: engine, quotas: quotas}
 }
 
 // CreateNote creates a new note inside a folder
@@ -65,21 +71,7 @@ func (h *NoteHandler) CreateNote(c *gin.Context) {
 	}
 
 	// Check if user is owner or has write access
-	hasWriteAccess := false
-	if folder.OwnerID == currentUserID.(uuid.UUID) {
-		hasWriteAccess = true
-	} else {
-		// Check for write permission
-		var folderShare models.FolderShare
-		if err := h.db.Where("folder_id = ? AND user_id = ? AND access_level = ?",
-			folderID, currentUserID, models.Write).First(&folderShare).Error; err == nil {
-			hasWriteAccess = true
-		}
-	}
-
-	if !hasWriteAccess {
-		log.Printf("User %s attempted to create note in folder %s without write permission", currentUserID, folderID)
-		c.JSON(http.StatusForbidden, responses.NewErrorResponse("You don't have permission to create notes in this folder", ""))
+	if _, ok := authorize(c, h.authz, authz.ActionFolderCreateNote, authz.Folder(folder), "You don't have permission to create notes in this folder"); !ok {
 		return
 	}
 
@@ -92,7 +84,24 @@ func (h *NoteHandler) CreateNote(c *gin.Context) {
 		FolderID: folderID,
 	}
 
-	if err := h.db.Create(&note).Error; err != nil {
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// Members of a team may only own so many notes, and team folders only hold so much
+		if err := h.quotas.CheckOwnedAssets(tx, note.OwnerID, services.QuotaNotesPerMember); err != nil {
+			return err
+		}
+		if err := h.quotas.CheckNoteBytes(tx, note.FolderID, len(note.Content)); err != nil {
+			return err
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		return services.AddAssetEvent(tx, kafka.NewNoteEvent(kafka.EventNoteCreated, note.ID, note.FolderID, note.OwnerID, note.OwnerID, nil))
+	})
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		respondQuotaExceeded(c, err)
+		return
+	}
+	if err != nil {
 		log.Printf("Failed to create note: %v", err)
 		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to create note", ""))
 		return
@@ -104,7 +113,7 @@ func (h *NoteHandler) CreateNote(c *gin.Context) {
 // GetNote retrieves a note
 func (h *NoteHandler) GetNote(c *gin.Context) {
 	// Get current user ID from context
-	currentUserID, exists := c.Get("user_id")
+	_, exists := c.Get("user_id")
 	if !exists {
 		log.Println("Unauthorized attempt to access note: missing user_id")
 		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
@@ -133,38 +142,34 @@ func (h *NoteHandler) GetNote(c *gin.Context) {
 		return
 	}
 
-	// Check permissions - owner has access directly
-	if note.OwnerID == currentUserID.(uuid.UUID) {
-		c.JSON(http.StatusOK, responses.NewSuccessResponse("Note retrieved successfully", note))
+	decision, ok := authorize(c, h.authz, authz.ActionNoteRead, authz.Note(note), "You don't have permission to access this note")
+	if !ok {
 		return
 	}
 
-	// Check if there's direct note sharing
-	var noteShare models.NoteShare
-	if err := h.db.Where("note_id = ? AND user_id = ?", noteID, currentUserID).First(&noteShare).Error; err == nil {
-		c.JSON(http.StatusOK, responses.NewSuccessResponse("Note retrieved successfully", gin.H{
+	switch decision.Grant.Via {
+	case "note_share", "folder_share":
+		data := gin.H{
 			"note":        note,
-			"accessLevel": noteShare.AccessLevel,
-			"sharedBy":    noteShare.SharedByID,
-		}))
-		return
-	}
-
-	// Check folder sharing
-	var folderShare models.FolderShare
-	if err := h.db.Where("folder_id = ? AND user_id = ?", note.FolderID, currentUserID).First(&folderShare).Error; err == nil {
+			"accessLevel": decision.Grant.AccessLevel,
+			"sharedBy":    decision.Grant.SharedByID,
+		}
+		if decision.Grant.Via == "folder_share" {
+			data["folderSharing"] = true
+		}
+		if decision.Grant.ShareTeamID != 0 {
+			data["sharedWithTeam"] = decision.Grant.ShareTeamID
+		}
+		c.JSON(http.StatusOK, responses.NewSuccessResponse("Note retrieved successfully", data))
+	case "team_folder":
 		c.JSON(http.StatusOK, responses.NewSuccessResponse("Note retrieved successfully", gin.H{
-			"note":          note,
-			"accessLevel":   folderShare.AccessLevel,
-			"sharedBy":      folderShare.SharedByID,
-			"folderSharing": true,
+			"note":        note,
+			"accessLevel": decision.Grant.AccessLevel,
+			"teamRole":    decision.Grant.TeamRole,
 		}))
-		return
+	default:
+		c.JSON(http.StatusOK, responses.NewSuccessResponse("Note retrieved successfully", note))
 	}
-
-	// No access
-	log.Printf("User %s attempted to access note %s without permission", currentUserID, noteID)
-	c.JSON(http.StatusForbidden, responses.NewErrorResponse("You don't have permission to access this note", ""))
 }
 
 // UpdateNote updates a note
@@ -212,42 +217,37 @@ func (h *NoteHandler) UpdateNote(c *gin.Context) {
 	}
 
 	// Check write permissions
-	hasWriteAccess := false
-
-	// Owner has write access
-	if note.OwnerID == currentUserID.(uuid.UUID) {
-		hasWriteAccess = true
-	} else {
-		// Check direct note sharing
-		var noteShare models.NoteShare
-		if err := h.db.Where("note_id = ? AND user_id = ? AND access_level = ?",
-			noteID, currentUserID, models.Write).First(&noteShare).Error; err == nil {
-			hasWriteAccess = true
-		} else {
-			// Check folder sharing
-			var folderShare models.FolderShare
-			if err := h.db.Where("folder_id = ? AND user_id = ? AND access_level = ?",
-				note.FolderID, currentUserID, models.Write).First(&folderShare).Error; err == nil {
-				hasWriteAccess = true
-			}
-		}
-	}
-
-	if !hasWriteAccess {
-		log.Printf("User %s attempted to update note %s without write permission", currentUserID, noteID)
-		c.JSON(http.StatusForbidden, responses.NewErrorResponse("You don't have permission to update this note", ""))
+	if _, ok := authorize(c, h.authz, authz.ActionNoteWrite, authz.Note(note), "You don't have permission to update this note"); !ok {
 		return
 	}
 
 	// Update note
+	grown := 0
 	if req.Title != "" {
 		note.Title = req.Title
 	}
 	if req.Content != "" {
+		grown = len(req.Content) - len(note.Content)
 		note.Content = req.Content
 	}
 
-	if err := h.db.Save(&note).Error; err != nil {
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// Growing a note in a team folder counts against the team's storage
+		if grown > 0 {
+			if err := h.quotas.CheckNoteBytes(tx, note.FolderID, grown); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&note).Error; err != nil {
+			return err
+		}
+		return services.AddAssetEvent(tx, kafka.NewNoteEvent(kafka.EventNoteUpdated, note.ID, note.FolderID, note.OwnerID, currentUserID.(uuid.UUID), nil))
+	})
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		respondQuotaExceeded(c, err)
+		return
+	}
+	if err != nil {
 		log.Printf("Failed to update note: %v", err)
 		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to update note", ""))
 		return
@@ -288,20 +288,13 @@ func (h *NoteHandler) DeleteNote(c *gin.Context) {
 		return
 	}
 
-	// Check if user is owner
-	if note.OwnerID != currentUserID.(uuid.UUID) {
-		// Check if user has write access to the folder
-		var folderShare models.FolderShare
-		if err := h.db.Where("folder_id = ? AND user_id = ? AND access_level = ?",
-			note.FolderID, currentUserID, models.Write).First(&folderShare).Error; err != nil {
-			log.Printf("User %s attempted to delete note %s without ownership or write permission", currentUserID, noteID)
-			c.JSON(http.StatusForbidden, responses.NewErrorResponse("You don't have permission to delete this note", ""))
-			return
-		}
+	// Check if user is owner or has write access to the folder
+	if _, ok := authorize(c, h.authz, authz.ActionNoteDelete, authz.Note(note), "You don't have permission to delete this note"); !ok {
+		return
 	}
 
 	// Begin transaction
-	tx := h.db.Begin()
+	tx := h.db.WithContext(c.Request.Context()).Begin()
 
 	// Delete note shares first
 	if err := tx.Where("note_id = ?", noteID).Delete(&models.NoteShare{}).Error; err != nil {
@@ -319,12 +312,26 @@ func (h *NoteHandler) DeleteNote(c *gin.Context) {
 		return
 	}
 
-	tx.Commit()
+	event := kafka.NewNoteEvent(kafka.EventNoteDeleted, note.ID, note.FolderID, note.OwnerID, currentUserID.(uuid.UUID), map[string]string{
+		"title": note.Title,
+	})
+	if err := services.AddAssetEvent(tx, event); err != nil {
+		tx.Rollback()
+		log.Printf("Failed to record note deletion: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to delete note", ""))
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Failed to commit note deletion: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to delete note", ""))
+		return
+	}
 
 	c.JSON(http.StatusOK, responses.NewSuccessResponse("Note deleted successfully", nil))
 }
 
-// ShareNote shares a note with another user
+// ShareNote shares a note with another user or with every member of a team
 func (h *NoteHandler) ShareNote(c *gin.Context) {
 	// Get current user ID from context
 	currentUserID, exists := c.Get("user_id")
@@ -345,7 +352,7 @@ func (h *NoteHandler) ShareNote(c *gin.Context) {
 
 	// Parse request body
 	var req struct {
-		UserID      uuid.UUID          `json:"userId" binding:"required"`
+		sharePrincipal
 		AccessLevel models.AccessLevel `json:"accessLevel" binding:"required"`
 	}
 
@@ -354,6 +361,9 @@ func (h *NoteHandler) ShareNote(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
 		return
 	}
+	if !req.validate(c, h.db) {
+		return
+	}
 
 	// Validate access level
 	if req.AccessLevel != models.Read && req.AccessLevel != models.Write {
@@ -381,21 +391,22 @@ func (h *NoteHandler) ShareNote(c *gin.Context) {
 	}
 
 	// Check if user is the owner
-	if note.OwnerID != currentUserID.(uuid.UUID) {
-		log.Printf("User %s attempted to share note %s without ownership", currentUserID, noteID)
-		c.JSON(http.StatusForbidden, gin.H{
-			"success": false,
-			"error":   "Only the owner can share this note",
-		})
+	if _, ok := authorize(c, h.authz, authz.ActionNoteShare, authz.Note(note), "Only the owner can share this note"); !ok {
 		return
 	}
 
-	// Check if already shared with this user
+	// Check if already shared with this user or team
 	var existingShare models.NoteShare
-	if err := h.db.Where("note_id = ? AND user_id = ?", noteID, req.UserID).First(&existingShare).Error; err == nil {
+	if err := req.where(h.db.Where("note_id = ?", noteID)).First(&existingShare).Error; err == nil {
 		// Update existing share
 		existingShare.AccessLevel = req.AccessLevel
-		if err := h.db.Save(&existingShare).Error; err != nil {
+		err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&existingShare).Error; err != nil {
+				return err
+			}
+			return services.AddAssetEvent(tx, noteShareEvent(kafka.EventNoteShared, note, existingShare, currentUserID.(uuid.UUID)))
+		})
+		if err != nil {
 			log.Printf("Failed to update share: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
@@ -424,11 +435,18 @@ func (h *NoteHandler) ShareNote(c *gin.Context) {
 		ID:          uuid.New(),
 		NoteID:      noteID,
 		UserID:      req.UserID,
+		TeamID:      req.TeamID,
 		AccessLevel: req.AccessLevel,
 		SharedByID:  currentUserID.(uuid.UUID),
 	}
 
-	if err := h.db.Create(&share).Error; err != nil {
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&share).Error; err != nil {
+			return err
+		}
+		return services.AddAssetEvent(tx, noteShareEvent(kafka.EventNoteShared, note, share, currentUserID.(uuid.UUID)))
+	})
+	if err != nil {
 		log.Printf("Failed to create share: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -444,7 +462,8 @@ func (h *NoteHandler) ShareNote(c *gin.Context) {
 	})
 }
 
-// RevokeNoteSharing revokes note sharing for a specific user
+// RevokeNoteSharing revokes note sharing for a specific user, or for a team
+// on the team route
 func (h *NoteHandler) RevokeNoteSharing(c *gin.Context) {
 	// Get current user ID from context
 	currentUserID, exists := c.Get("user_id")
@@ -469,15 +488,9 @@ func (h *NoteHandler) RevokeNoteSharing(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	userIDStr := c.Param("userId")
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		log.Printf("Invalid user ID format: %s", userIDStr)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error":   "Invalid user ID format",
-		})
+	// Parse the user or team the note is shared with
+	principal, ok := principalFromParams(c)
+	if !ok {
 		return
 	}
 
@@ -501,20 +514,15 @@ func (h *NoteHandler) RevokeNoteSharing(c *gin.Context) {
 	}
 
 	// Only the owner can revoke sharing
-	if note.OwnerID != currentUserID.(uuid.UUID) {
-		log.Printf("User %s attempted to revoke sharing for note %s without ownership", currentUserID, noteID)
-		c.JSON(http.StatusForbidden, gin.H{
-			"success": false,
-			"error":   "Only the owner can revoke sharing",
-		})
+	if _, ok := authorize(c, h.authz, authz.ActionNoteShare, authz.Note(note), "Only the owner can revoke sharing"); !ok {
 		return
 	}
 
 	// Find share
 	var share models.NoteShare
-	if err := h.db.Where("note_id = ? AND user_id = ?", noteID, userID).First(&share).Error; err != nil {
+	if err := principal.where(h.db.Where("note_id = ?", noteID)).First(&share).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Share not found for note %s and user %s", noteID, userID)
+			log.Printf("Share not found for note %s and %s", noteID, principal)
 			c.JSON(http.StatusNotFound, gin.H{
 				"success": false,
 				"error":   "Sharing not found",
@@ -530,7 +538,13 @@ func (h *NoteHandler) RevokeNoteSharing(c *gin.Context) {
 	}
 
 	// Delete share
-	if err := h.db.Delete(&share).Error; err != nil {
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&share).Error; err != nil {
+			return err
+		}
+		return services.AddAssetEvent(tx, noteShareEvent(kafka.EventNoteShareRevoked, note, share, currentUserID.(uuid.UUID)))
+	})
+	if err != nil {
 		log.Printf("Failed to delete share: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -544,3 +558,11 @@ func (h *NoteHandler) RevokeNoteSharing(c *gin.Context) {
 		"message": "Note sharing revoked successfully",
 	})
 }
+
+// noteShareEvent describes a change to who a note is shared with
+func noteShareEvent(eventType string, note models.Note, share models.NoteShare, performedBy uuid.UUID) kafka.AssetEvent {
+	event := kafka.NewNoteEvent(eventType, note.ID, note.FolderID, note.OwnerID, performedBy, map[string]string{
+		"accessLevel": string(share.AccessLevel),
+	})
+	return withPrincipal(event, share.UserID, share.TeamID)
+}
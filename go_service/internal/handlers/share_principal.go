@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// sharePrincipal is who a folder or note is shared with: a single user or
+// every member of a team
+type sharePrincipal struct {
+	UserID *uuid.UUID `json:"userId"`
+	TeamID *int       `json:"teamId"`
+}
+
+// validate checks that exactly one principal is given and that a team
+// principal exists and is active, writing the response if not
+func (p sharePrincipal) validate(c *gin.Context, db *gorm.DB) bool {
+	if (p.UserID == nil) == (p.TeamID == nil) {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Provide either userId or teamId", ""))
+		return false
+	}
+	if p.TeamID == nil {
+		return true
+	}
+
+	var team models.Team
+	if err := db.First(&team, *p.TeamID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Team not found", ""))
+			return false
+		}
+		log.Printf("Database error when finding team: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify team", ""))
+		return false
+	}
+	if team.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Team is archived", "restore the team first"))
+		return false
+	}
+	return true
+}
+
+// where narrows a share query to this principal's share
+func (p sharePrincipal) where(query *gorm.DB) *gorm.DB {
+	if p.TeamID != nil {
+		return query.Where("team_id = ?", *p.TeamID)
+	}
+	return query.Where("user_id = ?", *p.UserID)
+}
+
+// String names the principal in log lines
+func (p sharePrincipal) String() string {
+	if p.TeamID != nil {
+		return "team " + strconv.Itoa(*p.TeamID)
+	}
+	return "user " + p.UserID.String()
+}
+
+// principalFromParams reads the principal a revoke route names: :teamId on
+// the team route, :userId otherwise
+func principalFromParams(c *gin.Context) (sharePrincipal, bool) {
+	if teamIDStr := c.Param("teamId"); teamIDStr != "" {
+		teamID, err := strconv.Atoi(teamIDStr)
+		if err != nil {
+			log.Printf("Invalid team ID format: %s", teamIDStr)
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid team ID format", ""))
+			return sharePrincipal{}, false
+		}
+		return sharePrincipal{TeamID: &teamID}, true
+	}
+
+	userIDStr := c.Param("userId")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		log.Printf("Invalid user ID format: %s", userIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid user ID format", ""))
+		return sharePrincipal{}, false
+	}
+	return sharePrincipal{UserID: &userID}, true
+}
+
+// withPrincipal adds the share's principal to an asset event
+func withPrincipal(event kafka.AssetEvent, userID *uuid.UUID, teamID *int) kafka.AssetEvent {
+	if userID != nil {
+		return event.WithTarget(*userID)
+	}
+	if teamID != nil {
+		if event.Details == nil {
+			event.Details = map[string]string{}
+		}
+		event.Details["teamId"] = strconv.Itoa(*teamID)
+	}
+	return event
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_service/internal/authz"
+	"go_service/internal/models"
+	"go_service/internal/repositories"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// activityEntry is an activity feed entry with the usernames of the people involved
+type activityEntry struct {
+	models.TeamActivity
+	PerformedByUsername string `json:"performedByUsername,omitempty"`
+	TargetUsername      string `json:"targetUsername,omitempty"`
+}
+
+// GetTeamActivity returns the team's activity feed, newest first (team members
+// and managers). Filters: ?type= (comma-separated event types), ?actor= (user
+// ID), ?from= and ?to= (RFC 3339); page with ?limit= and ?cursor=.
+func (h *TeamHandler) GetTeamActivity(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamViewDetails, authz.Team(teamID), "You don't have permission to view this team"); !ok {
+		return
+	}
+
+	q, ok := parseActivityQuery(c)
+	if !ok {
+		return
+	}
+	q.TeamID = teamID
+
+	page, err := h.service.ListActivity(q)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid cursor", "start again without a cursor"))
+			return
+		}
+		log.Printf("Failed to load activity of team %d: %v", teamID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to load team activity", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team activity retrieved successfully", gin.H{
+		"teamId":     teamID,
+		"activities": h.withUsernames(c, page.Activities),
+		"nextCursor": page.NextCursor,
+	}))
+}
+
+// parseActivityQuery reads the feed filters, writing a 400 if one is malformed
+func parseActivityQuery(c *gin.Context) (repositories.ActivityQuery, bool) {
+	var q repositories.ActivityQuery
+	var ok bool
+	for _, eventType := range strings.Split(c.Query("type"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			q.EventTypes = append(q.EventTypes, strings.ToUpper(eventType))
+		}
+	}
+
+	if actor := c.Query("actor"); actor != "" {
+		actorID, err := uuid.Parse(actor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid actor; use a user ID", ""))
+			return q, false
+		}
+		q.ActorID = &actorID
+	}
+
+	if q.From, ok = parseOptionalTime(c, "from"); !ok {
+		return q, false
+	}
+	if q.To, ok = parseOptionalTime(c, "to"); !ok {
+		return q, false
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("from must be before to", ""))
+		return q, false
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid limit", ""))
+			return q, false
+		}
+		q.Limit = n
+	}
+	q.Cursor = c.Query("cursor")
+
+	return q, true
+}
+
+// parseOptionalTime reads an RFC 3339 query parameter, nil when absent
+func parseOptionalTime(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid "+name+"; use an RFC 3339 timestamp", ""))
+		return nil, false
+	}
+	return &at, true
+}
+
+// withUsernames adds the usernames of performers and targets from the user
+// directory. The feed is still served, without names, if the directory is down.
+func (h *TeamHandler) withUsernames(c *gin.Context, activities []models.TeamActivity) []activityEntry {
+	entries := make([]activityEntry, len(activities))
+	ids := make([]string, 0, len(activities))
+	seen := make(map[uuid.UUID]bool, len(activities))
+	for i, activity := range activities {
+		entries[i] = activityEntry{TeamActivity: activity}
+		for _, id := range []*uuid.UUID{&activity.PerformedBy, activity.TargetUserID} {
+			if id != nil && *id != uuid.Nil && !seen[*id] {
+				seen[*id] = true
+				ids = append(ids, id.String())
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return entries
+	}
+
+	users, err := h.userService.GetUsersByIDs(c.Request.Context(), ids)
+	if err != nil {
+		log.Printf("Failed to look up %d users for the activity feed: %v", len(ids), err)
+		return entries
+	}
+	for i := range entries {
+		if user, ok := users[entries[i].PerformedBy.String()]; ok {
+			entries[i].PerformedByUsername = user.Username
+		}
+		if target := entries[i].TargetUserID; target != nil {
+			if user, ok := users[target.String()]; ok {
+				entries[i].TargetUsername = user.Username
+			}
+		}
+	}
+	return entries
+}
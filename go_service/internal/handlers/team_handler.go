@@ -1,15 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
-	"go_service/internal/kafka"
+	"go_service/internal/authz"
 	"go_service/internal/models"
 	"go_service/internal/redisclient"
+	"go_service/internal/repositories"
 	"go_service/internal/services"
 	"go_service/pkg/responses"
 
@@ -20,30 +22,28 @@ import (
 
 type TeamHandler struct {
 	db          *gorm.DB
-	userService *services.UserService
+	userService services.UserDirectory
 	service     *services.TeamService
+	quotas      *services.QuotaService
 
-	producer    *kafka.Producer
 	redisClient *redisclient.TeamCache
+	authz       *authz.Engine
 }
 
-func NewTeamHandler(db *gorm.DB, producer *kafka.Producer, redisClient *redisclient.TeamCache) *TeamHandler {
+func NewTeamHandler(db *gorm.DB, teams *services.TeamService, quotas *services.QuotaService, redisClient *redisclient.TeamCache, users services.UserDirectory, engine *authz.Engine) *TeamHandler {
 	return &TeamHandler{
 		db:          db,
-		userService: services.NewUserService(),
-		service:     services.NewTeamService(db, producer, redisClient),
-		producer:    producer,
+		userService: users,
+		service:     teams,
+		quotas:      quotas,
 		redisClient: redisClient,
+		authz:       engine,
 	}
 }
 
 // CreateTeam creates a new team with members (only managers can create teams)
 func (h *TeamHandler) CreateTeam(c *gin.Context) {
-	role, exists := c.Get("role")
-
-	if !exists || strings.ToUpper(role.(string)) != "MANAGER" {
-		c.JSON(http.StatusForbidden, responses.NewErrorResponse("Unauthorized", "Insufficient permissions"))
-
+	if _, ok := authorize(c, h.authz, authz.ActionTeamCreate, authz.System(), "Only managers can create teams"); !ok {
 		return
 	}
 
@@ -59,7 +59,7 @@ func (h *TeamHandler) CreateTeam(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	creatorID := userID.(uuid.UUID)
 
-	team, failedMembers, err := h.service.CreateTeam(req.TeamName, req.UserIDs, creatorID)
+	team, failedMembers, err := h.service.WithContext(c.Request.Context()).CreateTeam(req.TeamName, req.UserIDs, creatorID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to create team:", err.Error()))
 		return
@@ -73,6 +73,280 @@ func (h *TeamHandler) CreateTeam(c *gin.Context) {
 	c.JSON(http.StatusCreated, responses.NewSuccessResponse("Team created successfully", response))
 }
 
+// ListTeams lists the teams the caller belongs to, or every team for managers.
+// Supports search, sort (name|createdAt), order (asc|desc), limit, cursor and,
+// for managers, mine=true to see only their own teams. archived=true lists
+// archived teams instead of active ones, and joinable=true lists the open and
+// request-to-join teams the caller is not on. label= (repeatable) and
+// field[key]=value keep only teams with all the given labels and field values.
+func (h *TeamHandler) ListTeams(c *gin.Context) {
+	subject, ok := currentSubject(c)
+	if !ok {
+		log.Println("Unauthorized attempt to list teams: missing user_id")
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return
+	}
+
+	query := repositories.TeamListQuery{
+		ViewerID: subject.UserID,
+		OnlyMine: !subject.IsManager() || c.Query("mine") == "true",
+		Archived: c.Query("archived") == "true",
+		Joinable: c.Query("joinable") == "true",
+		Search:   c.Query("search"),
+		Sort:     c.DefaultQuery("sort", repositories.TeamSortName),
+		Cursor:   c.Query("cursor"),
+	}
+
+	// Teams the caller could join are by definition not theirs
+	if query.Joinable {
+		query.OnlyMine = false
+		query.Archived = false
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid order. Must be 'asc' or 'desc'", ""))
+		return
+	}
+	if query.Sort != repositories.TeamSortName && query.Sort != repositories.TeamSortCreatedAt {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid sort. Must be 'name' or 'createdAt'", ""))
+		return
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > 100 {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid limit. Must be between 1 and 100", ""))
+			return
+		}
+		query.Limit = limit
+	}
+	for _, label := range c.QueryArray("label") {
+		if label = strings.ToLower(strings.TrimSpace(label)); label != "" {
+			query.Labels = append(query.Labels, label)
+		}
+	}
+	fields, err := h.service.FieldFilters(c.QueryMap("field"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownField) || errors.Is(err, services.ErrInvalidFieldValue) {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid field filter", err.Error()))
+			return
+		}
+		log.Printf("Failed to load team fields: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list teams", ""))
+		return
+	}
+	query.Fields = fields
+
+	page, err := h.service.ListTeams(query)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid cursor", "cursors are only valid with the same sort and order"))
+			return
+		}
+		log.Printf("Failed to list teams: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list teams", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Teams retrieved successfully", page))
+}
+
+// UpdateTeam renames a team and/or changes its join policy (team leaders only)
+func (h *TeamHandler) UpdateTeam(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamUpdate, authz.Team(teamID), "Only team leaders can update the team"); !ok {
+		return
+	}
+
+	var req struct {
+		TeamName   *string            `json:"teamName" binding:"omitempty,max=150"`
+		JoinPolicy *models.JoinPolicy `json:"joinPolicy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format:", err.Error()))
+		return
+	}
+	if req.TeamName == nil && req.JoinPolicy == nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Nothing to update", "provide teamName and/or joinPolicy"))
+		return
+	}
+
+	update := services.TeamUpdate{JoinPolicy: req.JoinPolicy}
+	if req.TeamName != nil {
+		name := strings.TrimSpace(*req.TeamName)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Team name cannot be empty", ""))
+			return
+		}
+		update.TeamName = &name
+	}
+	if req.JoinPolicy != nil && !req.JoinPolicy.Valid() {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid joinPolicy. Must be 'open', 'request' or 'invite_only'", ""))
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	team, err := h.service.WithContext(c.Request.Context()).UpdateTeam(teamID, update, subject.UserID)
+	if err != nil {
+		respondTeamChangeError(c, teamID, "update", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team updated successfully", team))
+}
+
+// ArchiveTeam hides a team and sets its roster aside until it is restored (only managers)
+func (h *TeamHandler) ArchiveTeam(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamArchive, authz.Team(teamID), "Only managers can archive teams"); !ok {
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	team, err := h.service.WithContext(c.Request.Context()).ArchiveTeam(c.Request.Context(), teamID, subject.UserID)
+	if err != nil {
+		respondTeamChangeError(c, teamID, "archive", err)
+		return
+	}
+
+	log.Printf("Team %d archived by %s", teamID, subject.UserID)
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team archived successfully", team))
+}
+
+// RestoreTeam brings an archived team and its roster back (only managers)
+func (h *TeamHandler) RestoreTeam(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamArchive, authz.Team(teamID), "Only managers can restore teams"); !ok {
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	team, err := h.service.WithContext(c.Request.Context()).RestoreTeam(c.Request.Context(), teamID, subject.UserID)
+	if err != nil {
+		respondTeamChangeError(c, teamID, "restore", err)
+		return
+	}
+
+	log.Printf("Team %d restored by %s", teamID, subject.UserID)
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team restored successfully", team))
+}
+
+// DeleteTeam permanently removes a team and its roster (only managers)
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamDelete, authz.Team(teamID), "Only managers can delete teams"); !ok {
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	team, err := h.service.WithContext(c.Request.Context()).DeleteTeam(c.Request.Context(), teamID, subject.UserID)
+	if err != nil {
+		respondTeamChangeError(c, teamID, "delete", err)
+		return
+	}
+
+	log.Printf("Team %d (%s) deleted by %s", team.ID, team.TeamName, subject.UserID)
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team deleted successfully", gin.H{
+		"teamId":   team.ID,
+		"teamName": team.TeamName,
+	}))
+}
+
+// parseTeamID reads the :teamId path parameter, responding 400 when it is malformed
+func parseTeamID(c *gin.Context) (int, bool) {
+	teamIDStr := c.Param("teamId")
+	teamID, err := strconv.ParseUint(teamIDStr, 10, 31)
+	if err != nil {
+		log.Printf("Invalid team ID format: %s", teamIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid team ID format", ""))
+		return 0, false
+	}
+	return int(teamID), true
+}
+
+// respondTeamChangeError maps TeamService errors to responses
+func respondTeamChangeError(c *gin.Context, teamID int, action string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		log.Printf("Team not found: %d", teamID)
+		c.JSON(http.StatusNotFound, responses.NewErrorResponse("Team not found", ""))
+	case errors.Is(err, services.ErrTeamArchived):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Team is archived", "restore the team first"))
+	case errors.Is(err, services.ErrTeamNotArchived):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Team is not archived", ""))
+	case errors.Is(err, services.ErrTeamNameTaken):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("A team with this name already exists", ""))
+	case errors.Is(err, services.ErrTeamCycle):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("A team cannot be placed under itself or one of its sub-teams", ""))
+	case errors.Is(err, services.ErrTeamNotAttached):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Team has no parent team", ""))
+	case errors.Is(err, services.ErrOwnerRole):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Ownership cannot be granted or removed by a role change", ""))
+	case errors.Is(err, services.ErrNotLeader):
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("User is not a manager of this team", ""))
+	case errors.Is(err, services.ErrLastLeader):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Cannot remove the only team leader. Assign another leader first.", ""))
+	case errors.Is(err, services.ErrQuotaExceeded):
+		respondQuotaExceeded(c, err)
+	case errors.Is(err, services.ErrUnknownField), errors.Is(err, services.ErrInvalidFieldValue), errors.Is(err, services.ErrInvalidLabel):
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid team metadata", err.Error()))
+	default:
+		log.Printf("Failed to %s team %d: %v", action, teamID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse(fmt.Sprintf("Failed to %s team", action), ""))
+	}
+}
+
+// GetTeam returns a team with its member and leader counts (members and managers)
+func (h *TeamHandler) GetTeam(c *gin.Context) {
+	teamIDStr := c.Param("teamId")
+	teamID, err := strconv.ParseUint(teamIDStr, 10, 64)
+	if err != nil {
+		log.Printf("Invalid team ID format: %s", teamIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid team ID format", ""))
+		return
+	}
+
+	subject, _ := currentSubject(c)
+
+	// Looked up first so a missing team is a 404 rather than a denial
+	team, err := h.service.GetTeam(int(teamID), subject.UserID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Printf("Team not found: %d", teamID)
+			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Team not found", ""))
+			return
+		}
+		log.Printf("Database error when finding team: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to retrieve team", ""))
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamViewDetails, authz.Team(int(teamID)), "You don't have permission to view this team"); !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team retrieved successfully", team))
+}
+
 func (h *TeamHandler) AddMemberToTeam(c *gin.Context) {
 	currentUserID, exists := c.Get("user_id")
 	if !exists {
@@ -115,21 +389,11 @@ func (h *TeamHandler) AddMemberToTeam(c *gin.Context) {
 	}
 
 	// Kiểm tra xem người dùng hiện tại có phải là leader của team hay không
-	var leaderRoster models.Roster
-	if err := h.db.Where("\"teamId\" = ? AND \"userId\" = ? AND \"isLeader\" = ?", teamID, currentUserID, true).First(&leaderRoster).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.Printf("User %s attempted to add member to team %d without leadership permission", currentUserID, teamID)
-			c.JSON(http.StatusForbidden, gin.H{
-				"success": false,
-				"error":   "Only team leaders can add members",
-			})
-			return
-		}
-		log.Printf("Database error when checking leadership: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "Failed to verify team leadership",
-		})
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageMembers, authz.Team(team.ID), "Only team leaders can add members"); !ok {
+		return
+	}
+	if team.ArchivedAt != nil {
+		respondTeamChangeError(c, team.ID, "add members to", services.ErrTeamArchived)
 		return
 	}
 
@@ -147,8 +411,14 @@ func (h *TeamHandler) AddMemberToTeam(c *gin.Context) {
 		return
 	}
 
+	// Tra cứu tất cả user trong một request
+	users, ok := h.lookupUsers(c, req.UserIDs)
+	if !ok {
+		return
+	}
+
 	// Bắt đầu transaction
-	tx := h.db.Begin()
+	tx := h.db.WithContext(c.Request.Context()).Begin()
 
 	type AddResult struct {
 		UserID     uuid.UUID `json:"userId"`
@@ -161,6 +431,7 @@ func (h *TeamHandler) AddMemberToTeam(c *gin.Context) {
 	addedCount := 0
 	existingCount := 0
 	failedCount := 0
+	var quotaErr error
 
 	processedUsers := make(map[uuid.UUID]bool)
 
@@ -178,8 +449,8 @@ func (h *TeamHandler) AddMemberToTeam(c *gin.Context) {
 		processedUsers[userID] = true
 
 		// Kiểm tra user tồn tại qua service
-		userResp, err := h.userService.GetUserByID(userID.String())
-		if err != nil {
+		user, found := users[userID.String()]
+		if !found {
 			results = append(results, AddResult{
 				UserID:     userID,
 				Status:     "user_not_found",
@@ -189,36 +460,29 @@ func (h *TeamHandler) AddMemberToTeam(c *gin.Context) {
 			continue
 		}
 
-		// Kiểm tra xem user đã là thành viên của team chưa
-		var existingMember models.Roster
-		if err := h.db.Where("\"teamId\" = ? AND \"userId\" = ?", teamID, userID).First(&existingMember).Error; err == nil {
-			results = append(results, AddResult{
-				UserID:     userID,
-				Username:   userResp.User.Username,
-				Status:     "already_member",
-				StatusCode: http.StatusConflict,
-			})
-			existingCount++
-			continue
-		} else if err != gorm.ErrRecordNotFound {
-			log.Printf("Database error when checking existing membership: %v", err)
-			results = append(results, AddResult{
-				UserID:     userID,
-				Status:     "error_verifying",
-				StatusCode: http.StatusInternalServerError,
-			})
-			failedCount++
-			continue
-		}
-
 		// Thêm user vào team
-		roster := models.Roster{
-			TeamID:   team.ID,
-			UserID:   userID,
-			IsLeader: false, // Mặc định là false
-		}
-
-		if err := tx.Create(&roster).Error; err != nil {
+		if err := h.service.WithContext(c.Request.Context()).AddMember(tx, team.ID, userID, currentUserID.(uuid.UUID)); err != nil {
+			if errors.Is(err, services.ErrAlreadyMember) {
+				results = append(results, AddResult{
+					UserID:     userID,
+					Username:   user.Username,
+					Status:     "already_member",
+					StatusCode: http.StatusConflict,
+				})
+				existingCount++
+				continue
+			}
+			if errors.Is(err, services.ErrQuotaExceeded) {
+				results = append(results, AddResult{
+					UserID:     userID,
+					Username:   user.Username,
+					Status:     "quota_exceeded",
+					StatusCode: http.StatusConflict,
+				})
+				quotaErr = err
+				failedCount++
+				continue
+			}
 			log.Printf("Failed to add user %s to team %d: %v", userID, teamID, err)
 			results = append(results, AddResult{
 				UserID:     userID,
@@ -232,7 +496,7 @@ func (h *TeamHandler) AddMemberToTeam(c *gin.Context) {
 		// Thêm thành công
 		results = append(results, AddResult{
 			UserID:     userID,
-			Username:   userResp.User.Username,
+			Username:   user.Username,
 			Status:     "added_successfully",
 			StatusCode: http.StatusCreated,
 		})
@@ -243,6 +507,15 @@ func (h *TeamHandler) AddMemberToTeam(c *gin.Context) {
 	if addedCount == 0 {
 		tx.Rollback()
 		log.Printf("No members were added to team %d", teamID)
+		if quotaErr != nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error":   "No members were added to the team: the roster is full",
+				"details": quotaErr.Error(),
+				"results": results,
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"error":   "No members were added to the team",
@@ -259,24 +532,6 @@ func (h *TeamHandler) AddMemberToTeam(c *gin.Context) {
 		})
 		return
 	}
-	//emit MEMBER_ADDED
-	if addedCount > 0 && h.producer != nil {
-		for _, result := range results {
-			if result.Status == "added_successfully" {
-				err := h.producer.SendTeamEvent(
-					kafka.EventMemberAdded,
-					teamID,
-					currentUserID.(uuid.UUID),
-					result.UserID,
-				)
-				if err != nil {
-					log.Printf("Failed to send Kafka event for member addition: %v", err)
-					// Continue processing even if Kafka event fails
-				}
-			}
-		}
-	}
-
 	var responseStatus int
 	var successValue bool
 
@@ -350,78 +605,49 @@ func (h *TeamHandler) RemoveMemberFromTeam(c *gin.Context) {
 	}
 
 	// Check if current user is team leader
-	var leaderRoster models.Roster
-	if err := h.db.Where("\"teamId\" = ? AND \"userId\" = ? AND \"isLeader\" = ?", teamID, currentUserID, true).First(&leaderRoster).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.Printf("User %s attempted to remove member from team %d without leadership permission", currentUserID, teamID)
-			c.JSON(http.StatusForbidden, responses.NewErrorResponse("Only team leaders can remove members", ""))
-			return
-		}
-		log.Printf("Database error when checking leadership: %v", err)
-		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify team leadership", err.Error()))
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageMembers, authz.Team(team.ID), "Only team leaders can remove members"); !ok {
 		return
 	}
 
-	// Check if member exists in the team
-	var memberRoster models.Roster
-	if err := h.db.Where("\"teamId\" = ? AND \"userId\" = ?", teamID, memberID).First(&memberRoster).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	// Only those who may transfer ownership can remove the owner, since a
+	// successor takes over the team
+	subject, _ := currentSubject(c)
+	mayRemoveOwner := h.authz.Can(subject, authz.ActionTeamTransferOwnership, authz.Team(team.ID)).Allowed
+
+	// Remove member; the succession policy decides what happens if they were
+	// the owner or the last leader
+	removal, err := h.service.WithContext(c.Request.Context()).RemoveMember(team.ID, memberID, currentUserID.(uuid.UUID), mayRemoveOwner)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
 			log.Printf("Member %s not found in team %d", memberID, teamID)
 			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Member not found in this team", ""))
-			return
-		}
-		log.Printf("Database error when checking member: %v", err)
-		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify team membership", err.Error()))
-		return
-	}
-
-	// Prevent removing yourself if you're the only leader
-	if memberID == currentUserID.(uuid.UUID) && memberRoster.IsLeader {
-		// Count team leaders
-		var leaderCount int64
-		if err := h.db.Model(&models.Roster{}).Where("\"teamId\" = ? AND \"isLeader\" = ?", teamID, true).Count(&leaderCount).Error; err != nil {
-			log.Printf("Error counting team leaders: %v", err)
-			c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify team leadership status", err.Error()))
-			return
+		case errors.Is(err, services.ErrOwnerRemoval):
+			c.JSON(http.StatusConflict, responses.NewErrorResponse("The team owner cannot be removed", "transfer ownership first"))
+		case errors.Is(err, services.ErrSuccessionBlocked):
+			log.Printf("Removal of the last leader %s of team %d blocked by succession policy", memberID, teamID)
+			c.JSON(http.StatusConflict, responses.NewErrorResponse("Cannot remove the only team leader. Transfer ownership or assign another leader first.", ""))
+		default:
+			log.Printf("Failed to remove member %s from team %d: %v", memberID, teamID, err)
+			c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to remove member from team", err.Error()))
 		}
-
-		if leaderCount <= 1 {
-			log.Printf("User %s attempted to remove self as the only leader of team %d", currentUserID, teamID)
-			c.JSON(http.StatusForbidden, responses.NewErrorResponse("Cannot remove yourself as the only team leader. Assign another leader first.", ""))
-			return
-		}
-	}
-
-	// Remove member
-	if err := h.db.Delete(&memberRoster).Error; err != nil {
-		log.Printf("Failed to remove member %s from team %d: %v", memberID, teamID, err)
-		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to remove member from team", err.Error()))
 		return
 	}
 
-	if err := h.producer.SendTeamEvent(
-		kafka.EventMemberRemoved,
-		teamID,
-		currentUserID.(uuid.UUID),
-		memberID,
-	); err != nil {
-		log.Printf("Failed to send Kafka event for member removed: %v", err)
-		// Continue processing even if Kafka event fails
-	}
-
 	// Fetch username for response
-	userResp, _ := h.userService.GetUserByID(memberID.String())
 	username := ""
-	if userResp != nil && userResp.User.Username != "" {
-		username = userResp.User.Username
+	if user, err := h.userService.GetUserByID(c.Request.Context(), memberID.String()); err == nil {
+		username = user.Username
 	}
 
 	c.JSON(http.StatusOK, responses.NewSuccessResponse("Member removed from team successfully", gin.H{
-		"teamId":    team.ID,
-		"teamName":  team.TeamName,
-		"memberId":  memberID,
-		"username":  username,
-		"wasLeader": memberRoster.IsLeader,
+		"teamId":     team.ID,
+		"teamName":   team.TeamName,
+		"memberId":   memberID,
+		"username":   username,
+		"wasLeader":  removal.Roster.IsLeader,
+		"role":       removal.Roster.Role,
+		"succession": removal.Succession,
 	}))
 }
 
@@ -436,16 +662,6 @@ func (h *TeamHandler) AddManagerToTeam(c *gin.Context) {
 		})
 		return
 	}
-	role, exists := c.Get("role")
-	if !exists || role != "MANAGER" {
-		log.Println("Unauthorized attempt to add team manager: insufficient permissions")
-		c.JSON(http.StatusForbidden, gin.H{
-			"success": false,
-			"error":   "Only team managers can add managers",
-		})
-		return
-	}
-
 	// Parse team ID
 	teamIDStr := c.Param("teamId")
 	teamID, err := strconv.ParseUint(teamIDStr, 10, 64)
@@ -477,22 +693,8 @@ func (h *TeamHandler) AddManagerToTeam(c *gin.Context) {
 		return
 	}
 
-	// Check if current user is team leader
-	var leaderRoster models.Roster
-	if err := h.db.Where("\"teamId\" = ? AND \"userId\" = ? AND \"isLeader\" = ?", teamID, currentUserID, true).First(&leaderRoster).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.Printf("User %s attempted to add manager to team %d without leadership permission", currentUserID, teamID)
-			c.JSON(http.StatusForbidden, gin.H{
-				"success": false,
-				"error":   "Only team leaders can add managers",
-			})
-			return
-		}
-		log.Printf("Database error when checking leadership: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "Failed to verify team leadership",
-		})
+	// Only managers who lead the team can promote
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageLeaders, authz.Team(team.ID), "Only managers who lead this team can add managers"); !ok {
 		return
 	}
 
@@ -518,76 +720,55 @@ func (h *TeamHandler) AddManagerToTeam(c *gin.Context) {
 		Status   string    `json:"status"`
 	}
 
+	// Verify users exist
+	users, ok := h.lookupUsers(c, req.UserIDs)
+	if !ok {
+		return
+	}
+
+	// Promote those the user service knows, in one transaction
+	known := make([]uuid.UUID, 0, len(req.UserIDs))
+	for _, userID := range req.UserIDs {
+		if _, found := users[userID.String()]; found {
+			known = append(known, userID)
+		}
+	}
+	outcomes, err := h.service.WithContext(c.Request.Context()).SetRoles(nil, team.ID, known, models.RosterRoleLeader, currentUserID.(uuid.UUID))
+	if err != nil {
+		respondTeamChangeError(c, team.ID, "add managers to", err)
+		return
+	}
+
 	results := make([]PromoteResult, 0, len(req.UserIDs))
 	successCount := 0
 	failedCount := 0
 
+	seen := make(map[uuid.UUID]bool, len(req.UserIDs))
 	for _, userID := range req.UserIDs {
-		// Verify user exists
-		userResp, err := h.userService.GetUserByID(userID.String())
-		if err != nil {
-			results = append(results, PromoteResult{
-				UserID: userID,
-				Status: "user_not_found",
-			})
-			failedCount++
-			continue
+		result := PromoteResult{UserID: userID}
+		if user, found := users[userID.String()]; found {
+			result.Username = user.Username
 		}
-
-		// Check if user is in team
-		var memberRoster models.Roster
-		if err := h.db.Where("\"teamId\" = ? AND \"userId\" = ?", teamID, userID).First(&memberRoster).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				results = append(results, PromoteResult{
-					UserID:   userID,
-					Username: userResp.User.Username,
-					Status:   "not_team_member",
-				})
-				failedCount++
-				continue
-			}
-
-			log.Printf("Database error when checking team membership: %v", err)
-			results = append(results, PromoteResult{
-				UserID:   userID,
-				Username: userResp.User.Username,
-				Status:   "database_error",
-			})
+		switch outcome := outcomes[userID]; {
+		case seen[userID]:
+			result.Status = "duplicate_in_request"
 			failedCount++
-			continue
-		}
-
-		// Check if already a leader
-		if memberRoster.IsLeader {
-			results = append(results, PromoteResult{
-				UserID:   userID,
-				Username: userResp.User.Username,
-				Status:   "already_leader",
-			})
+		case outcome == services.RoleChanged:
+			result.Status = "promoted_to_leader"
+			successCount++
+		case outcome == services.RoleUnchanged, outcome == services.RoleOwnerKept:
+			// The owner counts as a leader
+			result.Status = "already_leader"
 			failedCount++
-			continue
-		}
-
-		// Update to make the user a leader
-		memberRoster.IsLeader = true
-		if err := h.db.Save(&memberRoster).Error; err != nil {
-			log.Printf("Failed to promote user %s to team leader: %v", userID, err)
-			results = append(results, PromoteResult{
-				UserID:   userID,
-				Username: userResp.User.Username,
-				Status:   "update_failed",
-			})
+		case outcome == services.RoleNotMember:
+			result.Status = "not_team_member"
+			failedCount++
+		default:
+			result.Status = "user_not_found"
 			failedCount++
-			continue
 		}
-
-		// Success
-		results = append(results, PromoteResult{
-			UserID:   userID,
-			Username: userResp.User.Username,
-			Status:   "promoted_to_leader",
-		})
-		successCount++
+		seen[userID] = true
+		results = append(results, result)
 	}
 
 	var responseStatus int
@@ -627,17 +808,6 @@ func (h *TeamHandler) RemoveManagerFromTeam(c *gin.Context) {
 		return
 	}
 
-	role, exists := c.Get("role")
-	if !exists || role != "MANAGER" {
-		log.Println("Unauthorized attempt to add team manager: insufficient permissions")
-		c.JSON(http.StatusForbidden, gin.H{
-			"success": false,
-			"error":   "Only team managers can add managers",
-		})
-
-		return
-	}
-
 	// Parse team ID
 	teamIDStr := c.Param("teamId")
 	teamID, err := strconv.ParseUint(teamIDStr, 10, 64)
@@ -669,16 +839,8 @@ func (h *TeamHandler) RemoveManagerFromTeam(c *gin.Context) {
 		return
 	}
 
-	// Check if current user is team leader
-	var leaderRoster models.Roster
-	if err := h.db.Where("\"teamId\" = ? AND \"userId\" = ? AND \"isLeader\" = ?", teamID, currentUserID, true).First(&leaderRoster).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.Printf("User %s attempted to remove manager from team %d without leadership permission", currentUserID, teamID)
-			c.JSON(http.StatusForbidden, responses.NewErrorResponse("Only team leaders can remove managers", ""))
-			return
-		}
-		log.Printf("Database error when checking leadership: %v", err)
-		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify team leadership", err.Error()))
+	// Only managers who lead the team can demote
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageLeaders, authz.Team(team.ID), "Only managers who lead this team can remove managers"); !ok {
 		return
 	}
 
@@ -701,40 +863,35 @@ func (h *TeamHandler) RemoveManagerFromTeam(c *gin.Context) {
 		return
 	}
 
-	// Count team leaders
-	var leaderCount int64
-	if err := h.db.Model(&models.Roster{}).Where("\"teamId\" = ? AND \"isLeader\" = ?", teamID, true).Count(&leaderCount).Error; err != nil {
-		log.Printf("Error counting team leaders: %v", err)
-		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify team leadership status", err.Error()))
+	if managerRoster.Role == models.RosterRoleOwner {
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("The team owner cannot be demoted", ""))
 		return
 	}
 
-	if leaderCount <= 1 {
-		log.Printf("Attempted to remove the only leader from team %d", teamID)
-		c.JSON(http.StatusForbidden, responses.NewErrorResponse("Cannot remove the only team leader. Assign another leader first.", ""))
-		return
-	}
-
-	// Update to demote the manager
-	managerRoster.IsLeader = false
-	if err := h.db.Save(&managerRoster).Error; err != nil {
-		log.Printf("Failed to demote manager %s from team %d: %v", managerID, teamID, err)
-		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to demote manager", err.Error()))
+	// Demote; the succession policy decides what happens if they were the last leader
+	succession, err := h.service.WithContext(c.Request.Context()).DemoteLeader(team.ID, managerID, currentUserID.(uuid.UUID))
+	if err != nil {
+		if errors.Is(err, services.ErrSuccessionBlocked) {
+			log.Printf("Demotion of the last leader %s of team %d blocked by succession policy", managerID, teamID)
+			c.JSON(http.StatusConflict, responses.NewErrorResponse("Cannot remove the only team leader. Transfer ownership or assign another leader first.", ""))
+			return
+		}
+		respondTeamChangeError(c, team.ID, "demote a leader of", err)
 		return
 	}
 
 	// Fetch username for response
-	userResp, _ := h.userService.GetUserByID(managerID.String())
 	username := ""
-	if userResp != nil && userResp.User.Username != "" {
-		username = userResp.User.Username
+	if user, err := h.userService.GetUserByID(c.Request.Context(), managerID.String()); err == nil {
+		username = user.Username
 	}
 
 	c.JSON(http.StatusOK, responses.NewSuccessResponse("Manager demoted successfully", gin.H{
-		"teamId":   team.ID,
-		"teamName": team.TeamName,
-		"userId":   managerID,
-		"username": username,
+		"teamId":     team.ID,
+		"teamName":   team.TeamName,
+		"userId":     managerID,
+		"username":   username,
+		"succession": succession,
 	}))
 }
 
@@ -749,6 +906,10 @@ func (h *TeamHandler) GetTeamMembers(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorize(c, h.authz, authz.ActionTeamView, authz.Team(int(teamID)), "You don't have permission to view this team"); !ok {
+		return
+	}
+
 	// Check if team exists
 	var team models.Team
 	if err := h.db.First(&team, teamID).Error; err != nil {
@@ -810,20 +971,30 @@ func (h *TeamHandler) GetTeamMembers(c *gin.Context) {
 		}
 	}
 
+	// Get user details from user service in one request
+	memberIDs := make([]string, len(members))
+	for i, member := range members {
+		memberIDs[i] = member.UserID.String()
+	}
+	users, err := h.userService.GetUsersByIDs(c.Request.Context(), memberIDs)
+	if err != nil {
+		// Usernames are best effort; still return the roster
+		log.Printf("Failed to fetch member details for team %d: %v", teamID, err)
+	}
+
 	// Prepare response data
 	userInfos = make([]map[string]interface{}, 0, len(members))
 	for _, member := range members {
-		// Get user details from user service
-		userResp, err := h.userService.GetUserByID(member.UserID.String())
 		username := ""
-		if err == nil && userResp != nil && userResp.User != nil {
-			username = userResp.User.Username
+		if user, found := users[member.UserID.String()]; found {
+			username = user.Username
 		}
 
 		userInfo := map[string]interface{}{
 			"userId":   member.UserID,
 			"username": username,
 			"isLeader": member.IsLeader,
+			"role":     member.Role,
 		}
 		userInfos = append(userInfos, userInfo)
 	}
@@ -835,15 +1006,9 @@ func (h *TeamHandler) GetTeamMembers(c *gin.Context) {
 	}))
 }
 
-// GetTeamAssets retrieves all assets (folders and notes) that team members own or can access
+// GetTeamAssets retrieves all assets (folders and notes) that team members own or can access;
+// ?includeSubteams=true rolls up the members of every team below it too
 func (h *TeamHandler) GetTeamAssets(c *gin.Context) {
-	// Check if user has MANAGER role
-	role, exists := c.Get("role")
-	if !exists || role != "MANAGER" {
-		log.Printf("Unauthorized access attempt: role required is MANAGER, got %v", role)
-		c.JSON(http.StatusForbidden, responses.NewErrorResponse("Only managers can access team assets", ""))
-		return
-	}
 	// Parse team ID
 	teamIDStr := c.Param("teamId")
 	teamID, err := strconv.ParseUint(teamIDStr, 10, 64)
@@ -853,6 +1018,10 @@ func (h *TeamHandler) GetTeamAssets(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorize(c, h.authz, authz.ActionTeamViewAssets, authz.Team(int(teamID)), "Only managers can access team assets"); !ok {
+		return
+	}
+
 	// Check if team exists
 	var team models.Team
 	if err := h.db.First(&team, teamID).Error; err != nil {
@@ -866,28 +1035,63 @@ func (h *TeamHandler) GetTeamAssets(c *gin.Context) {
 		return
 	}
 
+	teamIDs := []int{team.ID}
+	if c.Query("includeSubteams") == "true" {
+		teamIDs, err = h.service.SubtreeTeamIDs(team.ID)
+		if err != nil {
+			log.Printf("Failed to fetch sub-teams of team %d: %v", team.ID, err)
+			c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to fetch sub-teams", ""))
+			return
+		}
+	}
+
 	// Get all team members
 	var rosters []models.Roster
-	if err := h.db.Where("\"teamId\" = ?", teamID).Find(&rosters).Error; err != nil {
+	if err := h.db.Where("\"teamId\" IN ?", teamIDs).Find(&rosters).Error; err != nil {
 		log.Printf("Failed to fetch team members: %v", err)
 		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to fetch team members", err.Error()))
 		return
 	}
 
+	// Get folders that belong to the teams, and the notes in them
+	teamFolders := []models.Folder{}
+	if err := h.db.Where("team_id IN ?", teamIDs).Find(&teamFolders).Error; err != nil {
+		log.Printf("Failed to fetch team folders: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to fetch team folders", ""))
+		return
+	}
+	teamFolderIDs := make([]uuid.UUID, len(teamFolders))
+	for i, folder := range teamFolders {
+		teamFolderIDs[i] = folder.ID
+	}
+	teamNotes := []models.Note{}
+	if len(teamFolderIDs) > 0 {
+		if err := h.db.Where("folder_id IN ?", teamFolderIDs).Find(&teamNotes).Error; err != nil {
+			log.Printf("Failed to fetch team folder notes: %v", err)
+			c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to fetch team folder notes", ""))
+			return
+		}
+	}
+
 	if len(rosters) == 0 {
 		c.JSON(http.StatusOK, responses.NewSuccessResponse("Team has no members", gin.H{
 			"teamId":   team.ID,
 			"teamName": team.TeamName,
-			"folders":  []interface{}{},
-			"notes":    []interface{}{},
+			"teamIds":  teamIDs,
+			"folders":  teamFolders,
+			"notes":    teamNotes,
 		}))
 		return
 	}
 
-	// Extract user IDs for query
-	userIDs := make([]uuid.UUID, len(rosters))
-	for i, roster := range rosters {
-		userIDs[i] = roster.UserID
+	// Extract user IDs for query; someone on several sub-teams is counted once
+	userIDs := make([]uuid.UUID, 0, len(rosters))
+	seenUsers := make(map[uuid.UUID]bool, len(rosters))
+	for _, roster := range rosters {
+		if !seenUsers[roster.UserID] {
+			seenUsers[roster.UserID] = true
+			userIDs = append(userIDs, roster.UserID)
+		}
 	}
 
 	// Get folders owned by team members
@@ -901,9 +1105,9 @@ func (h *TeamHandler) GetTeamAssets(c *gin.Context) {
 		return
 	}
 
-	// Get folders shared with team members
+	// Get folders shared with team members or with the teams themselves
 	var folderShares []models.FolderShare
-	if err := h.db.Preload("Folder").Where("user_id IN ?", userIDs).Find(&folderShares).Error; err != nil {
+	if err := h.db.Preload("Folder").Where("user_id IN ? OR team_id IN ?", userIDs, teamIDs).Find(&folderShares).Error; err != nil {
 		log.Printf("Failed to fetch folder shares: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -912,8 +1116,11 @@ func (h *TeamHandler) GetTeamAssets(c *gin.Context) {
 		return
 	}
 
-	// Add shared folders to the folders list, avoiding duplicates
+	// Add team and shared folders to the folders list, avoiding duplicates
 	folderMap := make(map[uuid.UUID]models.Folder)
+	for _, folder := range teamFolders {
+		folderMap[folder.ID] = folder
+	}
 	for _, folder := range folders {
 		folderMap[folder.ID] = folder
 	}
@@ -935,9 +1142,9 @@ func (h *TeamHandler) GetTeamAssets(c *gin.Context) {
 		return
 	}
 
-	// Get notes shared with team members
+	// Get notes shared with team members or with the teams themselves
 	var noteShares []models.NoteShare
-	if err := h.db.Preload("Note").Where("user_id IN ?", userIDs).Find(&noteShares).Error; err != nil {
+	if err := h.db.Preload("Note").Where("user_id IN ? OR team_id IN ?", userIDs, teamIDs).Find(&noteShares).Error; err != nil {
 		log.Printf("Failed to fetch note shares: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -946,8 +1153,11 @@ func (h *TeamHandler) GetTeamAssets(c *gin.Context) {
 		return
 	}
 
-	// Add shared notes to the notes list, avoiding duplicates
+	// Add team and shared notes to the notes list, avoiding duplicates
 	noteMap := make(map[uuid.UUID]models.Note)
+	for _, note := range teamNotes {
+		noteMap[note.ID] = note
+	}
 	for _, note := range notes {
 		noteMap[note.ID] = note
 	}
@@ -975,26 +1185,19 @@ func (h *TeamHandler) GetTeamAssets(c *gin.Context) {
 		"data": gin.H{
 			"teamId":   team.ID,
 			"teamName": team.TeamName,
+			"teamIds":  teamIDs,
 			"folders":  resultFolders,
 			"notes":    resultNotes,
 		},
 	})
 }
 
+// sharedWithUser matches shares made to a user directly or to any team on
+// whose roster they are; it takes the user ID twice
+const sharedWithUser = `user_id = ? OR team_id IN (SELECT "teamId" FROM "Rosters" WHERE "userId" = ?)`
+
 // GetUserAssets retrieves all assets (folders and notes) owned by or shared with a user
 func (h *TeamHandler) GetUserAssets(c *gin.Context) {
-
-	// Check if user has MANAGER role
-	role, exists := c.Get("role")
-	if !exists || role != "MANAGER" {
-		log.Printf("Unauthorized access attempt: role required is MANAGER, got %v", role)
-		c.JSON(http.StatusForbidden, gin.H{
-			"success": false,
-			"error":   "Only managers can access user assets",
-		})
-		return
-	}
-
 	// Parse user ID
 	userIDStr := c.Param("userId")
 	userID, err := uuid.Parse(userIDStr)
@@ -1007,9 +1210,21 @@ func (h *TeamHandler) GetUserAssets(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorize(c, h.authz, authz.ActionUserViewAssets, authz.User(userID), "Only managers can access user assets"); !ok {
+		return
+	}
+
 	// Verify user exists through the user service
-	userResp, err := h.userService.GetUserByID(userID.String())
-	if err != nil || userResp == nil || userResp.User == nil {
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID.String())
+	if err != nil {
+		if !errors.Is(err, services.ErrUserNotFound) {
+			log.Printf("Failed to look up user %s: %v", userID, err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"success": false,
+				"error":   "User service unavailable",
+			})
+			return
+		}
 		log.Printf("User not found: %v", err)
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -1029,9 +1244,9 @@ func (h *TeamHandler) GetUserAssets(c *gin.Context) {
 		return
 	}
 
-	// Get folders shared with the user
+	// Get folders shared with the user directly or through a team they are on
 	var folderShares []models.FolderShare
-	if err := h.db.Preload("Folder").Where("user_id = ?", userID).Find(&folderShares).Error; err != nil {
+	if err := h.db.Preload("Folder").Where(sharedWithUser, userID, userID).Find(&folderShares).Error; err != nil {
 		log.Printf("Failed to fetch folder shares: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -1051,9 +1266,9 @@ func (h *TeamHandler) GetUserAssets(c *gin.Context) {
 		return
 	}
 
-	// Get notes shared with the user
+	// Get notes shared with the user directly or through a team they are on
 	var noteShares []models.NoteShare
-	if err := h.db.Preload("Note").Where("user_id = ?", userID).Find(&noteShares).Error; err != nil {
+	if err := h.db.Preload("Note").Where(sharedWithUser, userID, userID).Find(&noteShares).Error; err != nil {
 		log.Printf("Failed to fetch note shares: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -1062,16 +1277,24 @@ func (h *TeamHandler) GetUserAssets(c *gin.Context) {
 		return
 	}
 
-	// Add shared folders to response
-	sharedFolders := make([]models.Folder, len(folderShares))
-	for i, share := range folderShares {
-		sharedFolders[i] = share.Folder
+	// Add shared folders to response; a folder shared both ways is listed once
+	sharedFolders := make([]models.Folder, 0, len(folderShares))
+	seenFolders := make(map[uuid.UUID]bool, len(folderShares))
+	for _, share := range folderShares {
+		if !seenFolders[share.FolderID] {
+			seenFolders[share.FolderID] = true
+			sharedFolders = append(sharedFolders, share.Folder)
+		}
 	}
 
 	// Add shared notes to response
-	sharedNotes := make([]models.Note, len(noteShares))
-	for i, share := range noteShares {
-		sharedNotes[i] = share.Note
+	sharedNotes := make([]models.Note, 0, len(noteShares))
+	seenNotes := make(map[uuid.UUID]bool, len(noteShares))
+	for _, share := range noteShares {
+		if !seenNotes[share.NoteID] {
+			seenNotes[share.NoteID] = true
+			sharedNotes = append(sharedNotes, share.Note)
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -1079,7 +1302,7 @@ func (h *TeamHandler) GetUserAssets(c *gin.Context) {
 		"message": "User assets retrieved successfully",
 		"data": gin.H{
 			"userId":        userID,
-			"username":      userResp.User.Username,
+			"username":      user.Username,
 			"ownedFolders":  folders,
 			"sharedFolders": sharedFolders,
 			"ownedNotes":    notes,
@@ -1087,3 +1310,22 @@ func (h *TeamHandler) GetUserAssets(c *gin.Context) {
 		},
 	})
 }
+
+// lookupUsers fetches userIDs from the user directory in a single batch, keyed
+// by ID string. Unknown users are simply absent from the map. If the directory
+// cannot be reached it writes a 503 and returns false.
+func (h *TeamHandler) lookupUsers(c *gin.Context, userIDs []uuid.UUID) (map[string]*services.User, bool) {
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+
+	users, err := h.userService.GetUsersByIDs(c.Request.Context(), ids)
+	if err != nil {
+		log.Printf("Failed to look up %d users: %v", len(ids), err)
+		c.JSON(http.StatusServiceUnavailable, responses.NewErrorResponse("User service unavailable", err.Error()))
+		return nil, false
+	}
+
+	return users, true
+}
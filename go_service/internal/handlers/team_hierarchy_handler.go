@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go_service/internal/authz"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AttachTeam places a team under a parent team (only managers)
+func (h *TeamHandler) AttachTeam(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ParentTeamID int `json:"parentTeamId" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format:", err.Error()))
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageHierarchy, authz.Team(teamID), "Only managers can move teams"); !ok {
+		return
+	}
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageHierarchy, authz.Team(req.ParentTeamID), "Only managers can move teams"); !ok {
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	team, err := h.service.WithContext(c.Request.Context()).AttachTeam(teamID, req.ParentTeamID, subject.UserID)
+	if err != nil {
+		respondTeamChangeError(c, teamID, "attach", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team attached successfully", team))
+}
+
+// DetachTeam moves a team back to the top level (only managers)
+func (h *TeamHandler) DetachTeam(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageHierarchy, authz.Team(teamID), "Only managers can move teams"); !ok {
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	team, err := h.service.WithContext(c.Request.Context()).DetachTeam(teamID, subject.UserID)
+	if err != nil {
+		respondTeamChangeError(c, teamID, "detach", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team detached successfully", team))
+}
+
+// GetTeamSubtree returns a team with its sub-teams nested below it;
+// ?archived=true includes archived sub-teams (members and managers)
+func (h *TeamHandler) GetTeamSubtree(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamViewDetails, authz.Team(teamID), "You don't have permission to view this team"); !ok {
+		return
+	}
+
+	tree, err := h.service.Subtree(teamID, c.Query("archived") == "true")
+	if err != nil {
+		respondTeamChangeError(c, teamID, "load", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team subtree retrieved successfully", tree))
+}
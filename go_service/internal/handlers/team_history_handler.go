@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"go_service/internal/authz"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// parseAt reads the required ?at= timestamp of a point-in-time query
+func parseAt(c *gin.Context) (time.Time, bool) {
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid or missing at; use an RFC 3339 timestamp", ""))
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+// GetRosterAt returns the team roster as it was at ?at=, with each member's
+// role then and the interval it was held for (team members and managers)
+func (h *TeamHandler) GetRosterAt(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+	at, ok := parseAt(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamViewDetails, authz.Team(teamID), "You don't have permission to view this team"); !ok {
+		return
+	}
+
+	roster, err := h.service.RosterAt(teamID, at)
+	if err != nil {
+		log.Printf("Failed to load roster of team %d at %s: %v", teamID, at.Format(time.RFC3339), err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to load team roster", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team roster retrieved successfully", gin.H{
+		"teamId":  teamID,
+		"at":      at,
+		"members": roster,
+		"count":   len(roster),
+	}))
+}
+
+// GetUserTeamsAt returns the teams a user was on at ?at= (the user and managers)
+func (h *TeamHandler) GetUserTeamsAt(c *gin.Context) {
+	userIDStr := c.Param("userId")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		log.Printf("Invalid user ID format: %s", userIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid user ID format", ""))
+		return
+	}
+	at, ok := parseAt(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionUserViewMemberships, authz.User(userID), "You can only view your own team memberships"); !ok {
+		return
+	}
+
+	memberships, err := h.service.MembershipsAt(userID, at)
+	if err != nil {
+		log.Printf("Failed to load teams of user %s at %s: %v", userID, at.Format(time.RFC3339), err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to load user teams", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("User teams retrieved successfully", gin.H{
+		"userId": userID,
+		"at":     at,
+		"teams":  memberships,
+		"count":  len(memberships),
+	}))
+}
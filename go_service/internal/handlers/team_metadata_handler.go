@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go_service/internal/authz"
+	"go_service/internal/models"
+	"go_service/internal/services"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTeamImportBytes caps the size of an uploaded team CSV
+const maxTeamImportBytes = 5 << 20
+
+// ListTeamFields returns the custom fields teams may carry (any signed-in user)
+func (h *TeamHandler) ListTeamFields(c *gin.Context) {
+	definitions, err := h.service.FieldDefinitions()
+	if err != nil {
+		log.Printf("Failed to list team fields: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list team fields", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team fields retrieved successfully", definitions))
+}
+
+// CreateTeamField defines a custom team field (managers only). Enum fields
+// list their allowed values in options.
+func (h *TeamHandler) CreateTeamField(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageFields, authz.System(), "Only managers can define team fields"); !ok {
+		return
+	}
+
+	var req struct {
+		Key     string           `json:"key" binding:"required"`
+		Name    string           `json:"name" binding:"required"`
+		Type    models.FieldType `json:"type" binding:"required"`
+		Options []string         `json:"options"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
+		return
+	}
+
+	definition, err := h.service.WithContext(c.Request.Context()).DefineField(models.TeamFieldDefinition{
+		Key:     req.Key,
+		Name:    req.Name,
+		Type:    req.Type,
+		Options: req.Options,
+	})
+	switch {
+	case errors.Is(err, services.ErrInvalidField):
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid team field", err.Error()))
+		return
+	case errors.Is(err, services.ErrFieldExists):
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("A team field with this key already exists", ""))
+		return
+	case err != nil:
+		log.Printf("Failed to create team field %q: %v", req.Key, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to create team field", ""))
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.NewSuccessResponse("Team field created successfully", definition))
+}
+
+// DeleteTeamField removes a custom team field and every team's value for it
+// (managers only)
+func (h *TeamHandler) DeleteTeamField(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageFields, authz.System(), "Only managers can remove team fields"); !ok {
+		return
+	}
+
+	key := c.Param("fieldKey")
+	if err := h.service.WithContext(c.Request.Context()).DeleteField(key); err != nil {
+		if errors.Is(err, services.ErrUnknownField) {
+			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Team field not found", ""))
+			return
+		}
+		log.Printf("Failed to delete team field %q: %v", key, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to delete team field", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team field deleted successfully", gin.H{"key": key}))
+}
+
+// UpdateTeamMetadata changes a team's labels and custom field values (team
+// leaders and managers). labels, when given, replaces the team's labels;
+// fields sets the listed fields, null clearing one.
+func (h *TeamHandler) UpdateTeamMetadata(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamUpdate, authz.Team(teamID), "Only team leaders can update the team"); !ok {
+		return
+	}
+
+	var req struct {
+		Labels *[]string          `json:"labels"`
+		Fields map[string]*string `json:"fields"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
+		return
+	}
+	if req.Labels == nil && len(req.Fields) == 0 {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Nothing to update", "provide labels and/or fields"))
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	metadata, err := h.service.WithContext(c.Request.Context()).SetMetadata(teamID, services.MetadataUpdate{Labels: req.Labels, Fields: req.Fields}, subject.UserID)
+	if err != nil {
+		respondTeamChangeError(c, teamID, "update metadata of", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team metadata updated successfully", gin.H{
+		"teamId": teamID,
+		"labels": metadata.Labels,
+		"fields": metadata.Fields,
+	}))
+}
+
+// ExportTeams downloads the active teams with their labels and custom fields
+// as CSV (managers only)
+func (h *TeamHandler) ExportTeams(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionTeamExport, authz.System(), "Only managers can export teams"); !ok {
+		return
+	}
+
+	// Built in memory so a failure can still be reported as JSON
+	var out bytes.Buffer
+	if err := h.service.ExportTeams(&out); err != nil {
+		log.Printf("Failed to export teams: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to export teams", ""))
+		return
+	}
+
+	filename := "teams-" + time.Now().UTC().Format("20060102") + ".csv"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", out.Bytes())
+}
+
+// ImportTeams creates and updates teams from an uploaded CSV in the export's
+// layout, sent as the "file" form field (managers only). Each row is reported
+// on its own.
+func (h *TeamHandler) ImportTeams(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionTeamCreate, authz.System(), "Only managers can import teams"); !ok {
+		return
+	}
+	subject, _ := currentSubject(c)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTeamImportBytes)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("No file was uploaded. Please use 'file' as the form field name.", err.Error()))
+		return
+	}
+	defer file.Close()
+	if !strings.HasSuffix(strings.ToLower(header.Filename), ".csv") && header.Header.Get("Content-Type") != "text/csv" {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Uploaded file must be a CSV file", ""))
+		return
+	}
+
+	results, err := h.service.WithContext(c.Request.Context()).ImportTeams(file, subject.UserID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTeamCSV) {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid team CSV", err.Error()))
+			return
+		}
+		log.Printf("Failed to import teams: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to import teams", ""))
+		return
+	}
+
+	counts := map[services.TeamImportStatus]int{}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team import completed", gin.H{
+		"created": counts[services.TeamImportCreated],
+		"updated": counts[services.TeamImportUpdated],
+		"failed":  counts[services.TeamImportFailed],
+		"results": results,
+	}))
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"go_service/internal/authz"
+	"go_service/internal/models"
+	"go_service/internal/services"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// respondQuotaExceeded writes the response for a QuotaError. A full roster or
+// member allowance is a conflict; note content that does not fit the team's
+// storage is unprocessable.
+func respondQuotaExceeded(c *gin.Context, err error) {
+	var quotaErr *services.QuotaError
+	if !errors.As(err, &quotaErr) {
+		c.JSON(http.StatusConflict, responses.NewErrorResponse("Team quota exceeded", err.Error()))
+		return
+	}
+
+	status := http.StatusConflict
+	if quotaErr.Quota == services.QuotaNoteBytes {
+		status = http.StatusUnprocessableEntity
+	}
+	response := responses.NewErrorResponse("Team quota exceeded", quotaErr.Error())
+	response.Data = quotaErr
+	c.JSON(status, response)
+}
+
+// GetTeamUsage returns the team's quota limits next to its current
+// consumption (team members and managers)
+func (h *TeamHandler) GetTeamUsage(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamViewDetails, authz.Team(teamID), "You don't have permission to view this team"); !ok {
+		return
+	}
+
+	if err := h.db.First(&models.Team{}, teamID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Team not found", ""))
+			return
+		}
+		log.Printf("Database error when finding team: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify team", ""))
+		return
+	}
+
+	usage, err := h.quotas.Usage(teamID)
+	if err != nil {
+		log.Printf("Failed to load quota usage of team %d: %v", teamID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to load team usage", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team usage retrieved successfully", usage))
+}
+
+// UpdateTeamQuota replaces the team's quota overrides; a limit left out or
+// null falls back to the global default and 0 lifts it (managers only)
+func (h *TeamHandler) UpdateTeamQuota(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageQuota, authz.Team(teamID), "Only managers can change team quotas"); !ok {
+		return
+	}
+	subject, _ := currentSubject(c)
+
+	var req struct {
+		MaxMembers          *int `json:"maxMembers" binding:"omitempty,min=0"`
+		MaxFoldersPerMember *int `json:"maxFoldersPerMember" binding:"omitempty,min=0"`
+		MaxNotesPerMember   *int `json:"maxNotesPerMember" binding:"omitempty,min=0"`
+		MaxNoteBytes        *int `json:"maxNoteBytes" binding:"omitempty,min=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
+		return
+	}
+
+	if err := h.db.First(&models.Team{}, teamID).Error; err != nil {
+		respondTeamChangeError(c, teamID, "find", err)
+		return
+	}
+
+	quota, err := h.quotas.WithContext(c.Request.Context()).SetOverrides(teamID, models.TeamQuota{
+		MaxMembers:          req.MaxMembers,
+		MaxFoldersPerMember: req.MaxFoldersPerMember,
+		MaxNotesPerMember:   req.MaxNotesPerMember,
+		MaxNoteBytes:        req.MaxNoteBytes,
+	}, subject.UserID)
+	if err != nil {
+		log.Printf("Failed to update quota of team %d: %v", teamID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to update team quota", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team quota updated successfully", quota))
+}
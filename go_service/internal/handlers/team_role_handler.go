@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"go_service/internal/authz"
+	"go_service/internal/models"
+	"go_service/internal/services"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ChangeMemberRole sets a member's roster role to leader, member or viewer
+// (the owner, and managers who lead the team or a team above it)
+func (h *TeamHandler) ChangeMemberRole(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(c.Param("memberId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid member ID format", ""))
+		return
+	}
+
+	var req struct {
+		Role models.RosterRole `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format:", err.Error()))
+		return
+	}
+	if !req.Role.Valid() {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid role. Must be 'leader', 'member' or 'viewer'", ""))
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageLeaders, authz.Team(teamID), "Only the team owner or managers who lead this team can change roles"); !ok {
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	roster, previous, err := h.service.WithContext(c.Request.Context()).ChangeRole(teamID, memberID, req.Role, subject.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, responses.NewErrorResponse("Member not found in this team", ""))
+		return
+	}
+	if err != nil {
+		respondTeamChangeError(c, teamID, "change a role on", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Member role updated successfully", gin.H{
+		"teamId":       teamID,
+		"userId":       roster.UserID,
+		"role":         roster.Role,
+		"previousRole": previous,
+		"isLeader":     roster.IsLeader,
+	}))
+}
+
+// TransferOwnership makes another user the team's owner; the previous owner
+// stays on as a leader (the owner, and managers settling a team left without one)
+func (h *TeamHandler) TransferOwnership(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uuid.UUID `json:"userId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format:", err.Error()))
+		return
+	}
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamTransferOwnership, authz.Team(teamID), "Only the team owner or managers can transfer ownership"); !ok {
+		return
+	}
+
+	subject, _ := currentSubject(c)
+	owner, previousOwner, err := h.service.WithContext(c.Request.Context()).TransferOwnership(teamID, req.UserID, subject.UserID)
+	if err != nil {
+		respondTeamChangeError(c, teamID, "transfer ownership of", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Team ownership transferred successfully", gin.H{
+		"teamId":          teamID,
+		"ownerId":         owner.UserID,
+		"previousOwnerId": previousOwner,
+	}))
+}
+
+// SyncRoster makes the team roster match the given members and leaders,
+// adding, removing, promoting and demoting as needed. With ?dryRun=true it
+// only returns the changes it would make. Leaders may sync members; changes
+// to leadership also need the right to manage roles.
+func (h *TeamHandler) SyncRoster(c *gin.Context) {
+	teamID, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Members []uuid.UUID `json:"members"`
+		Leaders []uuid.UUID `json:"leaders" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format:", err.Error()))
+		return
+	}
+	dryRun := c.Query("dryRun") == "true"
+
+	if _, ok := authorize(c, h.authz, authz.ActionTeamManageMembers, authz.Team(teamID), "Only team leaders can sync the roster"); !ok {
+		return
+	}
+	subject, _ := currentSubject(c)
+	roles := h.authz.Can(subject, authz.ActionTeamManageLeaders, authz.Team(teamID))
+	if roles.Err != nil {
+		log.Printf("Authorization check %s on team %d failed for user %s: %v", authz.ActionTeamManageLeaders, teamID, subject.UserID, roles.Err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to verify permissions", ""))
+		return
+	}
+
+	// Everyone on the desired roster must be a known user
+	desiredIDs := append(append([]uuid.UUID{}, req.Leaders...), req.Members...)
+	users, ok := h.lookupUsers(c, desiredIDs)
+	if !ok {
+		return
+	}
+	unknown := []uuid.UUID{}
+	for _, userID := range desiredIDs {
+		if _, found := users[userID.String()]; !found {
+			unknown = append(unknown, userID)
+		}
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":        false,
+			"error":          "Unknown users in roster",
+			"unknownUserIds": unknown,
+		})
+		return
+	}
+
+	desired := services.RosterSync{Members: req.Members, Leaders: req.Leaders}
+	options := services.RosterSyncOptions{DryRun: dryRun, AllowRoleChanges: roles.Allowed}
+	diff, err := h.service.WithContext(c.Request.Context()).SyncRoster(teamID, desired, options, subject.UserID)
+	if errors.Is(err, services.ErrRoleChangeNotAllowed) {
+		c.JSON(http.StatusForbidden, responses.NewErrorResponse("Only the team owner or managers who lead this team can change leadership", roles.Reason))
+		return
+	}
+	if err != nil {
+		respondTeamChangeError(c, teamID, "sync the roster of", err)
+		return
+	}
+
+	message := "Team roster synced successfully"
+	if dryRun {
+		message = "Team roster diff computed; nothing was changed"
+	}
+	c.JSON(http.StatusOK, responses.NewSuccessResponse(message, gin.H{
+		"teamId":    teamID,
+		"dryRun":    dryRun,
+		"changes":   diff.Changes,
+		"unchanged": diff.Unchanged,
+	}))
+}
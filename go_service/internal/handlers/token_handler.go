@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go_service/internal/authz"
+	"go_service/internal/middleware"
+	"go_service/internal/services"
+	"go_service/pkg/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	tokens *services.APITokenService
+	authz  *authz.Engine
+}
+
+func NewTokenHandler(tokens *services.APITokenService, engine *authz.Engine) *TokenHandler {
+	return &TokenHandler{
+		tokens: tokens,
+		authz:  engine,
+	}
+}
+
+// tokenRequest is the body for creating a personal or service account token
+type tokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+// CreatePersonalToken issues a personal access token for the current user
+func (h *TokenHandler) CreatePersonalToken(c *gin.Context) {
+	currentUserID, exists := c.Get("user_id")
+	if !exists {
+		log.Println("Unauthorized attempt to create token: missing user_id")
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return
+	}
+
+	// Tokens must not be able to mint further tokens, nor may an impersonator
+	// mint one for the impersonated user
+	if authType, _ := c.Get("auth_type"); authType != middleware.AuthTypeJWT || middleware.IsImpersonating(c) {
+		c.JSON(http.StatusForbidden, responses.NewErrorResponse("Personal access tokens can only be created from an interactive session", ""))
+		return
+	}
+
+	var req tokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
+		return
+	}
+
+	raw, token, err := h.tokens.CreatePersonalToken(currentUserID.(uuid.UUID), req.Name, req.Scopes, time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownScope) {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid scopes", err.Error()))
+			return
+		}
+		log.Printf("Failed to create personal access token: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to create token", ""))
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.NewSuccessResponse("Token created successfully. Store it now, it will not be shown again.", gin.H{
+		"token":  raw,
+		"detail": token,
+	}))
+}
+
+// ListPersonalTokens lists the current user's personal access tokens
+func (h *TokenHandler) ListPersonalTokens(c *gin.Context) {
+	currentUserID, exists := c.Get("user_id")
+	if !exists {
+		log.Println("Unauthorized attempt to list tokens: missing user_id")
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return
+	}
+
+	tokens, err := h.tokens.ListUserTokens(currentUserID.(uuid.UUID))
+	if err != nil {
+		log.Printf("Failed to list tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list tokens", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Tokens retrieved successfully", tokens))
+}
+
+// RevokePersonalToken revokes one of the current user's personal access tokens
+func (h *TokenHandler) RevokePersonalToken(c *gin.Context) {
+	currentUserID, exists := c.Get("user_id")
+	if !exists {
+		log.Println("Unauthorized attempt to revoke token: missing user_id")
+		c.JSON(http.StatusUnauthorized, responses.NewErrorResponse("Authentication required", ""))
+		return
+	}
+
+	tokenIDStr := c.Param("tokenId")
+	tokenID, err := uuid.Parse(tokenIDStr)
+	if err != nil {
+		log.Printf("Invalid token ID format: %s", tokenIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid token ID format", ""))
+		return
+	}
+
+	if err := h.tokens.RevokeUserToken(currentUserID.(uuid.UUID), tokenID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Token not found", ""))
+			return
+		}
+		log.Printf("Failed to revoke token %s: %v", tokenID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to revoke token", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Token revoked successfully", nil))
+}
+
+// CreateServiceAccount registers a service account (only managers)
+func (h *TokenHandler) CreateServiceAccount(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can manage service accounts"); !ok {
+		return
+	}
+	currentUserID, _ := c.Get("user_id")
+
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+		Role        string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
+		return
+	}
+
+	accountRole := strings.ToUpper(req.Role)
+	if accountRole == "" {
+		accountRole = "MEMBER"
+	}
+	if accountRole != "MEMBER" && accountRole != "MANAGER" {
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid role. Must be 'MEMBER' or 'MANAGER'", ""))
+		return
+	}
+
+	account, err := h.tokens.CreateServiceAccount(req.Name, req.Description, accountRole, currentUserID.(uuid.UUID))
+	if err != nil {
+		log.Printf("Failed to create service account: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to create service account", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.NewSuccessResponse("Service account created successfully", account))
+}
+
+// ListServiceAccounts lists every service account (only managers)
+func (h *TokenHandler) ListServiceAccounts(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can manage service accounts"); !ok {
+		return
+	}
+
+	accounts, err := h.tokens.ListServiceAccounts()
+	if err != nil {
+		log.Printf("Failed to list service accounts: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list service accounts", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Service accounts retrieved successfully", accounts))
+}
+
+// DisableServiceAccount disables a service account and revokes its credentials (only managers)
+func (h *TokenHandler) DisableServiceAccount(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can manage service accounts"); !ok {
+		return
+	}
+
+	accountID, ok := parseServiceAccountID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.tokens.DisableServiceAccount(accountID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Service account not found or already disabled", ""))
+			return
+		}
+		log.Printf("Failed to disable service account %s: %v", accountID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to disable service account", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Service account disabled successfully", nil))
+}
+
+// CreateServiceAccountToken issues a credential for a service account (only managers)
+func (h *TokenHandler) CreateServiceAccountToken(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can manage service accounts"); !ok {
+		return
+	}
+	currentUserID, _ := c.Get("user_id")
+
+	accountID, ok := parseServiceAccountID(c)
+	if !ok {
+		return
+	}
+
+	var req tokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid request format", err.Error()))
+		return
+	}
+
+	account, err := h.tokens.GetServiceAccount(accountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Service account not found", ""))
+			return
+		}
+		log.Printf("Database error when finding service account: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to retrieve service account", ""))
+		return
+	}
+
+	raw, token, err := h.tokens.CreateServiceAccountToken(account, currentUserID.(uuid.UUID), req.Name, req.Scopes, time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownScope) {
+			c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid scopes", err.Error()))
+			return
+		}
+		log.Printf("Failed to create service account token: %v", err)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Failed to create token", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.NewSuccessResponse("Token created successfully. Store it now, it will not be shown again.", gin.H{
+		"token":  raw,
+		"detail": token,
+	}))
+}
+
+// ListServiceAccountTokens lists the credentials of a service account (only managers)
+func (h *TokenHandler) ListServiceAccountTokens(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can manage service accounts"); !ok {
+		return
+	}
+
+	accountID, ok := parseServiceAccountID(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.tokens.ListServiceAccountTokens(accountID)
+	if err != nil {
+		log.Printf("Failed to list service account tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to list tokens", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Tokens retrieved successfully", tokens))
+}
+
+// RevokeServiceAccountToken revokes one credential of a service account (only managers)
+func (h *TokenHandler) RevokeServiceAccountToken(c *gin.Context) {
+	if _, ok := authorize(c, h.authz, authz.ActionSystemAdmin, authz.System(), "Only managers can manage service accounts"); !ok {
+		return
+	}
+
+	accountID, ok := parseServiceAccountID(c)
+	if !ok {
+		return
+	}
+
+	tokenIDStr := c.Param("tokenId")
+	tokenID, err := uuid.Parse(tokenIDStr)
+	if err != nil {
+		log.Printf("Invalid token ID format: %s", tokenIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid token ID format", ""))
+		return
+	}
+
+	if err := h.tokens.RevokeServiceAccountToken(accountID, tokenID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, responses.NewErrorResponse("Token not found", ""))
+			return
+		}
+		log.Printf("Failed to revoke token %s: %v", tokenID, err)
+		c.JSON(http.StatusInternalServerError, responses.NewErrorResponse("Failed to revoke token", ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSuccessResponse("Token revoked successfully", nil))
+}
+
+func parseServiceAccountID(c *gin.Context) (uuid.UUID, bool) {
+	accountIDStr := c.Param("accountId")
+	accountID, err := uuid.Parse(accountIDStr)
+	if err != nil {
+		log.Printf("Invalid service account ID format: %s", accountIDStr)
+		c.JSON(http.StatusBadRequest, responses.NewErrorResponse("Invalid service account ID format", ""))
+		return uuid.Nil, false
+	}
+	return accountID, true
+}
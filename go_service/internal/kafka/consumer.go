@@ -9,15 +9,34 @@ import (
 	"syscall"
 	"time"
 
+	"go_service/internal/cache"
+
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
+// seenEventsCapacity bounds how many recent event IDs are remembered for deduplication
+const seenEventsCapacity = 10000
+
+const (
+	// handlerAttempts is how many times a failing handler runs on one event
+	// before the consumer gives up on it and moves on
+	handlerAttempts = 5
+	// handlerBackoff is the wait before the first retry, doubled after each
+	handlerBackoff = 500 * time.Millisecond
+)
+
 type EventHandler func(event TeamEvent) error
 
+// AllEvents registers a handler for every event type
+const AllEvents = "*"
+
 type Consumer struct {
 	consumer *kafka.Consumer
 	handlers map[string][]EventHandler
 	topic    string
+	// seen holds recently handled event IDs; the outbox relay delivers at
+	// least once, so a redelivered event is skipped
+	seen *cache.LRU[string, struct{}]
 }
 
 // NewConsumer creates a new Kafka consumer
@@ -33,6 +52,8 @@ func NewConsumer(bootstrapServers, username, password, topic string) (*Consumer,
 		"sasl.mechanisms":   "PLAIN",
 		"group.id":          "team-consumer",
 		"auto.offset.reset": "earliest",
+		// Offsets are committed by Start once an event is done with
+		"enable.auto.commit": false,
 	})
 
 	if err != nil {
@@ -49,10 +70,12 @@ func NewConsumer(bootstrapServers, username, password, topic string) (*Consumer,
 		consumer: c,
 		handlers: make(map[string][]EventHandler),
 		topic:    topic,
+		seen:     cache.NewLRU[string, struct{}](seenEventsCapacity),
 	}, nil
 }
 
-// RegisterHandler registers a handler for a specific event type
+// RegisterHandler registers a handler for a specific event type, or for
+// every event with AllEvents
 func (c *Consumer) RegisterHandler(eventType string, handler EventHandler) {
 	if c.handlers[eventType] == nil {
 		c.handlers[eventType] = []EventHandler{}
@@ -60,7 +83,9 @@ func (c *Consumer) RegisterHandler(eventType string, handler EventHandler) {
 	c.handlers[eventType] = append(c.handlers[eventType], handler)
 }
 
-// Start starts consuming messages
+// Start consumes messages until interrupted. A message's offset is committed
+// only after its event was handled, or retried handlerAttempts times and
+// given up on, so a consumer that stops mid-event gets it again on restart.
 func (c *Consumer) Start() {
 	// Set up a channel for handling Ctrl-C, etc
 	sigchan := make(chan os.Signal, 1)
@@ -87,16 +112,15 @@ func (c *Consumer) Start() {
 			var event TeamEvent
 			if err := json.Unmarshal(ev.Value, &event); err != nil {
 				log.Printf("Failed to unmarshal event: %v\n", err)
-				continue
+			} else if _, _, dup := c.seen.Get(event.EventID); dup && event.EventID != "" {
+				log.Printf("Skipping duplicate event %s (%s)\n", event.EventID, event.EventType)
+			} else if c.handle(event) && event.EventID != "" {
+				// Only a fully handled event counts as seen
+				c.seen.Set(event.EventID, struct{}{}, time.Now())
 			}
 
-			// Process event with registered handlers
-			if handlers, ok := c.handlers[event.EventType]; ok {
-				for _, handler := range handlers {
-					if err := handler(event); err != nil {
-						log.Printf("Error handling event %s: %v\n", event.EventType, err)
-					}
-				}
+			if _, err := c.consumer.CommitMessage(ev); err != nil {
+				log.Printf("Failed to commit offset %v: %v\n", ev.TopicPartition, err)
 			}
 		}
 	}
@@ -104,6 +128,39 @@ func (c *Consumer) Start() {
 	c.consumer.Close()
 }
 
+// handle runs the registered handlers on event, those for all events first,
+// and reports whether every one succeeded. A failing handler is retried on
+// its own, so the handlers that succeeded do not see the event twice.
+func (c *Consumer) handle(event TeamEvent) bool {
+	ok := true
+	for _, eventType := range []string{AllEvents, event.EventType} {
+		for _, handler := range c.handlers[eventType] {
+			if !retryHandler(handler, event, handlerAttempts, handlerBackoff) {
+				ok = false
+			}
+		}
+	}
+	return ok
+}
+
+// retryHandler runs handler on event up to attempts times, waiting backoff
+// before the first retry and twice as long before each next one
+func retryHandler(handler EventHandler, event TeamEvent, attempts int, backoff time.Duration) bool {
+	for attempt := 1; ; attempt++ {
+		err := handler(event)
+		if err == nil {
+			return true
+		}
+		if attempt == attempts {
+			log.Printf("Giving up on event %s (%s) after %d attempts: %v\n", event.EventID, event.EventType, attempts, err)
+			return false
+		}
+		log.Printf("Error handling event %s (attempt %d of %d): %v\n", event.EventType, attempt, attempts, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
 // Close the consumer
 func (c *Consumer) Close() {
 	c.consumer.Close()
@@ -0,0 +1,99 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Topics events are published to
+const (
+	TeamTopic  = "team.activity"
+	AssetTopic = "asset.activity"
+)
+
+// Asset EventType constants
+const (
+	EventFolderCreated      = "FOLDER_CREATED"
+	EventFolderUpdated      = "FOLDER_UPDATED"
+	EventFolderDeleted      = "FOLDER_DELETED"
+	EventFolderShared       = "FOLDER_SHARED"
+	EventFolderShareRevoked = "FOLDER_SHARE_REVOKED"
+	EventNoteCreated        = "NOTE_CREATED"
+	EventNoteUpdated        = "NOTE_UPDATED"
+	EventNoteDeleted        = "NOTE_DELETED"
+	EventNoteShared         = "NOTE_SHARED"
+	EventNoteShareRevoked   = "NOTE_SHARE_REVOKED"
+)
+
+// NewTeamEvent builds a team event with a fresh event ID and the current time
+func NewTeamEvent(eventType string, teamID uint64, performedBy, targetUserID uuid.UUID, details map[string]string) TeamEvent {
+	return TeamEvent{
+		EventID:      uuid.NewString(),
+		EventType:    eventType,
+		TeamID:       teamID,
+		PerformedBy:  performedBy,
+		TargetUserID: targetUserID,
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Details:      details,
+	}
+}
+
+// Key is the partition key; every event of a team lands on the same partition, in order
+func (e TeamEvent) Key() string {
+	return fmt.Sprintf("team-%d", e.TeamID)
+}
+
+// AssetEvent represents a change to a folder or note
+type AssetEvent struct {
+	EventID   string `json:"eventId"`
+	EventType string `json:"eventType"`
+	// AssetType is "folder" or "note"
+	AssetType string    `json:"assetType"`
+	AssetID   uuid.UUID `json:"assetId"`
+	// FolderID is the folder itself for folder events and the containing folder for note events
+	FolderID     uuid.UUID `json:"folderId"`
+	OwnerID      uuid.UUID `json:"ownerId"`
+	PerformedBy  uuid.UUID `json:"performedBy"`
+	TargetUserID uuid.UUID `json:"targetUserId,omitempty"`
+	// ImpersonatedBy is the manager who made the change while acting as PerformedBy
+	ImpersonatedBy *uuid.UUID        `json:"impersonatedBy,omitempty"`
+	Timestamp      string            `json:"timestamp"`
+	Details        map[string]string `json:"details,omitempty"`
+}
+
+// NewFolderEvent builds an event about a folder
+func NewFolderEvent(eventType string, folderID, ownerID, performedBy uuid.UUID, details map[string]string) AssetEvent {
+	return newAssetEvent(eventType, "folder", folderID, folderID, ownerID, performedBy, details)
+}
+
+// NewNoteEvent builds an event about a note
+func NewNoteEvent(eventType string, noteID, folderID, ownerID, performedBy uuid.UUID, details map[string]string) AssetEvent {
+	return newAssetEvent(eventType, "note", noteID, folderID, ownerID, performedBy, details)
+}
+
+func newAssetEvent(eventType, assetType string, assetID, folderID, ownerID, performedBy uuid.UUID, details map[string]string) AssetEvent {
+	return AssetEvent{
+		EventID:     uuid.NewString(),
+		EventType:   eventType,
+		AssetType:   assetType,
+		AssetID:     assetID,
+		FolderID:    folderID,
+		OwnerID:     ownerID,
+		PerformedBy: performedBy,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Details:     details,
+	}
+}
+
+// Key is the partition key; a folder and its notes share one so their events stay in order
+func (e AssetEvent) Key() string {
+	return fmt.Sprintf("folder-%s", e.FolderID)
+}
+
+// WithTarget returns the event addressed to userID, such as the user a folder was shared with
+func (e AssetEvent) WithTarget(userID uuid.UUID) AssetEvent {
+	e.TargetUserID = userID
+	return e
+}
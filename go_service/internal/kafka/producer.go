@@ -1,10 +1,10 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/google/uuid"
@@ -12,20 +12,52 @@ import (
 
 // TeamEvent represents a team activity event
 type TeamEvent struct {
+	// EventID is unique per event so consumers can drop redeliveries
+	EventID      string    `json:"eventId,omitempty"`
 	EventType    string    `json:"eventType"`
 	TeamID       uint64    `json:"teamId"`
 	PerformedBy  uuid.UUID `json:"performedBy"`
 	TargetUserID uuid.UUID `json:"targetUserId,omitempty"`
-	Timestamp    string    `json:"timestamp"`
+	// ImpersonatedBy is the manager who made the change while acting as PerformedBy
+	ImpersonatedBy *uuid.UUID `json:"impersonatedBy,omitempty"`
+	Timestamp      string     `json:"timestamp"`
+	// Details carries event-specific context, such as the previous name of a renamed team
+	Details map[string]string `json:"details,omitempty"`
 }
 
 // EventType constants
 const (
-	EventTeamCreated    = "TEAM_CREATED"
-	EventMemberAdded    = "MEMBER_ADDED"
-	EventMemberRemoved  = "MEMBER_REMOVED"
-	EventManagerAdded   = "MANAGER_ADDED"
-	EventManagerRemoved = "MANAGER_REMOVED"
+	EventTeamCreated       = "TEAM_CREATED"
+	EventTeamUpdated       = "TEAM_UPDATED"
+	EventTeamArchived      = "TEAM_ARCHIVED"
+	EventTeamRestored      = "TEAM_RESTORED"
+	EventTeamDeleted       = "TEAM_DELETED"
+	EventTeamParentChanged = "TEAM_PARENT_CHANGED"
+	EventMemberAdded       = "MEMBER_ADDED"
+	EventMemberRemoved     = "MEMBER_REMOVED"
+	EventManagerAdded      = "MANAGER_ADDED"
+	EventManagerRemoved    = "MANAGER_REMOVED"
+	EventMemberRoleChanged = "MEMBER_ROLE_CHANGED"
+	EventUserRoleChanged   = "USER_ROLE_CHANGED"
+
+	EventOwnershipTransferred = "OWNERSHIP_TRANSFERRED"
+	EventLeaderSucceeded      = "LEADER_SUCCEEDED"
+	EventSuccessionEscalated  = "LEADER_SUCCESSION_ESCALATED"
+	EventSuccessionBlocked    = "LEADER_SUCCESSION_BLOCKED"
+
+	EventInvitationCreated   = "INVITATION_CREATED"
+	EventInvitationResent    = "INVITATION_RESENT"
+	EventInvitationCancelled = "INVITATION_CANCELLED"
+	EventInvitationAccepted  = "INVITATION_ACCEPTED"
+	EventInvitationDeclined  = "INVITATION_DECLINED"
+	EventInvitationExpired   = "INVITATION_EXPIRED"
+
+	EventJoinRequested        = "JOIN_REQUESTED"
+	EventJoinRequestApproved  = "JOIN_REQUEST_APPROVED"
+	EventJoinRequestRejected  = "JOIN_REQUEST_REJECTED"
+	EventJoinRequestCancelled = "JOIN_REQUEST_CANCELLED"
+
+	EventTeamQuotaUpdated = "TEAM_QUOTA_UPDATED"
 )
 
 // Producer encapsulates a Kafka producer
@@ -46,6 +78,8 @@ func NewProducer(bootstrapServers, username, password, topic string) (*Producer,
 		"security.protocol": "SASL_SSL",
 		"sasl.mechanisms":   "PLAIN",
 		"acks":              "all",
+		// Keeps retried messages in order within a partition
+		"enable.idempotence": true,
 	})
 
 	if err != nil {
@@ -75,25 +109,21 @@ func NewProducer(bootstrapServers, username, password, topic string) (*Producer,
 
 // SendTeamEvent sends a team event to the Kafka topic
 func (p *Producer) SendTeamEvent(eventType string, teamID uint64, performedBy, targetUserID uuid.UUID) error {
-	event := TeamEvent{
-		EventType:    eventType,
-		TeamID:       teamID,
-		PerformedBy:  performedBy,
-		TargetUserID: targetUserID,
-		Timestamp:    time.Now().UTC().Format(time.RFC3339),
-	}
+	return p.SendTeamEventWithDetails(eventType, teamID, performedBy, targetUserID, nil)
+}
+
+// SendTeamEventWithDetails sends a team event carrying extra details
+func (p *Producer) SendTeamEventWithDetails(eventType string, teamID uint64, performedBy, targetUserID uuid.UUID, details map[string]string) error {
+	event := NewTeamEvent(eventType, teamID, performedBy, targetUserID, details)
 
 	eventJSON, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	// Use the team ID as the key for partitioning
-	key := fmt.Sprintf("team-%d", teamID)
-
 	err = p.producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
-		Key:            []byte(key),
+		Key:            []byte(event.Key()),
 		Value:          eventJSON,
 	}, nil)
 
@@ -104,6 +134,33 @@ func (p *Producer) SendTeamEvent(eventType string, teamID uint64, performedBy, t
 	return nil
 }
 
+// Publish sends a message to topic and waits until the broker acknowledges it
+func (p *Producer) Publish(ctx context.Context, topic, key string, value []byte) error {
+	delivery := make(chan kafka.Event, 1)
+	err := p.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(key),
+		Value:          value,
+	}, delivery)
+	if err != nil {
+		return fmt.Errorf("failed to produce message: %w", err)
+	}
+
+	select {
+	case e := <-delivery:
+		msg, ok := e.(*kafka.Message)
+		if !ok {
+			return fmt.Errorf("unexpected delivery event: %v", e)
+		}
+		if msg.TopicPartition.Error != nil {
+			return fmt.Errorf("failed to deliver message: %w", msg.TopicPartition.Error)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Close closes the producer
 func (p *Producer) Close() {
 	p.producer.Flush(15 * 1000) // 15 seconds timeout
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -9,11 +10,19 @@ import (
 	"go_service/internal/services"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
-	userService := services.NewUserService()
+// Values stored under "auth_type"
+const (
+	AuthTypeJWT            = "jwt"
+	AuthTypePersonalToken  = "personal_access_token"
+	AuthTypeServiceAccount = "service_account"
+)
+
+// AuthMiddleware authenticates the caller with a JWT or API token. Managers
+// may add X-Impersonate-User to act as another user; impersonations may be
+// nil to disable that.
+func AuthMiddleware(identities *services.IdentityCache, revocations *services.RevocationService, tokens *services.APITokenService, impersonations *services.ImpersonationService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -23,6 +32,11 @@ func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
+		if services.IsAPIToken(tokenString) {
+			authenticateAPIToken(c, tokenString, identities, revocations, tokens, impersonations)
+			return
+		}
+
 		claims, err := auth.ValidateToken(tokenString)
 		if err != nil {
 			log.Printf("Token validation failed: %v", err)
@@ -31,28 +45,95 @@ func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		log.Printf("Token validated successfully for user ID: %s", claims.UserID.String())
+		// Reject tokens revoked by jti or by the user's watermark
+		if err := revocations.Check(c.Request.Context(), claims); err != nil {
+			abortRevoked(c, claims.UserID.String(), err)
+			return
+		}
 
-		// Fetch user info from database to get role
-		userResponse, err := userService.GetUserByID(claims.UserID.String())
-		if err != nil {
-			log.Printf("Failed to fetch user data: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Fail to fetch user data"})
-			c.Abort()
+		// Resolve the user's role through the identity cache
+		user, ok := resolveUser(c, identities, claims.UserID.String())
+		if !ok {
 			return
 		}
 
-		if userResponse == nil || userResponse.User.ID == "" {
-			log.Printf("User data is empty for ID: %s", claims.UserID.String())
+		c.Set("user_id", claims.UserID)
+		c.Set("role", user.Role)
+		c.Set("auth_type", AuthTypeJWT)
+		c.Set("token_claims", claims)
+		proceed(c, identities, impersonations)
+	}
+}
+
+// authenticateAPIToken handles personal access tokens and service account credentials
+func authenticateAPIToken(c *gin.Context, raw string, identities *services.IdentityCache, revocations *services.RevocationService, tokens *services.APITokenService, impersonations *services.ImpersonationService) {
+	principal, err := tokens.Authenticate(raw)
+	if err != nil {
+		log.Printf("API token authentication failed: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
+	}
+
+	if principal.ServiceAccount != nil {
+		c.Set("user_id", principal.UserID)
+		c.Set("role", principal.ServiceAccount.Role)
+		c.Set("auth_type", AuthTypeServiceAccount)
+		c.Set("scopes", principal.Scopes)
+		c.Set("api_token_id", principal.Token.ID)
+		proceed(c, identities, impersonations)
+		return
+	}
+
+	// Personal tokens die with the user's other sessions
+	if err := revocations.CheckIssuedAt(c.Request.Context(), principal.UserID.String(), principal.Token.CreatedAt); err != nil {
+		abortRevoked(c, principal.UserID.String(), err)
+		return
+	}
+
+	user, ok := resolveUser(c, identities, principal.UserID.String())
+	if !ok {
+		return
+	}
+
+	c.Set("user_id", principal.UserID)
+	c.Set("role", user.Role)
+	c.Set("auth_type", AuthTypePersonalToken)
+	c.Set("scopes", principal.Scopes)
+	c.Set("api_token_id", principal.Token.ID)
+	proceed(c, identities, impersonations)
+}
+
+func resolveUser(c *gin.Context, identities *services.IdentityCache, userID string) (*services.User, bool) {
+	user, err := identities.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to fetch user data: %v", err)
+		if errors.Is(err, services.ErrUserNotFound) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-			c.Abort()
-			return
+		} else {
+			// The token may be fine; the user service is not
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "User service unavailable"})
 		}
+		c.Abort()
+		return nil, false
+	}
 
-		log.Printf("User found: ID=%s, Role=%s", userResponse.User.ID, userResponse.User.Role)
+	if user == nil || user.ID == "" {
+		log.Printf("User data is empty for ID: %s", userID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		c.Abort()
+		return nil, false
+	}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("role", userResponse.User.Role)
-		c.Next()
+	return user, true
+}
+
+func abortRevoked(c *gin.Context, userID string, err error) {
+	log.Printf("Rejected token for user %s: %v", userID, err)
+	if errors.Is(err, services.ErrTokenRevoked) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+	} else {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token status"})
 	}
+	c.Abort()
 }
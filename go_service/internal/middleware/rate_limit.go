@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_service/internal/cache"
+	"go_service/internal/redisclient"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit allows Limit requests per Window, with bursts of up to Limit
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseRateLimit parses limits written as "<requests>/<window>", e.g. "100/1m"
+func ParseRateLimit(value string) (RateLimit, error) {
+	count, window, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("rate limit %q must look like 100/1m", value)
+	}
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit < 1 {
+		return RateLimit{}, fmt.Errorf("invalid request count in rate limit %q", value)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid window in rate limit %q", value)
+	}
+	return RateLimit{Limit: limit, Window: d}, nil
+}
+
+// RateLimitPolicy is the limit for one route group
+type RateLimitPolicy struct {
+	Default RateLimit
+	// PerRole overrides Default for callers with that role
+	PerRole map[string]RateLimit
+	// ByIP keys the bucket on the client IP even for authenticated callers
+	ByIP bool
+}
+
+// DefaultRateLimitPolicies are the built-in limits by route group
+func DefaultRateLimitPolicies() map[string]RateLimitPolicy {
+	return map[string]RateLimitPolicy{
+		// Applied before authentication to slow down token guessing
+		"ip": {Default: RateLimit{Limit: 300, Window: time.Minute}, ByIP: true},
+		"teams": {
+			Default: RateLimit{Limit: 120, Window: time.Minute},
+			PerRole: map[string]RateLimit{"MANAGER": {Limit: 300, Window: time.Minute}},
+		},
+		"assets": {
+			Default: RateLimit{Limit: 60, Window: time.Minute},
+			PerRole: map[string]RateLimit{"MANAGER": {Limit: 120, Window: time.Minute}},
+		},
+		"import": {
+			Default: RateLimit{Limit: 5, Window: time.Minute},
+			PerRole: map[string]RateLimit{"MANAGER": {Limit: 10, Window: time.Minute}},
+		},
+	}
+}
+
+// RateLimitPoliciesFromEnv applies RATE_LIMIT_<GROUP>=<n>/<window> and
+// RATE_LIMIT_<GROUP>_<ROLE>=<n>/<window> overrides to the defaults
+func RateLimitPoliciesFromEnv() map[string]RateLimitPolicy {
+	policies := DefaultRateLimitPolicies()
+
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "RATE_LIMIT_") || name == "RATE_LIMIT_ENABLED" {
+			continue
+		}
+		limit, err := ParseRateLimit(value)
+		if err != nil {
+			log.Printf("Ignoring %s: %v", name, err)
+			continue
+		}
+
+		group, role, _ := strings.Cut(strings.ToLower(strings.TrimPrefix(name, "RATE_LIMIT_")), "_")
+		policy := policies[group]
+		if role == "" {
+			policy.Default = limit
+		} else {
+			perRole := make(map[string]RateLimit, len(policy.PerRole)+1)
+			for r, l := range policy.PerRole {
+				perRole[r] = l
+			}
+			perRole[strings.ToUpper(role)] = limit
+			policy.PerRole = perRole
+		}
+		policies[group] = policy
+	}
+
+	return policies
+}
+
+// redisRetryDelay is how long the limiter stays on local buckets after a Redis error
+const redisRetryDelay = 5 * time.Second
+
+// RateLimiter enforces token-bucket limits stored in Redis. When Redis is
+// unreachable it falls back to per-instance buckets, which are only as strict
+// as a single instance can be.
+type RateLimiter struct {
+	store    *redisclient.RateLimitStore
+	policies map[string]RateLimitPolicy
+
+	mu             sync.Mutex
+	local          *cache.LRU[string, float64]
+	redisDownUntil time.Time
+}
+
+// NewRateLimiter creates a limiter; store may be nil to use local buckets only
+func NewRateLimiter(store *redisclient.RateLimitStore, policies map[string]RateLimitPolicy) *RateLimiter {
+	return &RateLimiter{
+		store:    store,
+		policies: policies,
+		local:    cache.NewLRU[string, float64](10000),
+	}
+}
+
+// Limit returns middleware enforcing the named policy. A nil limiter or an
+// unknown policy lets every request through.
+func (l *RateLimiter) Limit(name string) gin.HandlerFunc {
+	if l == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	policy, ok := l.policies[name]
+	if !ok || policy.Default.Limit == 0 {
+		log.Printf("No rate limit policy %q, requests will not be limited", name)
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		limit, subject := policy.resolve(c)
+		result := l.take(c.Request.Context(), name, subject, limit)
+
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Limit, ceilSeconds(limit.Window)))
+
+		if !result.Allowed {
+			log.Printf("Rate limit %s exceeded by %s on %s %s", name, subject, c.Request.Method, c.FullPath())
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"error":   "Too many requests",
+				"details": fmt.Sprintf("retry in %d seconds", ceilSeconds(result.RetryAfter)),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// resolve picks the limit for the caller's role and the bucket subject
+func (p RateLimitPolicy) resolve(c *gin.Context) (RateLimit, string) {
+	limit := p.Default
+	if role, exists := c.Get("role"); exists {
+		if roleLimit, ok := p.PerRole[strings.ToUpper(fmt.Sprint(role))]; ok {
+			limit = roleLimit
+		}
+	}
+
+	if !p.ByIP {
+		if userID, exists := c.Get("user_id"); exists {
+			return limit, fmt.Sprintf("user:%v", userID)
+		}
+	}
+	return limit, "ip:" + c.ClientIP()
+}
+
+func (l *RateLimiter) take(ctx context.Context, name, subject string, limit RateLimit) redisclient.RateLimitResult {
+	if l.store != nil && l.redisAvailable() {
+		result, err := l.store.Take(ctx, name, subject, limit.Limit, limit.Window)
+		if err == nil {
+			return result
+		}
+		log.Printf("Rate limit store unavailable, using local limits for %s: %v", redisRetryDelay, err)
+		l.mu.Lock()
+		l.redisDownUntil = time.Now().Add(redisRetryDelay)
+		l.mu.Unlock()
+	}
+
+	return l.takeLocal(name+":"+subject, limit, time.Now())
+}
+
+func (l *RateLimiter) redisAvailable() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().After(l.redisDownUntil)
+}
+
+// takeLocal is the in-memory equivalent of the Redis token bucket script
+func (l *RateLimiter) takeLocal(key string, limit RateLimit, now time.Time) redisclient.RateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := float64(limit.Limit)
+	rate := capacity / float64(limit.Window)
+
+	tokens, last, found := l.local.Get(key)
+	if !found {
+		tokens = capacity
+	} else if elapsed := now.Sub(last); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+float64(elapsed)*rate)
+	}
+
+	result := redisclient.RateLimitResult{}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - tokens) / rate))
+	}
+	l.local.Set(key, tokens, now)
+
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration(math.Ceil((capacity - tokens) / rate))
+	return result
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
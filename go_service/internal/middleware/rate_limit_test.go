@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	limit, err := ParseRateLimit("100/1m")
+	if err != nil || limit.Limit != 100 || limit.Window != time.Minute {
+		t.Fatalf("ParseRateLimit(100/1m) = %+v, %v", limit, err)
+	}
+	for _, bad := range []string{"", "100", "0/1m", "x/1m", "10/forever"} {
+		if _, err := ParseRateLimit(bad); err == nil {
+			t.Errorf("ParseRateLimit(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRateLimiterLocalFallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(nil, map[string]RateLimitPolicy{
+		"test": {
+			Default: RateLimit{Limit: 2, Window: time.Minute},
+			PerRole: map[string]RateLimit{"MANAGER": {Limit: 3, Window: time.Minute}},
+		},
+	})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if role := c.GetHeader("X-Role"); role != "" {
+			c.Set("role", role)
+			c.Set("user_id", "user-"+role)
+		}
+	})
+	router.GET("/", limiter.Limit("test"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(role string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Role", role)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := send("MEMBER"); w.Code != http.StatusOK {
+			t.Fatalf("member request %d: status %d", i, w.Code)
+		}
+	}
+	w := send("MEMBER")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected headers: %v", w.Header())
+	}
+
+	// Managers have their own bucket and a higher limit
+	for i := 0; i < 3; i++ {
+		if w := send("MANAGER"); w.Code != http.StatusOK {
+			t.Fatalf("manager request %d: status %d", i, w.Code)
+		}
+	}
+	if w := send("MANAGER"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for manager, got %d", w.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"go_service/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope rejects API tokens that were not granted scope. Interactive
+// JWT sessions carry no scopes and are limited by role checks only.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasScope(c, scope) {
+			userID, _ := c.Get("user_id")
+			log.Printf("Token for %v lacks scope %s for %s %s", userID, scope, c.Request.Method, c.FullPath())
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Token is missing required scope",
+				"details": scope,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasScope reports whether the current request may use scope
+func HasScope(c *gin.Context, scope string) bool {
+	value, exists := c.Get("scopes")
+	if !exists {
+		return true
+	}
+	scopes, _ := value.([]string)
+	return auth.HasScope(scopes, scope)
+}
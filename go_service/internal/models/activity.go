@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TeamActivity is a team event as persisted by the consumer for the activity
+// feed. Rows outlive the team so its history can still be audited.
+type TeamActivity struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// EventID is the producer's event ID; redeliveries of an event are dropped on it.
+	// Events from producers that do not set one have none.
+	EventID      *string    `gorm:"size:64;uniqueIndex" json:"eventId,omitempty"`
+	EventType    string     `gorm:"size:50;not null" json:"eventType"`
+	TeamID       int        `gorm:"not null;index:idx_team_activity_feed,priority:1" json:"teamId"`
+	PerformedBy  uuid.UUID  `gorm:"type:uuid" json:"performedBy"`
+	TargetUserID *uuid.UUID `gorm:"type:uuid" json:"targetUserId,omitempty"`
+	// ImpersonatedBy is the manager who acted as PerformedBy, if any
+	ImpersonatedBy *uuid.UUID `gorm:"type:uuid" json:"impersonatedBy,omitempty"`
+	// OccurredAt is when the event was produced, not when it was consumed
+	OccurredAt time.Time         `gorm:"not null;index:idx_team_activity_feed,priority:2" json:"occurredAt"`
+	Details    map[string]string `gorm:"serializer:json;type:jsonb" json:"details,omitempty"`
+}
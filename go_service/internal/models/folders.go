@@ -10,15 +10,21 @@ type Folder struct {
 	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	FolderName string    `gorm:"size:150;not null" json:"folderName"`
 	OwnerID    uuid.UUID `gorm:"type:uuid;not null" json:"ownerId"`
-	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
+	// TeamID is set on folders that belong to a team; members reach them
+	// through their roster role and OwnerID only records who created them
+	TeamID    *int      `gorm:"index" json:"teamId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
-// FolderShare represents sharing permissions for folders
+// FolderShare represents sharing permissions for folders. It is granted to
+// a user or to a whole team, whose roster is read at request time; exactly
+// one of UserID and TeamID is set.
 type FolderShare struct {
 	ID          uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	FolderID    uuid.UUID   `gorm:"type:uuid;not null" json:"folderId"`
-	UserID      uuid.UUID   `gorm:"type:uuid;not null" json:"userId"`
+	UserID      *uuid.UUID  `gorm:"type:uuid;index" json:"userId,omitempty"`
+	TeamID      *int        `gorm:"index" json:"teamId,omitempty"`
 	AccessLevel AccessLevel `gorm:"type:access_level;not null" json:"accessLevel"`
 	SharedByID  uuid.UUID   `gorm:"type:uuid;not null" json:"sharedById"`
 	CreatedAt   time.Time   `json:"createdAt"`
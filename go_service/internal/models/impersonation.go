@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImpersonationAudit records one request made while impersonating another user
+type ImpersonationAudit struct {
+	ID       uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ActorID  uuid.UUID `gorm:"type:uuid;not null;index" json:"actorId"`
+	TargetID uuid.UUID `gorm:"type:uuid;not null;index" json:"targetId"`
+	// Elevated sessions may make changes; the rest are read-only
+	Elevated  bool      `gorm:"not null;default:false" json:"elevated"`
+	Reason    string    `gorm:"size:500" json:"reason,omitempty"`
+	Method    string    `gorm:"size:10;not null" json:"method"`
+	Path      string    `gorm:"size:500;not null" json:"path"`
+	Status    int       `gorm:"not null" json:"status"`
+	ClientIP  string    `gorm:"size:64" json:"clientIp"`
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvitationStatus is the lifecycle state of a team invitation
+type InvitationStatus string
+
+const (
+	InvitationPending   InvitationStatus = "pending"
+	InvitationAccepted  InvitationStatus = "accepted"
+	InvitationDeclined  InvitationStatus = "declined"
+	InvitationCancelled InvitationStatus = "cancelled"
+	InvitationExpired   InvitationStatus = "expired"
+)
+
+// TeamInvitation asks a user, by ID or email, to join a team.
+// Only a SHA-256 hash of the invitation token is stored.
+type TeamInvitation struct {
+	ID          uuid.UUID        `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TeamID      int              `gorm:"not null;index" json:"teamId"`
+	InviteeID   *uuid.UUID       `gorm:"type:uuid;index" json:"inviteeId,omitempty"`
+	Email       string           `gorm:"size:255;index" json:"email,omitempty"`
+	InvitedByID uuid.UUID        `gorm:"type:uuid;not null" json:"invitedById"`
+	Status      InvitationStatus `gorm:"size:20;not null;default:pending;index" json:"status"`
+	TokenHash   string           `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	// SendCount is how many times the invitation has been sent, including resends
+	SendCount   int        `gorm:"not null;default:1" json:"sendCount"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expiresAt"`
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+
+	// Foreign key relationships
+	Team Team `gorm:"foreignKey:TeamID" json:"-"`
+}
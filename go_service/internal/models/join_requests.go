@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JoinRequestStatus is the lifecycle state of a join request
+type JoinRequestStatus string
+
+const (
+	JoinRequestPending   JoinRequestStatus = "pending"
+	JoinRequestApproved  JoinRequestStatus = "approved"
+	JoinRequestRejected  JoinRequestStatus = "rejected"
+	JoinRequestCancelled JoinRequestStatus = "cancelled"
+)
+
+// TeamJoinRequest is a user's request to join a team with the request join policy
+type TeamJoinRequest struct {
+	ID     uuid.UUID         `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TeamID int               `gorm:"not null;index" json:"teamId"`
+	UserID uuid.UUID         `gorm:"type:uuid;not null;index" json:"userId"`
+	Status JoinRequestStatus `gorm:"size:20;not null;default:pending;index" json:"status"`
+	// Message is the requester's note to the team leaders
+	Message string `gorm:"size:500" json:"message,omitempty"`
+	// Reason is the leader's explanation of the decision
+	Reason      string     `gorm:"size:500" json:"reason,omitempty"`
+	DecidedByID *uuid.UUID `gorm:"type:uuid" json:"decidedById,omitempty"`
+	DecidedAt   *time.Time `json:"decidedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
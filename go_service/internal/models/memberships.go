@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a membership interval ended
+const (
+	MembershipLeft         = "left"
+	MembershipRoleChanged  = "role_changed"
+	MembershipTeamArchived = "team_archived"
+	MembershipTeamDeleted  = "team_deleted"
+)
+
+// MembershipInterval is a span of time a user held one role on a team. A
+// role change ends one interval and starts the next; EndedAt is nil while
+// the interval is current. Rows outlive the roster entry so past rosters
+// can be reconstructed.
+type MembershipInterval struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	TeamID    int        `gorm:"not null;index:idx_membership_team_period,priority:1" json:"teamId"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"userId"`
+	Role      RosterRole `gorm:"size:20;not null" json:"role"`
+	StartedAt time.Time  `gorm:"not null;index:idx_membership_team_period,priority:2" json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	EndReason string     `gorm:"size:20" json:"endReason,omitempty"`
+}
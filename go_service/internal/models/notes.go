@@ -16,11 +16,13 @@ type Note struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
-// NoteShare represents sharing permissions for individual notes
+// NoteShare represents sharing permissions for individual notes, granted
+// to a user or to a whole team like FolderShare
 type NoteShare struct {
 	ID          uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	NoteID      uuid.UUID   `gorm:"type:uuid;not null" json:"noteId"`
-	UserID      uuid.UUID   `gorm:"type:uuid;not null" json:"userId"`
+	UserID      *uuid.UUID  `gorm:"type:uuid;index" json:"userId,omitempty"`
+	TeamID      *int        `gorm:"index" json:"teamId,omitempty"`
 	AccessLevel AccessLevel `gorm:"type:access_level;not null" json:"accessLevel"`
 	SharedByID  uuid.UUID   `gorm:"type:uuid;not null" json:"sharedById"`
 	CreatedAt   time.Time   `json:"createdAt"`
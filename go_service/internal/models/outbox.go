@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEvent is an event stored in the same transaction as the change it
+// describes, waiting for the outbox relay to publish it to Kafka
+type OutboxEvent struct {
+	// ID orders events; the relay publishes each key's events in ID order
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"eventId"`
+	Topic     string    `gorm:"size:100;not null" json:"topic"`
+	Key       string    `gorm:"size:100;not null;index" json:"key"`
+	EventType string    `gorm:"size:50;not null" json:"eventType"`
+	Payload   string    `gorm:"type:jsonb;not null" json:"payload"`
+	// Attempts counts failed publishes; NextAttemptAt delays the next one
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"nextAttemptAt"`
+	LastError     string     `gorm:"size:1000" json:"lastError,omitempty"`
+	DeliveredAt   *time.Time `gorm:"index" json:"deliveredAt,omitempty"`
+	// DeadAt is set once the relay gives up on the event; it then no longer
+	// holds back the events after it on the same key
+	DeadAt    *time.Time `gorm:"index" json:"deadAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TeamQuota overrides the global quota defaults for one team. A nil limit
+// falls back to the default and zero means unlimited.
+type TeamQuota struct {
+	TeamID     int  `gorm:"primaryKey;autoIncrement:false" json:"teamId"`
+	MaxMembers *int `json:"maxMembers"`
+	// MaxFoldersPerMember and MaxNotesPerMember cap what each member of the
+	// team may own across all their folders and notes
+	MaxFoldersPerMember *int `json:"maxFoldersPerMember"`
+	MaxNotesPerMember   *int `json:"maxNotesPerMember"`
+	// MaxNoteBytes caps the total size of the notes in the team's folders
+	MaxNoteBytes *int      `json:"maxNoteBytes"`
+	UpdatedByID  uuid.UUID `gorm:"type:uuid" json:"updatedById"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
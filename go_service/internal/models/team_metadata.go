@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// FieldType is the value type of a custom team field
+type FieldType string
+
+const (
+	FieldTypeString FieldType = "string"
+	FieldTypeNumber FieldType = "number"
+	FieldTypeEnum   FieldType = "enum"
+	// FieldTypeDate holds a calendar date, YYYY-MM-DD
+	FieldTypeDate FieldType = "date"
+)
+
+// Valid reports whether t is a known field type
+func (t FieldType) Valid() bool {
+	switch t {
+	case FieldTypeString, FieldTypeNumber, FieldTypeEnum, FieldTypeDate:
+		return true
+	}
+	return false
+}
+
+// TeamFieldDefinition is a custom field, defined by managers, that every team may carry
+type TeamFieldDefinition struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Key names the field in API requests, list filters and CSV headers
+	Key  string    `gorm:"size:64;not null;uniqueIndex" json:"key"`
+	Name string    `gorm:"size:150;not null" json:"name"`
+	Type FieldType `gorm:"size:20;not null" json:"type"`
+	// Options lists the allowed values of an enum field
+	Options   []string  `gorm:"serializer:json;type:text" json:"options,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TeamFieldValue is a team's value for a custom field in canonical text form:
+// numbers in their shortest decimal form and dates as YYYY-MM-DD, so equal
+// values compare equal in filters
+type TeamFieldValue struct {
+	TeamID  int    `gorm:"primaryKey;autoIncrement:false" json:"teamId"`
+	FieldID uint   `gorm:"primaryKey;autoIncrement:false;index" json:"fieldId"`
+	Value   string `gorm:"size:500;not null" json:"value"`
+}
+
+// TeamLabel is a free-form label on a team, such as a location or product area
+type TeamLabel struct {
+	TeamID int    `gorm:"primaryKey;autoIncrement:false" json:"teamId"`
+	Label  string `gorm:"primaryKey;size:100;index" json:"label"`
+}
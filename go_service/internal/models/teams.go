@@ -6,23 +6,76 @@ import (
 	"github.com/google/uuid"
 )
 
+// JoinPolicy controls how users other than leaders can get onto a team roster
+type JoinPolicy string
+
+const (
+	// JoinPolicyOpen lets anyone join straight away
+	JoinPolicyOpen JoinPolicy = "open"
+	// JoinPolicyRequest lets anyone ask to join, subject to a leader's approval
+	JoinPolicyRequest JoinPolicy = "request"
+	// JoinPolicyInviteOnly admits only users added or invited by a leader
+	JoinPolicyInviteOnly JoinPolicy = "invite_only"
+)
+
+// Valid reports whether p is a known join policy
+func (p JoinPolicy) Valid() bool {
+	return p == JoinPolicyOpen || p == JoinPolicyRequest || p == JoinPolicyInviteOnly
+}
+
 type Team struct {
 	ID        int       `gorm:"primary_key;auto_increment;column:teamId" json:"id"`
 	TeamName  string    `gorm:"size:150;not null;unique;column:teamName" json:"teamName"`
 	CreatedAt time.Time `gorm:"column:createdAt" json:"createdAt"`
 	UpdatedAt time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+	// JoinPolicy defaults to invite_only, matching teams that predate join requests
+	JoinPolicy JoinPolicy `gorm:"size:20;not null;default:invite_only;column:joinPolicy" json:"joinPolicy"`
+	// ArchivedAt is set while the team is archived; archived teams are hidden and have no roster
+	ArchivedAt *time.Time `gorm:"column:archivedAt;index" json:"archivedAt,omitempty"`
+	// ParentTeamID places the team under another one, e.g. a squad under its department
+	ParentTeamID *int `gorm:"column:parentTeamId;index" json:"parentTeamId,omitempty"`
 }
 
 func (Team) TableName() string {
 	return "Teams"
 }
 
+// RosterRole is a user's place on a team roster
+type RosterRole string
+
+const (
+	// RosterRoleOwner is held by exactly one user per team and can manage everything, roles included
+	RosterRoleOwner RosterRole = "owner"
+	// RosterRoleLeader is a co-lead who manages the team and its members
+	RosterRoleLeader RosterRole = "leader"
+	// RosterRoleMember is a regular member
+	RosterRoleMember RosterRole = "member"
+	// RosterRoleViewer is a read-only observer
+	RosterRoleViewer RosterRole = "viewer"
+)
+
+// Valid reports whether r is a known roster role
+func (r RosterRole) Valid() bool {
+	switch r {
+	case RosterRoleOwner, RosterRoleLeader, RosterRoleMember, RosterRoleViewer:
+		return true
+	}
+	return false
+}
+
+// IsLeader reports whether the role leads the team; it is what the isLeader column holds
+func (r RosterRole) IsLeader() bool {
+	return r == RosterRoleOwner || r == RosterRoleLeader
+}
+
 // Roster represents the junction table for Team-User many-to-many relationship
 type Roster struct {
 	ID       int       `gorm:"primary_key;auto_increment;column:rosterId" json:"id"`
 	TeamID   int       `gorm:"not null;column:teamId" json:"teamId"`
 	UserID   uuid.UUID `gorm:"type:uuid;not null;column:userId" json:"userId"`
 	IsLeader bool      `gorm:"default:false;column:isLeader" json:"isLeader"` // Match Node.js field
+	// Role refines IsLeader; set it with SetRole so the two stay in step
+	Role RosterRole `gorm:"size:20;not null;default:member;column:role" json:"role"`
 
 	// Foreign key relationships
 	Team Team `gorm:"foreignKey:TeamID"`
@@ -32,3 +85,27 @@ type Roster struct {
 func (Roster) TableName() string {
 	return "Rosters"
 }
+
+// SetRole sets the role along with the older isLeader flag, which leader
+// queries still use
+func (r *Roster) SetRole(role RosterRole) {
+	r.Role = role
+	r.IsLeader = role.IsLeader()
+}
+
+// NewRoster returns a roster entry for userID on teamID with the given role
+func NewRoster(teamID int, userID uuid.UUID, role RosterRole) *Roster {
+	roster := &Roster{TeamID: teamID, UserID: userID}
+	roster.SetRole(role)
+	return roster
+}
+
+// ArchivedRoster keeps the roster of an archived team so restoring it brings the members back
+type ArchivedRoster struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	TeamID     int        `gorm:"not null;index" json:"teamId"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null" json:"userId"`
+	IsLeader   bool       `gorm:"not null;default:false" json:"isLeader"`
+	Role       RosterRole `gorm:"size:20;not null;default:member" json:"role"`
+	ArchivedAt time.Time  `gorm:"not null" json:"archivedAt"`
+}
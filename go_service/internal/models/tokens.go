@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceAccount is a non-human principal used by scripts and background jobs
+type ServiceAccount struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name        string     `gorm:"size:150;not null;unique" json:"name"`
+	Description string     `gorm:"size:500" json:"description"`
+	Role        string     `gorm:"size:20;not null" json:"role"`
+	CreatedByID uuid.UUID  `gorm:"type:uuid;not null" json:"createdById"`
+	DisabledAt  *time.Time `json:"disabledAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// APIToken is a personal access token or service account credential.
+// Only a SHA-256 hash of the secret is stored.
+type APIToken struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name             string     `gorm:"size:150;not null" json:"name"`
+	Prefix           string     `gorm:"size:16;not null" json:"prefix"`
+	TokenHash        string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	UserID           *uuid.UUID `gorm:"type:uuid;index" json:"userId,omitempty"`
+	ServiceAccountID *uuid.UUID `gorm:"type:uuid;index" json:"serviceAccountId,omitempty"`
+	Scopes           []string   `gorm:"serializer:json;type:text;not null" json:"scopes"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expiresAt"`
+	LastUsedAt       *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	CreatedByID      uuid.UUID  `gorm:"type:uuid;not null" json:"createdById"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+
+	// Foreign key relationships
+	ServiceAccount *ServiceAccount `gorm:"foreignKey:ServiceAccountID" json:"serviceAccount,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User mirrors the Node user-service's Users table. That service owns the
+// schema (including the role enum), so it is not auto-migrated here.
+type User struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid();column:userId" json:"userId"`
+	Username  string    `gorm:"not null;column:username" json:"username"`
+	Email     string    `gorm:"not null;unique;column:email" json:"email"`
+	Password  string    `gorm:"not null;column:password" json:"-"`
+	Role      string    `gorm:"not null;column:role" json:"role"`
+	CreatedAt time.Time `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName overrides the table name used by User to `Users`
+func (User) TableName() string {
+	return "Users"
+}
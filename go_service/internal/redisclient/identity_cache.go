@@ -0,0 +1,157 @@
+package redisclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// identityInvalidationChannel is the pub/sub channel used to tell every
+// instance to drop a user from its in-process cache
+const identityInvalidationChannel = "identity:invalidate"
+
+// identityGenerationRetention keeps a user's generation around far longer
+// than any fetch can be in flight
+const identityGenerationRetention = 24 * time.Hour
+
+// setIdentityIfCurrent stores ARGV[1] under KEYS[1] for ARGV[2] milliseconds
+// unless the generation in KEYS[2] has moved past ARGV[3], i.e. the identity
+// was invalidated while it was being fetched
+var setIdentityIfCurrent = redis.NewScript(`
+local generation = tonumber(redis.call('GET', KEYS[2]) or '0')
+if generation ~= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// Identity is the cached subset of a user record needed for authentication
+type Identity struct {
+	UserID    string    `json:"userId"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// IdentityStore provides the shared Redis tier of the identity cache
+type IdentityStore struct {
+	client *redis.Client
+}
+
+// NewIdentityStore creates a new IdentityStore instance
+func NewIdentityStore(client *redis.Client) *IdentityStore {
+	return &IdentityStore{
+		client: client,
+	}
+}
+
+// GetIdentityKey returns the Redis key for a cached user identity
+func (s *IdentityStore) GetIdentityKey(userID string) string {
+	return fmt.Sprintf("user:%s:identity", userID)
+}
+
+// GetGenerationKey returns the Redis key counting a user's invalidations
+func (s *IdentityStore) GetGenerationKey(userID string) string {
+	return fmt.Sprintf("user:%s:identity:generation", userID)
+}
+
+// Generation returns how many times userID's identity has been invalidated
+// lately. Read it before fetching the identity and pass it to Set.
+func (s *IdentityStore) Generation(ctx context.Context, userID string) (int64, error) {
+	if s.client == nil {
+		return 0, fmt.Errorf("Redis client not initialized")
+	}
+
+	generation, err := s.client.Get(ctx, s.GetGenerationKey(userID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return generation, err
+}
+
+// Get retrieves a cached identity, returning nil on a cache miss
+func (s *IdentityStore) Get(ctx context.Context, userID string) (*Identity, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("Redis client not initialized")
+	}
+
+	data, err := s.client.Get(ctx, s.GetIdentityKey(userID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			// Cache miss
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var identity Identity
+	if err := json.Unmarshal(data, &identity); err != nil {
+		log.Printf("Invalid identity in cache for %s: %v", userID, err)
+		return nil, nil
+	}
+
+	return &identity, nil
+}
+
+// Set stores an identity fetched after reading generation, unless it has
+// been invalidated since; retention should cover the max-stale window so the
+// entry can still be served while the user service is down
+func (s *IdentityStore) Set(ctx context.Context, identity *Identity, retention time.Duration, generation int64) error {
+	if s.client == nil {
+		return fmt.Errorf("Redis client not initialized")
+	}
+
+	data, err := json.Marshal(identity)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{s.GetIdentityKey(identity.UserID), s.GetGenerationKey(identity.UserID)}
+	return setIdentityIfCurrent.Run(ctx, s.client, keys, data, retention.Milliseconds(), generation).Err()
+}
+
+// Invalidate deletes a cached identity, moves its generation on so fetches
+// already in flight are not stored, and notifies every instance
+func (s *IdentityStore) Invalidate(ctx context.Context, userID string) error {
+	if s.client == nil {
+		return fmt.Errorf("Redis client not initialized")
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, s.GetIdentityKey(userID))
+	pipe.Incr(ctx, s.GetGenerationKey(userID))
+	pipe.Expire(ctx, s.GetGenerationKey(userID), identityGenerationRetention)
+	pipe.Publish(ctx, identityInvalidationChannel, userID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// SubscribeInvalidations calls onInvalidate for every user ID published by
+// Invalidate until ctx is cancelled
+func (s *IdentityStore) SubscribeInvalidations(ctx context.Context, onInvalidate func(userID string)) {
+	if s.client == nil {
+		return
+	}
+
+	sub := s.client.Subscribe(ctx, identityInvalidationChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			onInvalidate(msg.Payload)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package redisclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills the bucket from the time elapsed since the last
+// request and takes one token if available. It uses the Redis clock so every
+// instance agrees on time.
+//
+// KEYS[1] bucket key; ARGV[1] capacity; ARGV[2] window in ms (time to refill
+// an empty bucket). Returns {allowed, remaining, retry_after_ms, reset_ms}.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local rate = capacity / window
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], window)
+
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+// RateLimitResult is the outcome of taking a token from a bucket
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// RateLimitStore keeps token buckets in Redis so limits hold across instances
+type RateLimitStore struct {
+	client *redis.Client
+}
+
+// NewRateLimitStore creates a new RateLimitStore instance
+func NewRateLimitStore(client *redis.Client) *RateLimitStore {
+	return &RateLimitStore{
+		client: client,
+	}
+}
+
+// GetBucketKey returns the Redis key of a rate limit bucket
+func (s *RateLimitStore) GetBucketKey(policy, subject string) string {
+	return fmt.Sprintf("ratelimit:%s:%s", policy, subject)
+}
+
+// Take removes one token from the bucket for subject under policy, allowing
+// limit requests per window
+func (s *RateLimitStore) Take(ctx context.Context, policy, subject string, limit int, window time.Duration) (RateLimitResult, error) {
+	if s.client == nil {
+		return RateLimitResult{}, fmt.Errorf("Redis client not initialized")
+	}
+
+	values, err := tokenBucketScript.Run(ctx, s.client, []string{s.GetBucketKey(policy, subject)},
+		limit, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(values) != 4 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,158 @@
+package redisclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// revocationChannel is the pub/sub channel used to push revocations to
+// every instance so cached decisions do not outlive them
+const revocationChannel = "revocation:events"
+
+// legacyWatermarkLimit separates watermarks stored in Unix seconds, as they
+// once were, from those in Unix microseconds: no microsecond value since 1973
+// is this small
+const legacyWatermarkLimit = 100_000_000_000
+
+// raiseWatermark sets KEYS[1] to ARGV[1] and announces it on ARGV[2] as
+// ARGV[3], unless the stored watermark is already at or past it. Comparing in
+// the script keeps two concurrent revocations from moving it backwards.
+// Microseconds fit a Lua number exactly; nanoseconds would not.
+var raiseWatermark = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current < tonumber(ARGV[4]) then
+	current = current * 1000000
+end
+if tonumber(ARGV[1]) <= current then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+redis.call('PUBLISH', ARGV[2], ARGV[3])
+return 1
+`)
+
+// RevocationStore keeps revoked token IDs and per-user revocation watermarks
+type RevocationStore struct {
+	client *redis.Client
+}
+
+// NewRevocationStore creates a new RevocationStore instance
+func NewRevocationStore(client *redis.Client) *RevocationStore {
+	return &RevocationStore{
+		client: client,
+	}
+}
+
+// GetRevokedTokenKey returns the Redis key marking a jti as revoked
+func (s *RevocationStore) GetRevokedTokenKey(jti string) string {
+	return fmt.Sprintf("revoked:jti:%s", jti)
+}
+
+// GetUserWatermarkKey returns the Redis key holding a user's revocation watermark
+func (s *RevocationStore) GetUserWatermarkKey(userID string) string {
+	return fmt.Sprintf("revoked:user:%s:before", userID)
+}
+
+// RevokeToken marks a jti as revoked until the token would have expired anyway
+func (s *RevocationStore) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if s.client == nil {
+		return fmt.Errorf("Redis client not initialized")
+	}
+
+	pipe := s.client.Pipeline()
+	pipe.Set(ctx, s.GetRevokedTokenKey(jti), 1, ttl)
+	pipe.Publish(ctx, revocationChannel, "jti:"+jti)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// IsTokenRevoked reports whether a jti has been revoked
+func (s *RevocationStore) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if s.client == nil {
+		return false, fmt.Errorf("Redis client not initialized")
+	}
+
+	n, err := s.client.Exists(ctx, s.GetRevokedTokenKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetUserWatermark invalidates every token issued to userID before the given
+// time, kept to the microsecond. The watermark only ever moves forward.
+func (s *RevocationStore) SetUserWatermark(ctx context.Context, userID string, before time.Time) error {
+	if s.client == nil {
+		return fmt.Errorf("Redis client not initialized")
+	}
+
+	return raiseWatermark.Run(ctx, s.client, []string{s.GetUserWatermarkKey(userID)},
+		before.UnixMicro(), revocationChannel, "user:"+userID, legacyWatermarkLimit).Err()
+}
+
+// GetUserWatermark returns the user's revocation watermark, or the zero time if none is set
+func (s *RevocationStore) GetUserWatermark(ctx context.Context, userID string) (time.Time, error) {
+	if s.client == nil {
+		return time.Time{}, fmt.Errorf("Redis client not initialized")
+	}
+
+	value, err := s.client.Get(ctx, s.GetUserWatermarkKey(userID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	watermark, err := parseWatermark(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid watermark for user %s: %w", userID, err)
+	}
+	return watermark, nil
+}
+
+// parseWatermark reads a stored watermark, in Unix microseconds or, for one
+// written before they were, Unix seconds
+func parseWatermark(value string) (time.Time, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n < legacyWatermarkLimit {
+		return time.Unix(n, 0), nil
+	}
+	return time.UnixMicro(n), nil
+}
+
+// SubscribeRevocations calls onTokenRevoked or onUserRevoked for every
+// revocation published by any instance until ctx is cancelled
+func (s *RevocationStore) SubscribeRevocations(ctx context.Context, onTokenRevoked, onUserRevoked func(id string)) {
+	if s.client == nil {
+		return
+	}
+
+	sub := s.client.Subscribe(ctx, revocationChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if jti, found := strings.CutPrefix(msg.Payload, "jti:"); found {
+				onTokenRevoked(jti)
+			} else if userID, found := strings.CutPrefix(msg.Payload, "user:"); found {
+				onUserRevoked(userID)
+			}
+		}
+	}
+}
@@ -95,8 +95,18 @@ func (tc *TeamCache) AddMember(ctx context.Context, teamID uint64, userID uuid.U
 
 	key := tc.GetTeamMembersKey(teamID)
 
+	// A set created here would hold only this member and hide the rest of the
+	// roster, so leave an uncached team for the next read to load in full
+	exists, err := tc.client.Exists(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return nil
+	}
+
 	// Add member to set
-	err := tc.client.SAdd(ctx, key, userID.String()).Err()
+	err = tc.client.SAdd(ctx, key, userID.String()).Err()
 	if err != nil {
 		return err
 	}
@@ -117,6 +127,15 @@ func (tc *TeamCache) RemoveMember(ctx context.Context, teamID uint64, userID uui
 	return tc.client.SRem(ctx, key, userID.String()).Err()
 }
 
+// InvalidateMembers drops the cached members of a team
+func (tc *TeamCache) InvalidateMembers(ctx context.Context, teamID uint64) error {
+	if tc.client == nil {
+		return fmt.Errorf("Redis client not initialized")
+	}
+
+	return tc.client.Del(ctx, tc.GetTeamMembersKey(teamID)).Err()
+}
+
 // SMembers is a wrapper around the Redis SMembers command
 func (tc *TeamCache) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
 	return tc.client.SMembers(ctx, key)
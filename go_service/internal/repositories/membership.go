@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"time"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// openMembership starts an interval for each roster entry
+func openMembership(db *gorm.DB, rosters []models.Roster, at time.Time) error {
+	if len(rosters) == 0 {
+		return nil
+	}
+	intervals := make([]models.MembershipInterval, len(rosters))
+	for i, roster := range rosters {
+		intervals[i] = models.MembershipInterval{TeamID: roster.TeamID, UserID: roster.UserID, Role: roster.Role, StartedAt: at}
+	}
+	return db.Create(&intervals).Error
+}
+
+// closeMembership ends the current intervals on teamID, only userID's when it is set
+func closeMembership(db *gorm.DB, teamID int, userID uuid.UUID, at time.Time, reason string) error {
+	query := db.Model(&models.MembershipInterval{}).Where("team_id = ? AND ended_at IS NULL", teamID)
+	if userID != uuid.Nil {
+		query = query.Where("user_id = ?", userID)
+	}
+	return query.Updates(map[string]interface{}{"ended_at": at, "end_reason": reason}).Error
+}
+
+// RosterAt returns the intervals covering at on teamID: the team's roster as it was then
+func (r *TeamRepository) RosterAt(teamID int, at time.Time) ([]models.MembershipInterval, error) {
+	var intervals []models.MembershipInterval
+	err := r.db.Where("team_id = ? AND started_at <= ? AND (ended_at IS NULL OR ended_at > ?)", teamID, at, at).
+		Order("started_at, id").
+		Find(&intervals).Error
+	return intervals, err
+}
+
+// MembershipsAt returns the intervals covering at for userID: the teams they were on then
+func (r *TeamRepository) MembershipsAt(userID uuid.UUID, at time.Time) ([]models.MembershipInterval, error) {
+	var intervals []models.MembershipInterval
+	err := r.db.Where("user_id = ? AND started_at <= ? AND (ended_at IS NULL OR ended_at > ?)", userID, at, at).
+		Order("team_id").
+		Find(&intervals).Error
+	return intervals, err
+}
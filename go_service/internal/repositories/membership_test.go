@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"go_service/internal/models"
+	"go_service/internal/testutil"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// testRepo returns a repository on a rolled-back transaction; see testutil.DB
+func testRepo(t *testing.T) (*TeamRepository, *gorm.DB) {
+	t.Helper()
+	db := testutil.DB(t)
+	return NewTeamRepository(db), db
+}
+
+// testTeam creates a team with a roster entry for each role given
+func testTeam(t *testing.T, repo *TeamRepository, roles ...models.RosterRole) (*models.Team, []*models.Roster) {
+	t.Helper()
+	team := testutil.Team(t, repo.db, models.JoinPolicyInviteOnly)
+	rosters := make([]*models.Roster, len(roles))
+	for i, role := range roles {
+		rosters[i] = models.NewRoster(team.ID, uuid.New(), role)
+		if err := repo.AddMemberToTeam(rosters[i]); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+	}
+	return team, rosters
+}
+
+// intervals returns every interval on teamID, oldest first
+func intervals(t *testing.T, db *gorm.DB, teamID int) []models.MembershipInterval {
+	t.Helper()
+	var found []models.MembershipInterval
+	if err := db.Where("team_id = ?", teamID).Order("id").Find(&found).Error; err != nil {
+		t.Fatalf("list intervals: %v", err)
+	}
+	return found
+}
+
+func TestRosterAtBoundaries(t *testing.T) {
+	repo, db := testRepo(t)
+	team, _ := testTeam(t, repo)
+	user := uuid.New()
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	roster := models.NewRoster(team.ID, user, models.RosterRoleMember)
+	if err := openMembership(db, []models.Roster{*roster}, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := closeMembership(db, team.ID, user, end, models.MembershipLeft); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"before start", start.Add(-time.Second), false},
+		{"at start", start, true},
+		{"inside", start.Add(30 * time.Minute), true},
+		{"just before end", end.Add(-time.Microsecond), true},
+		{"at end", end, false},
+		{"after end", end.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roster, err := repo.RosterAt(team.ID, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(roster) == 1 && roster[0].UserID == user; got != tt.want {
+				t.Errorf("RosterAt(%v) = %+v, want member %v", tt.at, roster, tt.want)
+			}
+
+			memberships, err := repo.MembershipsAt(user, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(memberships) == 1 && memberships[0].TeamID == team.ID; got != tt.want {
+				t.Errorf("MembershipsAt(%v) = %+v, want member %v", tt.at, memberships, tt.want)
+			}
+		})
+	}
+}
+
+func TestRosterAtOpenInterval(t *testing.T) {
+	repo, db := testRepo(t)
+	team, _ := testTeam(t, repo)
+	user := uuid.New()
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	roster := models.NewRoster(team.ID, user, models.RosterRoleViewer)
+	if err := openMembership(db, []models.Roster{*roster}, start); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.RosterAt(team.ID, start.AddDate(10, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].UserID != user || found[0].Role != models.RosterRoleViewer {
+		t.Fatalf("an open interval should cover any later time, got %+v", found)
+	}
+}
+
+func TestSetRoleSplitsInterval(t *testing.T) {
+	repo, db := testRepo(t)
+	team, rosters := testTeam(t, repo, models.RosterRoleMember)
+	roster := rosters[0]
+
+	if err := repo.SetRole(roster, models.RosterRoleLeader); err != nil {
+		t.Fatal(err)
+	}
+
+	found := intervals(t, db, team.ID)
+	if len(found) != 2 {
+		t.Fatalf("got %d intervals, want the member one ended and a leader one started", len(found))
+	}
+	before, after := found[0], found[1]
+	if before.Role != models.RosterRoleMember || before.EndedAt == nil || before.EndReason != models.MembershipRoleChanged {
+		t.Errorf("member interval = %+v, want ended as %s", before, models.MembershipRoleChanged)
+	}
+	if after.Role != models.RosterRoleLeader || after.EndedAt != nil {
+		t.Errorf("leader interval = %+v, want open", after)
+	}
+	if before.EndedAt != nil && !before.EndedAt.Equal(after.StartedAt) {
+		t.Errorf("leader interval starts at %v, want %v where the member one ended", after.StartedAt, *before.EndedAt)
+	}
+}
+
+func TestSetRoleSameRoleKeepsInterval(t *testing.T) {
+	repo, db := testRepo(t)
+	team, rosters := testTeam(t, repo, models.RosterRoleMember)
+
+	if err := repo.SetRole(rosters[0], models.RosterRoleMember); err != nil {
+		t.Fatal(err)
+	}
+
+	if found := intervals(t, db, team.ID); len(found) != 1 || found[0].EndedAt != nil {
+		t.Fatalf("intervals = %+v, want the one open interval untouched", found)
+	}
+}
+
+func TestRemoveRosterEndsInterval(t *testing.T) {
+	repo, db := testRepo(t)
+	team, rosters := testTeam(t, repo, models.RosterRoleOwner, models.RosterRoleMember)
+
+	if err := repo.RemoveRoster(rosters[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, interval := range intervals(t, db, team.ID) {
+		left := interval.UserID == rosters[1].UserID
+		if left && (interval.EndedAt == nil || interval.EndReason != models.MembershipLeft) {
+			t.Errorf("removed member's interval = %+v, want ended as %s", interval, models.MembershipLeft)
+		}
+		if !left && interval.EndedAt != nil {
+			t.Errorf("owner's interval = %+v, want open", interval)
+		}
+	}
+}
+
+func TestArchiveAndRestoreTeamHistory(t *testing.T) {
+	repo, db := testRepo(t)
+	team, _ := testTeam(t, repo, models.RosterRoleOwner, models.RosterRoleMember)
+
+	if _, err := repo.ArchiveTeam(team, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	archived := intervals(t, db, team.ID)
+	if len(archived) != 2 {
+		t.Fatalf("got %d intervals after archiving, want 2", len(archived))
+	}
+	for _, interval := range archived {
+		if interval.EndedAt == nil || interval.EndReason != models.MembershipTeamArchived {
+			t.Errorf("interval = %+v, want ended as %s", interval, models.MembershipTeamArchived)
+		}
+	}
+
+	if _, err := repo.RestoreTeam(team); err != nil {
+		t.Fatal(err)
+	}
+	restored := intervals(t, db, team.ID)
+	if len(restored) != 4 {
+		t.Fatalf("got %d intervals after restoring, want the 2 archived ones and 2 new ones", len(restored))
+	}
+	roles := map[uuid.UUID]models.RosterRole{}
+	for _, interval := range archived {
+		roles[interval.UserID] = interval.Role
+	}
+	for _, interval := range restored[2:] {
+		if role, ok := roles[interval.UserID]; !ok || interval.EndedAt != nil || interval.Role != role {
+			t.Errorf("restored interval = %+v, want an open one in the role held before archiving", interval)
+		}
+		delete(roles, interval.UserID)
+	}
+}
+
+func TestDeleteTeamEndsIntervals(t *testing.T) {
+	repo, db := testRepo(t)
+	team, _ := testTeam(t, repo, models.RosterRoleOwner, models.RosterRoleLeader)
+
+	if _, err := repo.DeleteTeam(team); err != nil {
+		t.Fatal(err)
+	}
+
+	found := intervals(t, db, team.ID)
+	if len(found) != 2 {
+		t.Fatalf("got %d intervals, want the history kept after the team is gone", len(found))
+	}
+	for _, interval := range found {
+		if interval.EndedAt == nil || interval.EndReason != models.MembershipTeamDeleted {
+			t.Errorf("interval = %+v, want ended as %s", interval, models.MembershipTeamDeleted)
+		}
+	}
+}
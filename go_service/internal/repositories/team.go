@@ -1,9 +1,13 @@
 package repositories
 
 import (
+	"time"
+
 	"go_service/internal/models"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TeamRepository struct {
@@ -14,12 +18,256 @@ func NewTeamRepository(db *gorm.DB) *TeamRepository {
 	return &TeamRepository{db: db}
 }
 
+// WithTx returns a repository that runs its queries on tx
+func (r *TeamRepository) WithTx(tx *gorm.DB) *TeamRepository {
+	return &TeamRepository{db: tx}
+}
+
 // inserts a new team into the database
 func (r *TeamRepository) CreateTeam(team *models.Team) error {
 	return r.db.Create(team).Error
 }
 
-// inserts a roster entry for a team member
+// inserts a roster entry for a team member and starts their membership interval
 func (r *TeamRepository) AddMemberToTeam(roster *models.Roster) error {
-	return r.db.Create(roster).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(roster).Error; err != nil {
+			return err
+		}
+		return openMembership(tx, []models.Roster{*roster}, time.Now())
+	})
+}
+
+// IsMember reports whether userID is on the roster of teamID
+func (r *TeamRepository) IsMember(teamID int, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Roster{}).
+		Where("\"teamId\" = ? AND \"userId\" = ?", teamID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindRoster returns userID's roster entry on teamID, or gorm.ErrRecordNotFound
+func (r *TeamRepository) FindRoster(teamID int, userID uuid.UUID) (*models.Roster, error) {
+	var roster models.Roster
+	if err := r.db.Where("\"teamId\" = ? AND \"userId\" = ?", teamID, userID).First(&roster).Error; err != nil {
+		return nil, err
+	}
+	return &roster, nil
+}
+
+// ListRosters returns teamID's roster, longest-standing entries first
+func (r *TeamRepository) ListRosters(teamID int) ([]models.Roster, error) {
+	var rosters []models.Roster
+	err := r.db.Where("\"teamId\" = ?", teamID).Order("\"rosterId\"").Find(&rosters).Error
+	return rosters, err
+}
+
+// FindOwner returns teamID's owner roster entry, or gorm.ErrRecordNotFound
+func (r *TeamRepository) FindOwner(teamID int) (*models.Roster, error) {
+	var roster models.Roster
+	if err := r.db.Where("\"teamId\" = ? AND role = ?", teamID, models.RosterRoleOwner).First(&roster).Error; err != nil {
+		return nil, err
+	}
+	return &roster, nil
+}
+
+// RemoveRoster deletes a roster entry and ends its membership interval
+func (r *TeamRepository) RemoveRoster(roster *models.Roster) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(roster).Error; err != nil {
+			return err
+		}
+		return closeMembership(tx, roster.TeamID, roster.UserID, time.Now(), models.MembershipLeft)
+	})
+}
+
+// CountLeaders returns how many users lead teamID, the owner included
+func (r *TeamRepository) CountLeaders(teamID int) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Roster{}).Where("\"teamId\" = ? AND \"isLeader\"", teamID).Count(&count).Error
+	return count, err
+}
+
+// SetRole changes a roster entry's role and isLeader flag together, ending
+// the membership interval under the old role and starting one under the new
+func (r *TeamRepository) SetRole(roster *models.Roster, role models.RosterRole) error {
+	changed := roster.Role != role
+	roster.SetRole(role)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(roster).Updates(map[string]interface{}{
+			"role":     roster.Role,
+			"isLeader": roster.IsLeader,
+		}).Error
+		if err != nil || !changed {
+			return err
+		}
+
+		now := time.Now()
+		if err := closeMembership(tx, roster.TeamID, roster.UserID, now, models.MembershipRoleChanged); err != nil {
+			return err
+		}
+		return openMembership(tx, []models.Roster{*roster}, now)
+	})
+}
+
+// FindTeam returns a team by ID, or gorm.ErrRecordNotFound
+func (r *TeamRepository) FindTeam(teamID int) (*models.Team, error) {
+	var team models.Team
+	if err := r.db.First(&team, teamID).Error; err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// LockTeam returns a team like FindTeam and holds a row lock on it until the
+// transaction ends, so roster changes to the team run one at a time
+func (r *TeamRepository) LockTeam(teamID int) (*models.Team, error) {
+	var team models.Team
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&team, teamID).Error; err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// TeamNameTaken reports whether a team other than excludeID already uses name
+func (r *TeamRepository) TeamNameTaken(name string, excludeID int) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Team{}).
+		Where("\"teamName\" = ? AND \"teamId\" <> ?", name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindTeamByName returns the team with the given name, or gorm.ErrRecordNotFound
+func (r *TeamRepository) FindTeamByName(name string) (*models.Team, error) {
+	var team models.Team
+	if err := r.db.Where("\"teamName\" = ?", name).First(&team).Error; err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// UpdateTeam writes the given column values to a team
+func (r *TeamRepository) UpdateTeam(team *models.Team, updates map[string]interface{}) error {
+	return r.db.Model(team).Updates(updates).Error
+}
+
+// ArchiveTeam marks a team archived and moves its roster into ArchivedRosters.
+// It returns the users that were on the roster.
+func (r *TeamRepository) ArchiveTeam(team *models.Team, at time.Time) ([]uuid.UUID, error) {
+	var members []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var rosters []models.Roster
+		if err := tx.Where("\"teamId\" = ?", team.ID).Find(&rosters).Error; err != nil {
+			return err
+		}
+
+		if len(rosters) > 0 {
+			archived := make([]models.ArchivedRoster, len(rosters))
+			for i, roster := range rosters {
+				archived[i] = models.ArchivedRoster{TeamID: team.ID, UserID: roster.UserID, IsLeader: roster.IsLeader, Role: roster.Role, ArchivedAt: at}
+				members = append(members, roster.UserID)
+			}
+			if err := tx.Create(&archived).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("\"teamId\" = ?", team.ID).Delete(&models.Roster{}).Error; err != nil {
+				return err
+			}
+			if err := closeMembership(tx, team.ID, uuid.Nil, at, models.MembershipTeamArchived); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(team).Update("archivedAt", at).Error
+	})
+	return members, err
+}
+
+// RestoreTeam clears the archived mark and puts the archived roster back
+func (r *TeamRepository) RestoreTeam(team *models.Team) ([]uuid.UUID, error) {
+	var members []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var archived []models.ArchivedRoster
+		if err := tx.Where("team_id = ?", team.ID).Find(&archived).Error; err != nil {
+			return err
+		}
+
+		if len(archived) > 0 {
+			rosters := make([]models.Roster, len(archived))
+			for i, entry := range archived {
+				rosters[i] = *models.NewRoster(team.ID, entry.UserID, entry.Role)
+				members = append(members, entry.UserID)
+			}
+			if err := tx.Create(&rosters).Error; err != nil {
+				return err
+			}
+			if err := openMembership(tx, rosters, time.Now()); err != nil {
+				return err
+			}
+			if err := tx.Where("team_id = ?", team.ID).Delete(&models.ArchivedRoster{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(team).Update("archivedAt", nil).Error
+	})
+	return members, err
+}
+
+// DeleteTeam removes a team together with its current and archived rosters,
+// its invitations and join requests, and the shares made to it.
+// It returns the users that were on the roster.
+func (r *TeamRepository) DeleteTeam(team *models.Team) ([]uuid.UUID, error) {
+	var members []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Roster{}).Where("\"teamId\" = ?", team.ID).Pluck("userId", &members).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("\"teamId\" = ?", team.ID).Delete(&models.Roster{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.ArchivedRoster{}).Error; err != nil {
+			return err
+		}
+		// Invitations reference the team, and requests to join it are moot
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamJoinRequest{}).Error; err != nil {
+			return err
+		}
+		// The team's folders go back to the people who created them
+		if err := tx.Model(&models.Folder{}).Where("team_id = ?", team.ID).Update("team_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamQuota{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamLabel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamFieldValue{}).Error; err != nil {
+			return err
+		}
+		// Shares made to the team would otherwise outlive it
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.FolderShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.NoteShare{}).Error; err != nil {
+			return err
+		}
+		// Membership history is kept for audits after the team is gone
+		if err := closeMembership(tx, team.ID, uuid.Nil, time.Now(), models.MembershipTeamDeleted); err != nil {
+			return err
+		}
+		// Sub-teams move up to the deleted team's parent so they stay in the same branch
+		err := tx.Model(&models.Team{}).Where("\"parentTeamId\" = ?", team.ID).Update("parentTeamId", team.ParentTeamID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(team).Error
+	})
+	return members, err
 }
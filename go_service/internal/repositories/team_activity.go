@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// ActivityQuery selects a page of a team's activity feed, newest first
+type ActivityQuery struct {
+	TeamID int
+	// EventTypes restricts the feed to these event types
+	EventTypes []string
+	// ActorID restricts the feed to events performed by this user
+	ActorID *uuid.UUID
+	// From and To bound OccurredAt; From is inclusive, To exclusive
+	From  *time.Time
+	To    *time.Time
+	Limit int
+	// Cursor is the NextCursor of the previous page
+	Cursor string
+}
+
+// ActivityPage is one page of ListActivity
+type ActivityPage struct {
+	Activities []models.TeamActivity `json:"activities"`
+	NextCursor string                `json:"nextCursor,omitempty"`
+}
+
+// activityCursor is the keyset position after the last entry of a page
+type activityCursor struct {
+	OccurredAt string `json:"t"`
+	ID         uint   `json:"i"`
+}
+
+func (c activityCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeActivityCursor(value string) (time.Time, uint, error) {
+	var c activityCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	occurredAt, err := time.Parse(time.RFC3339Nano, c.OccurredAt)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	return occurredAt, c.ID, nil
+}
+
+// RecordActivity stores a team event in the activity feed. An event whose
+// ID is already stored is a redelivery and is ignored.
+func (r *TeamRepository) RecordActivity(activity *models.TeamActivity) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(activity).Error
+}
+
+// ListActivity returns a page of a team's activity, newest first, using
+// keyset pagination on (occurredAt, id)
+func (r *TeamRepository) ListActivity(q ActivityQuery) (*ActivityPage, error) {
+	if q.Limit <= 0 || q.Limit > 100 {
+		q.Limit = 50
+	}
+
+	query := r.db.Where("team_id = ?", q.TeamID)
+	if len(q.EventTypes) > 0 {
+		query = query.Where("event_type IN ?", q.EventTypes)
+	}
+	if q.ActorID != nil {
+		query = query.Where("performed_by = ?", *q.ActorID)
+	}
+	if q.From != nil {
+		query = query.Where("occurred_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("occurred_at < ?", *q.To)
+	}
+	if q.Cursor != "" {
+		occurredAt, id, err := decodeActivityCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("(occurred_at, id) < (?, ?)", occurredAt, id)
+	}
+
+	var activities []models.TeamActivity
+	if err := query.Order("occurred_at DESC, id DESC").Limit(q.Limit + 1).Find(&activities).Error; err != nil {
+		return nil, err
+	}
+
+	page := &ActivityPage{Activities: activities}
+	if len(activities) > q.Limit {
+		page.Activities = activities[:q.Limit]
+		last := page.Activities[q.Limit-1]
+		page.NextCursor = activityCursor{OccurredAt: last.OccurredAt.Format(time.RFC3339Nano), ID: last.ID}.encode()
+	}
+
+	return page, nil
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"sort"
+
+	"go_service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TeamMetadata is a team's labels and custom field values, keyed by field key
+type TeamMetadata struct {
+	Labels []string          `json:"labels"`
+	Fields map[string]string `json:"fields"`
+}
+
+// ListFieldDefinitions returns every custom team field, ordered by key
+func (r *TeamRepository) ListFieldDefinitions() ([]models.TeamFieldDefinition, error) {
+	var definitions []models.TeamFieldDefinition
+	err := r.db.Order("key").Find(&definitions).Error
+	return definitions, err
+}
+
+// CreateFieldDefinition inserts a custom team field
+func (r *TeamRepository) CreateFieldDefinition(definition *models.TeamFieldDefinition) error {
+	return r.db.Create(definition).Error
+}
+
+// DeleteFieldDefinition removes a custom team field and every team's value for it
+func (r *TeamRepository) DeleteFieldDefinition(definition *models.TeamFieldDefinition) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("field_id = ?", definition.ID).Delete(&models.TeamFieldValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(definition).Error
+	})
+}
+
+// SetLabels replaces a team's labels
+func (r *TeamRepository) SetLabels(teamID int, labels []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", teamID).Delete(&models.TeamLabel{}).Error; err != nil {
+			return err
+		}
+		if len(labels) == 0 {
+			return nil
+		}
+		rows := make([]models.TeamLabel, len(labels))
+		for i, label := range labels {
+			rows[i] = models.TeamLabel{TeamID: teamID, Label: label}
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// SetFieldValue sets a team's value for a custom field
+func (r *TeamRepository) SetFieldValue(teamID int, fieldID uint, value string) error {
+	return r.db.Save(&models.TeamFieldValue{TeamID: teamID, FieldID: fieldID, Value: value}).Error
+}
+
+// ClearFieldValue removes a team's value for a custom field
+func (r *TeamRepository) ClearFieldValue(teamID int, fieldID uint) error {
+	return r.db.Where("team_id = ? AND field_id = ?", teamID, fieldID).Delete(&models.TeamFieldValue{}).Error
+}
+
+// Metadata returns the labels and field values of each of teamIDs. Teams
+// without any still get an entry.
+func (r *TeamRepository) Metadata(teamIDs []int) (map[int]*TeamMetadata, error) {
+	metadata := make(map[int]*TeamMetadata, len(teamIDs))
+	for _, teamID := range teamIDs {
+		metadata[teamID] = &TeamMetadata{Labels: []string{}, Fields: map[string]string{}}
+	}
+	if len(teamIDs) == 0 {
+		return metadata, nil
+	}
+
+	var labels []models.TeamLabel
+	if err := r.db.Where("team_id IN ?", teamIDs).Order("label").Find(&labels).Error; err != nil {
+		return nil, err
+	}
+	for _, label := range labels {
+		metadata[label.TeamID].Labels = append(metadata[label.TeamID].Labels, label.Label)
+	}
+
+	var values []struct {
+		TeamID int
+		Key    string
+		Value  string
+	}
+	err := r.db.Table("team_field_values v").
+		Select("v.team_id, d.key, v.value").
+		Joins("JOIN team_field_definitions d ON d.id = v.field_id").
+		Where("v.team_id IN ?", teamIDs).
+		Scan(&values).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		metadata[value.TeamID].Fields[value.Key] = value.Value
+	}
+
+	return metadata, nil
+}
+
+// attachMetadata fills in the labels and field values of listed teams
+func (r *TeamRepository) attachMetadata(teams []TeamSummary) error {
+	teamIDs := make([]int, len(teams))
+	for i, team := range teams {
+		teamIDs[i] = team.ID
+	}
+	metadata, err := r.Metadata(teamIDs)
+	if err != nil {
+		return err
+	}
+	for i := range teams {
+		teams[i].Labels = metadata[teams[i].ID].Labels
+		teams[i].Fields = metadata[teams[i].ID].Fields
+	}
+	return nil
+}
+
+// filterMetadata narrows a team query to teams carrying every label and
+// field value given; fields maps field keys to canonical values
+func filterMetadata(query *gorm.DB, labels []string, fields map[string]string) *gorm.DB {
+	for _, label := range labels {
+		query = query.Where(`EXISTS (SELECT 1 FROM team_labels l WHERE l.team_id = "Teams"."teamId" AND l.label = ?)`, label)
+	}
+	// Sorted so the generated SQL is stable
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query = query.Where(`EXISTS (SELECT 1 FROM team_field_values v JOIN team_field_definitions d ON d.id = v.field_id
+			WHERE v.team_id = "Teams"."teamId" AND d.key = ? AND v.value = ?)`, key, fields[key])
+	}
+	return query
+}
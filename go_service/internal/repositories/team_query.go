@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed or were issued for another sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Team list sort keys
+const (
+	TeamSortName      = "name"
+	TeamSortCreatedAt = "createdAt"
+)
+
+// TeamSummary is a team with its roster counts and the caller's place in it
+type TeamSummary struct {
+	models.Team
+	MemberCount int64 `gorm:"column:member_count" json:"memberCount"`
+	LeaderCount int64 `gorm:"column:leader_count" json:"leaderCount"`
+	IsMember    bool  `gorm:"column:is_member" json:"isMember"`
+	IsLeader    bool  `gorm:"column:is_leader" json:"isLeader"`
+	// Role is the caller's roster role, empty when they are not on the team
+	Role models.RosterRole `gorm:"column:role" json:"role,omitempty"`
+	// Labels and Fields are the team's metadata, keyed by field key
+	Labels []string          `gorm:"-" json:"labels"`
+	Fields map[string]string `gorm:"-" json:"fields"`
+}
+
+// TeamListQuery selects a page of teams
+type TeamListQuery struct {
+	// ViewerID is the caller; IsMember and IsLeader are computed for them
+	ViewerID uuid.UUID
+	// OnlyMine restricts the list to teams whose roster includes ViewerID
+	OnlyMine bool
+	// Archived lists archived teams instead of active ones
+	Archived bool
+	// Joinable restricts the list to open and request-to-join teams ViewerID is not on
+	Joinable bool
+	// Search matches team names case-insensitively
+	Search string
+	// Labels restricts the list to teams with every one of these labels
+	Labels []string
+	// Fields restricts the list to teams with these custom field values,
+	// keyed by field key and given in canonical form
+	Fields map[string]string
+	Sort   string
+	Desc   bool
+	Limit  int
+	// Cursor is the NextCursor of the previous page
+	Cursor string
+}
+
+// TeamPage is one page of ListTeams
+type TeamPage struct {
+	Teams      []TeamSummary `json:"teams"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// teamCursor is the keyset position after the last team of a page
+type teamCursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d"`
+	Name   string `json:"n,omitempty"`
+	Create string `json:"c,omitempty"`
+	ID     int    `json:"i"`
+}
+
+func (c teamCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTeamCursor(value string) (teamCursor, error) {
+	var c teamCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// teamSummarySelect aggregates roster counts and the viewer's membership per team
+const teamSummarySelect = `"Teams".*,
+	COUNT(r."rosterId") AS member_count,
+	COUNT(r."rosterId") FILTER (WHERE r."isLeader") AS leader_count,
+	COALESCE(BOOL_OR(r."userId" = ?), false) AS is_member,
+	COALESCE(BOOL_OR(r."userId" = ? AND r."isLeader"), false) AS is_leader,
+	COALESCE(MAX(r.role) FILTER (WHERE r."userId" = ?), '') AS role`
+
+func (r *TeamRepository) summaries(viewerID uuid.UUID) *gorm.DB {
+	return r.db.Table(`"Teams"`).
+		Select(teamSummarySelect, viewerID, viewerID, viewerID).
+		Joins(`LEFT JOIN "Rosters" r ON r."teamId" = "Teams"."teamId"`).
+		Group(`"Teams"."teamId"`)
+}
+
+// ListTeams returns a page of teams ordered by q.Sort, using keyset pagination
+// on (sort key, teamId)
+func (r *TeamRepository) ListTeams(q TeamListQuery) (*TeamPage, error) {
+	sortColumn := `"Teams"."teamName"`
+	switch q.Sort {
+	case "", TeamSortName:
+		q.Sort = TeamSortName
+	case TeamSortCreatedAt:
+		sortColumn = `"Teams"."createdAt"`
+	default:
+		return nil, fmt.Errorf("unknown sort %q", q.Sort)
+	}
+	if q.Limit <= 0 || q.Limit > 100 {
+		q.Limit = 20
+	}
+
+	query := r.summaries(q.ViewerID)
+	if q.Archived {
+		query = query.Where(`"Teams"."archivedAt" IS NOT NULL`)
+	} else {
+		query = query.Where(`"Teams"."archivedAt" IS NULL`)
+	}
+	if q.OnlyMine {
+		query = query.Where(`EXISTS (SELECT 1 FROM "Rosters" m WHERE m."teamId" = "Teams"."teamId" AND m."userId" = ?)`, q.ViewerID)
+	}
+	if q.Joinable {
+		query = query.Where(`"Teams"."joinPolicy" IN ? AND NOT EXISTS (SELECT 1 FROM "Rosters" m WHERE m."teamId" = "Teams"."teamId" AND m."userId" = ?)`,
+			[]models.JoinPolicy{models.JoinPolicyOpen, models.JoinPolicyRequest}, q.ViewerID)
+	}
+	if search := strings.TrimSpace(q.Search); search != "" {
+		query = query.Where(`"Teams"."teamName" ILIKE ?`, "%"+escapeLike(search)+"%")
+	}
+	query = filterMetadata(query, q.Labels, q.Fields)
+
+	direction, comparison := "ASC", ">"
+	if q.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if q.Cursor != "" {
+		cursor, err := decodeTeamCursor(q.Cursor)
+		if err != nil || cursor.Sort != q.Sort || cursor.Desc != q.Desc {
+			return nil, ErrInvalidCursor
+		}
+		var key interface{} = cursor.Name
+		if q.Sort == TeamSortCreatedAt {
+			createdAt, err := time.Parse(time.RFC3339Nano, cursor.Create)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			key = createdAt
+		}
+		query = query.Where(fmt.Sprintf(`(%s, "Teams"."teamId") %s (?, ?)`, sortColumn, comparison), key, cursor.ID)
+	}
+
+	var teams []TeamSummary
+	err := query.
+		Order(fmt.Sprintf(`%s %s, "Teams"."teamId" %s`, sortColumn, direction, direction)).
+		Limit(q.Limit + 1).
+		Scan(&teams).Error
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TeamPage{Teams: teams}
+	if err := r.attachMetadata(teams); err != nil {
+		return nil, err
+	}
+	if len(teams) > q.Limit {
+		page.Teams = teams[:q.Limit]
+		last := page.Teams[q.Limit-1]
+		page.NextCursor = teamCursor{
+			Sort:   q.Sort,
+			Desc:   q.Desc,
+			Name:   last.TeamName,
+			Create: last.CreatedAt.Format(time.RFC3339Nano),
+			ID:     last.ID,
+		}.encode()
+	}
+
+	return page, nil
+}
+
+// GetTeamSummary returns one team with its counts, or gorm.ErrRecordNotFound
+func (r *TeamRepository) GetTeamSummary(teamID int, viewerID uuid.UUID) (*TeamSummary, error) {
+	var teams []TeamSummary
+	if err := r.summaries(viewerID).Where(`"Teams"."teamId" = ?`, teamID).Scan(&teams).Error; err != nil {
+		return nil, err
+	}
+	if len(teams) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := r.attachMetadata(teams); err != nil {
+		return nil, err
+	}
+	return &teams[0], nil
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestDeleteTeamWithInvitationsAndRequests(t *testing.T) {
+	repo, db := testRepo(t)
+	team, rosters := testTeam(t, repo, models.RosterRoleOwner)
+
+	invitation := &models.TeamInvitation{
+		TeamID:      team.ID,
+		Email:       "invitee@example.com",
+		InvitedByID: rosters[0].UserID,
+		TokenHash:   uuid.NewString(),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	if err := db.Create(invitation).Error; err != nil {
+		t.Fatal(err)
+	}
+	request := &models.TeamJoinRequest{ID: uuid.New(), TeamID: team.ID, UserID: uuid.New(), Status: models.JoinRequestPending}
+	if err := db.Create(request).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.DeleteTeam(team); err != nil {
+		t.Fatalf("deleting a team with an invitation: %v", err)
+	}
+
+	var left int64
+	if err := db.Model(&models.TeamInvitation{}).Where("team_id = ?", team.ID).Count(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d invitations outlived the team", left)
+	}
+	if err := db.Model(&models.TeamJoinRequest{}).Where("team_id = ?", team.ID).Count(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d join requests outlived the team", left)
+	}
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"time"
+
+	"go_service/internal/models"
+)
+
+// TeamNode is a team in a subtree listing
+type TeamNode struct {
+	ID           int        `gorm:"column:teamId" json:"id"`
+	TeamName     string     `gorm:"column:teamName" json:"teamName"`
+	ParentTeamID *int       `gorm:"column:parentTeamId" json:"parentTeamId,omitempty"`
+	ArchivedAt   *time.Time `gorm:"column:archivedAt" json:"archivedAt,omitempty"`
+	// Depth is 0 for the root of the subtree
+	Depth       int   `gorm:"column:depth" json:"depth"`
+	MemberCount int64 `gorm:"column:member_count" json:"memberCount"`
+}
+
+// subtreeCTE walks down from a root team. Attaching teams refuses cycles; the
+// depth bound only keeps a corrupted hierarchy from looping forever.
+const subtreeCTE = `WITH RECURSIVE subtree AS (
+		SELECT "teamId", 0 AS depth FROM "Teams" WHERE "teamId" = @root
+		UNION ALL
+		SELECT t."teamId", s.depth + 1 FROM "Teams" t
+		JOIN subtree s ON t."parentTeamId" = s."teamId"
+		WHERE (@archived OR t."archivedAt" IS NULL) AND s.depth < 64
+	)`
+
+// Subtree returns rootID and every team below it, shallowest first. Archived
+// sub-teams and the teams under them are left out unless includeArchived is set.
+func (r *TeamRepository) Subtree(rootID int, includeArchived bool) ([]TeamNode, error) {
+	var nodes []TeamNode
+	err := r.db.Raw(subtreeCTE+`
+		SELECT t."teamId", t."teamName", t."parentTeamId", t."archivedAt", MIN(s.depth) AS depth,
+			(SELECT COUNT(*) FROM "Rosters" r WHERE r."teamId" = t."teamId") AS member_count
+		FROM subtree s JOIN "Teams" t ON t."teamId" = s."teamId"
+		GROUP BY t."teamId"
+		ORDER BY depth, t."teamName"`,
+		map[string]interface{}{"root": rootID, "archived": includeArchived}).
+		Scan(&nodes).Error
+	return nodes, err
+}
+
+// SubtreeIDs returns the IDs of rootID and every team below it, archived ones
+// included. Unlike Subtree it has no depth bound, so the cycle check in
+// AttachTeam sees the whole subtree; UNION drops teams already visited, which
+// ends the walk even on a corrupted hierarchy.
+func (r *TeamRepository) SubtreeIDs(rootID int) ([]int, error) {
+	var ids []int
+	err := r.db.Raw(`WITH RECURSIVE subtree AS (
+			SELECT "teamId" FROM "Teams" WHERE "teamId" = ?
+			UNION
+			SELECT t."teamId" FROM "Teams" t
+			JOIN subtree s ON t."parentTeamId" = s."teamId"
+		)
+		SELECT "teamId" FROM subtree`, rootID).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// SetParent moves a team under parentID, or to the top level when parentID is nil
+func (r *TeamRepository) SetParent(team *models.Team, parentID *int) error {
+	if err := r.db.Model(team).Update("parentTeamId", parentID).Error; err != nil {
+		return err
+	}
+	team.ParentTeamID = parentID
+	return nil
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import "testing"
+
+func TestSubtreeIDsBeyondDisplayDepth(t *testing.T) {
+	repo, _ := testRepo(t)
+	root, _ := testTeam(t, repo)
+
+	// A chain deeper than the listing's depth bound
+	want := map[int]bool{root.ID: true}
+	parent := root
+	for i := 0; i < 70; i++ {
+		child, _ := testTeam(t, repo)
+		if err := repo.SetParent(child, &parent.ID); err != nil {
+			t.Fatal(err)
+		}
+		want[child.ID] = true
+		parent = child
+	}
+
+	ids, err := repo.SubtreeIDs(root.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("got %d teams in the subtree, want %d", len(ids), len(want))
+	}
+	for _, id := range ids {
+		if !want[id] {
+			t.Errorf("team %d is not in the subtree", id)
+		}
+	}
+}
+
+func TestDeleteTeamMovesSubTeamsUp(t *testing.T) {
+	repo, _ := testRepo(t)
+	top, _ := testTeam(t, repo)
+	middle, _ := testTeam(t, repo)
+	bottom, _ := testTeam(t, repo)
+	if err := repo.SetParent(middle, &top.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetParent(bottom, &middle.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.DeleteTeam(middle); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := repo.FindTeam(bottom.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.ParentTeamID == nil || *moved.ParentTeamID != top.ID {
+		t.Errorf("sub-team's parent = %v, want %d", moved.ParentTeamID, top.ID)
+	}
+}
+
+func TestParentMustExist(t *testing.T) {
+	repo, _ := testRepo(t)
+	team, _ := testTeam(t, repo)
+
+	missing := -1
+	if err := repo.SetParent(team, &missing); err == nil {
+		t.Error("a team should not be placed under a team that does not exist")
+	}
+}
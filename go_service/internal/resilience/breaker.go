@@ -0,0 +1,134 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned while the breaker is rejecting calls
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// Breaker is a consecutive-failure circuit breaker. After FailureThreshold
+// failures in a row it opens and rejects calls for Cooldown, then lets a
+// single probe through; the probe's outcome closes or re-opens it.
+type Breaker struct {
+	mu               sync.Mutex
+	state            State
+	failures         int
+	openedAt         time.Time
+	probing          bool
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time
+	onStateChange    func(from, to State)
+}
+
+// NewBreaker creates a closed breaker. onStateChange may be nil.
+func NewBreaker(failureThreshold int, cooldown time.Duration, onStateChange func(from, to State)) *Breaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &Breaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		now:              time.Now,
+		onStateChange:    onStateChange,
+	}
+}
+
+// State returns the current state, moving from open to half-open once the cooldown has passed
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return b.state
+}
+
+// Allow reports whether a call may proceed. Every allowed call must be
+// followed by exactly one call to Done.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+
+	switch b.state {
+	case StateOpen:
+		return ErrCircuitOpen
+	case StateHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Done records the outcome of an allowed call
+func (b *Breaker) Done(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+		if success {
+			b.failures = 0
+			b.transition(StateClosed)
+		} else {
+			b.trip()
+		}
+		return
+	}
+
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == StateClosed && b.failures >= b.failureThreshold {
+		b.trip()
+	}
+}
+
+func (b *Breaker) trip() {
+	b.openedAt = b.now()
+	b.transition(StateOpen)
+}
+
+func (b *Breaker) refresh() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.transition(StateHalfOpen)
+	}
+}
+
+func (b *Breaker) transition(to State) {
+	if b.state == to {
+		return
+	}
+	from := b.state
+	b.state = to
+	if b.onStateChange != nil {
+		b.onStateChange(from, to)
+	}
+}
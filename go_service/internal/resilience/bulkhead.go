@@ -0,0 +1,52 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBulkheadFull is returned when no slot frees up within the wait time
+var ErrBulkheadFull = errors.New("too many concurrent calls")
+
+// Bulkhead caps the number of calls in flight at once
+type Bulkhead struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewBulkhead allows up to limit concurrent calls; callers wait at most maxWait for a slot
+func NewBulkhead(limit int, maxWait time.Duration) *Bulkhead {
+	if limit < 1 {
+		limit = 1
+	}
+	return &Bulkhead{slots: make(chan struct{}, limit), maxWait: maxWait}
+}
+
+// Acquire takes a slot. The returned release func must be called once the call finishes.
+func (b *Bulkhead) Acquire(ctx context.Context) (func(), error) {
+	release := func() { <-b.slots }
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrBulkheadFull
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight returns the number of calls currently holding a slot
+func (b *Bulkhead) InFlight() int {
+	return len(b.slots)
+}
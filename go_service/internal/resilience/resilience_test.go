@@ -0,0 +1,100 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerLifecycle(t *testing.T) {
+	now := time.Unix(0, 0)
+	var transitions []State
+	b := NewBreaker(2, time.Minute, func(_, to State) { transitions = append(transitions, to) })
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("closed breaker rejected call %d: %v", i, err)
+		}
+		b.Done(false)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open breaker, got %v", err)
+	}
+
+	// After the cooldown a single probe is let through
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("half-open breaker rejected probe: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected second probe to be rejected, got %v", err)
+	}
+
+	// A failed probe re-opens, a successful one closes
+	b.Done(false)
+	if b.State() != StateOpen {
+		t.Fatalf("expected open after failed probe, got %s", b.State())
+	}
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("half-open breaker rejected probe: %v", err)
+	}
+	b.Done(true)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed after successful probe, got %s", b.State())
+	}
+
+	want := []State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions = %v, want %v", transitions, want)
+		}
+	}
+}
+
+func TestRetryStopsOnPermanentError(t *testing.T) {
+	permanent := errors.New("permanent")
+	transient := errors.New("transient")
+	retryable := func(err error) bool { return err == transient }
+
+	calls := 0
+	err := Retry(context.Background(), 3, Backoff{}, retryable, nil, func() error {
+		calls++
+		if calls < 2 {
+			return transient
+		}
+		return permanent
+	})
+	if err != permanent || calls != 2 {
+		t.Fatalf("got err=%v calls=%d, want permanent after 2 calls", err, calls)
+	}
+
+	calls = 0
+	err = Retry(context.Background(), 2, Backoff{}, retryable, nil, func() error {
+		calls++
+		return transient
+	})
+	if err != transient || calls != 3 {
+		t.Fatalf("got err=%v calls=%d, want transient after 3 calls", err, calls)
+	}
+}
+
+func TestBulkheadRejectsWhenFull(t *testing.T) {
+	b := NewBulkhead(1, 10*time.Millisecond)
+	release, err := b.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	if _, err := b.Acquire(context.Background()); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("expected ErrBulkheadFull, got %v", err)
+	}
+	release()
+	if _, err := b.Acquire(context.Background()); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
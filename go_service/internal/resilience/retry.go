@@ -0,0 +1,52 @@
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes full-jitter exponential delays between attempts
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns a random delay in [0, min(Max, Base*2^attempt)) for the given
+// zero-based retry attempt
+func (b Backoff) Delay(attempt int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	ceiling := b.Base << uint(attempt)
+	if ceiling <= 0 || (b.Max > 0 && ceiling > b.Max) {
+		ceiling = b.Max
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// Retry runs fn up to retries+1 times, sleeping between attempts, while
+// retryable reports true for the error. onRetry is called before each retry
+// and may be nil.
+func Retry(ctx context.Context, retries int, backoff Backoff, retryable func(error) bool, onRetry func(attempt int, err error), fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = fn(); err == nil || attempt >= retries || !retryable(err) {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(attempt+1, err)
+		}
+
+		timer := time.NewTimer(backoff.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
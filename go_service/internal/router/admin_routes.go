@@ -0,0 +1,27 @@
+package router
+
+import (
+	"go_service/internal/auth"
+	"go_service/internal/handlers"
+	"go_service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminRoutes defines routes for operational and administrative tasks
+func AdminRoutes(rg *gin.RouterGroup, adminHandler *handlers.AdminHandler) {
+	admin := rg.Group("/admin", middleware.RequireScope(auth.ScopeAdmin))
+	{
+		admin.DELETE("/users/:userId/identity-cache", adminHandler.InvalidateUserIdentity)
+
+		// Token revocation
+		admin.POST("/tokens/revoke", adminHandler.RevokeToken)
+		admin.POST("/users/:userId/revoke-tokens", adminHandler.RevokeUserTokens)
+
+		// Offboarding: take a deactivated user off every team
+		admin.DELETE("/users/:userId/teams", adminHandler.RemoveUserFromTeams)
+
+		// Impersonation audit
+		admin.GET("/impersonations", adminHandler.ListImpersonations)
+	}
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"go_service/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthRoutes defines routes for the caller's own session
+func AuthRoutes(rg *gin.RouterGroup, authHandler *handlers.AuthHandler) {
+	authRoutes := rg.Group("/auth")
+	{
+		authRoutes.POST("/logout", authHandler.Logout)
+	}
+}
@@ -1,25 +1,32 @@
 package router
 
 import (
+	"go_service/internal/auth"
 	"go_service/internal/handlers"
+	"go_service/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // FolderRoutes defines routes for folder management
-func FolderRoutes(rg *gin.RouterGroup, folderHandler *handlers.FolderHandler, noteHandler *handlers.NoteHandler) {
-	folders := rg.Group("/folders")
+func FolderRoutes(rg *gin.RouterGroup, folderHandler *handlers.FolderHandler, noteHandler *handlers.NoteHandler, limiter *middleware.RateLimiter) {
+	read := middleware.RequireScope(auth.ScopeAssetsRead)
+	write := middleware.RequireScope(auth.ScopeAssetsWrite)
+
+	folders := rg.Group("/folders", limiter.Limit("assets"))
 	{
-		folders.POST("", folderHandler.CreateFolder)
-		folders.GET("/:folderId", folderHandler.GetFolderDetails)
-		folders.PUT("/:folderId", folderHandler.UpdateFolder)
-		folders.DELETE("/:folderId", folderHandler.DeleteFolder)
+		folders.GET("", read, folderHandler.ListFolders)
+		folders.POST("", write, folderHandler.CreateFolder)
+		folders.GET("/:folderId", read, folderHandler.GetFolderDetails)
+		folders.PUT("/:folderId", write, folderHandler.UpdateFolder)
+		folders.DELETE("/:folderId", write, folderHandler.DeleteFolder)
 
 		// Note creation within folder
-		folders.POST("/:folderId/notes", noteHandler.CreateNote)
+		folders.POST("/:folderId/notes", write, noteHandler.CreateNote)
 
 		// Sharing
-		folders.POST("/:folderId/share", folderHandler.ShareFolder)
-		folders.DELETE("/:folderId/share/:userId", folderHandler.RevokeSharing)
+		folders.POST("/:folderId/share", write, folderHandler.ShareFolder)
+		folders.DELETE("/:folderId/share/:userId", write, folderHandler.RevokeSharing)
+		folders.DELETE("/:folderId/share/teams/:teamId", write, folderHandler.RevokeSharing)
 	}
 }
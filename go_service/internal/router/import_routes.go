@@ -1,13 +1,15 @@
 package router
 
 import (
+	"go_service/internal/auth"
 	"go_service/internal/handlers"
+	"go_service/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ImportRoutes defines routes for importing data
-func ImportRoutes(rg *gin.RouterGroup, importHandler *handlers.ImportHandler) {
-	rg.POST("/import-users", importHandler.ImportUsers)
+func ImportRoutes(rg *gin.RouterGroup, importHandler *handlers.ImportHandler, limiter *middleware.RateLimiter) {
+	rg.POST("/import-users", limiter.Limit("import"), middleware.RequireScope(auth.ScopeImportUsers), importHandler.ImportUsers)
 
 }
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"go_service/internal/auth"
+	"go_service/internal/handlers"
+	"go_service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvitationRoutes sets up routes for inviting users to teams and answering invitations
+func InvitationRoutes(rg *gin.RouterGroup, h *handlers.InvitationHandler, limiter *middleware.RateLimiter) {
+	read := middleware.RequireScope(auth.ScopeTeamsRead)
+	write := middleware.RequireScope(auth.ScopeTeamsWrite)
+
+	// Team leaders manage a team's invitations
+	team := rg.Group("/teams/:teamId/invitations", limiter.Limit("teams"))
+	{
+		team.POST("", write, h.CreateInvitation)
+		team.GET("", read, h.ListTeamInvitations)
+		team.POST("/:invitationId/resend", write, h.ResendInvitation)
+		team.DELETE("/:invitationId", write, h.CancelInvitation)
+	}
+
+	// Invitees see and answer the invitations addressed to them
+	mine := rg.Group("/invitations", limiter.Limit("teams"))
+	{
+		mine.GET("", read, h.ListMyInvitations)
+		mine.POST("/redeem", write, h.RedeemInvitation)
+		mine.POST("/:invitationId/accept", write, h.AcceptInvitation)
+		mine.POST("/:invitationId/decline", write, h.DeclineInvitation)
+	}
+}
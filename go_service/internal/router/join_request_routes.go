@@ -0,0 +1,26 @@
+package router
+
+import (
+	"go_service/internal/auth"
+	"go_service/internal/handlers"
+	"go_service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JoinRequestRoutes sets up routes for joining teams and deciding join requests
+func JoinRequestRoutes(rg *gin.RouterGroup, h *handlers.JoinRequestHandler, limiter *middleware.RateLimiter) {
+	read := middleware.RequireScope(auth.ScopeTeamsRead)
+	write := middleware.RequireScope(auth.ScopeTeamsWrite)
+
+	team := rg.Group("/teams/:teamId", limiter.Limit("teams"))
+	{
+		team.POST("/join", write, h.JoinTeam)
+		team.GET("/join-requests", read, h.ListJoinRequests)
+		team.POST("/join-requests/:requestId/approve", write, h.ApproveJoinRequest)
+		team.POST("/join-requests/:requestId/reject", write, h.RejectJoinRequest)
+		team.DELETE("/join-requests/:requestId", write, h.CancelJoinRequest)
+	}
+
+	rg.GET("/join-requests", limiter.Limit("teams"), read, h.ListMyJoinRequests)
+}
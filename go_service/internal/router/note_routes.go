@@ -1,21 +1,27 @@
 package router
 
 import (
+	"go_service/internal/auth"
 	"go_service/internal/handlers"
+	"go_service/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // NoteRoutes defines routes for note management
-func NoteRoutes(rg *gin.RouterGroup, noteHandler *handlers.NoteHandler) {
-	notes := rg.Group("/notes")
+func NoteRoutes(rg *gin.RouterGroup, noteHandler *handlers.NoteHandler, limiter *middleware.RateLimiter) {
+	read := middleware.RequireScope(auth.ScopeAssetsRead)
+	write := middleware.RequireScope(auth.ScopeAssetsWrite)
+
+	notes := rg.Group("/notes", limiter.Limit("assets"))
 	{
-		notes.GET("/:noteId", noteHandler.GetNote)
-		notes.PUT("/:noteId", noteHandler.UpdateNote)
-		notes.DELETE("/:noteId", noteHandler.DeleteNote)
+		notes.GET("/:noteId", read, noteHandler.GetNote)
+		notes.PUT("/:noteId", write, noteHandler.UpdateNote)
+		notes.DELETE("/:noteId", write, noteHandler.DeleteNote)
 
 		// Sharing
-		notes.POST("/:noteId/share", noteHandler.ShareNote)
-		notes.DELETE("/:noteId/share/:userId", noteHandler.RevokeNoteSharing)
+		notes.POST("/:noteId/share", write, noteHandler.ShareNote)
+		notes.DELETE("/:noteId/share/:userId", write, noteHandler.RevokeNoteSharing)
+		notes.DELETE("/:noteId/share/teams/:teamId", write, noteHandler.RevokeNoteSharing)
 	}
 }
@@ -1,32 +1,59 @@
 package router
 
 import (
+	"os"
+
+	"go_service/internal/authz"
 	"go_service/internal/handlers"
-	"go_service/internal/kafka"
 	"go_service/internal/middleware"
 	"go_service/internal/redisclient"
+	"go_service/internal/services"
 
 	"github.com/gin-gonic/gin"
 
 	"gorm.io/gorm"
 )
 
-func SetupRouter(router *gin.Engine, db *gorm.DB, producer *kafka.Producer, redis_client *redisclient.TeamCache) {
+func SetupRouter(router *gin.Engine, db *gorm.DB, redis_client *redisclient.TeamCache, users services.UserDirectory, identities *services.IdentityCache, revocations *services.RevocationService, limiter *middleware.RateLimiter) {
+	engine := authz.NewEngine(authz.NewGormStore(db))
+	apiTokens := services.NewAPITokenService(db)
+
+	// Managers may act as another user unless impersonation is switched off
+	var impersonations *services.ImpersonationService
+	if os.Getenv("IMPERSONATION_ENABLED") != "false" {
+		impersonations = services.NewImpersonationService(db)
+	}
+
+	quotas := services.NewQuotaService(db, services.QuotaDefaultsFromEnv())
+	teams := services.NewTeamService(db, redis_client, services.SuccessionPolicyFromEnv(), quotas)
+	invitations := services.NewInvitationService(db, teams, services.InvitationTTLFromEnv())
+	joinRequests := services.NewJoinRequestService(db, teams)
+
 	// Create handlers
-	teamHandler := handlers.NewTeamHandler(db, producer, redis_client)
-	folderHandler := handlers.NewFolderHandler(db)
-	noteHandler := handlers.NewNoteHandler(db)
-	importHandler := handlers.NewImportHandler()
+	teamHandler := handlers.NewTeamHandler(db, teams, quotas, redis_client, users, engine)
+	invitationHandler := handlers.NewInvitationHandler(invitations, users, engine)
+	joinRequestHandler := handlers.NewJoinRequestHandler(joinRequests, engine)
+	folderHandler := handlers.NewFolderHandler(db, engine, quotas)
+	noteHandler := handlers.NewNoteHandler(db, engine, quotas)
+	importHandler := handlers.NewImportHandler(users)
+	adminHandler := handlers.NewAdminHandler(db, identities, revocations, impersonations, teams, engine)
+	authHandler := handlers.NewAuthHandler(revocations)
+	tokenHandler := handlers.NewTokenHandler(apiTokens, engine)
 
 	//v1 api
 	v1 := router.Group("/api/v1")
 
 	protectedRoutes := v1.Group("/")
-	protectedRoutes.Use(middleware.AuthMiddleware(db))
+	protectedRoutes.Use(limiter.Limit("ip"), middleware.AuthMiddleware(identities, revocations, apiTokens, impersonations))
 
 	// Set up all routes
-	TeamRoutes(protectedRoutes, teamHandler)
-	FolderRoutes(protectedRoutes, folderHandler, noteHandler)
-	NoteRoutes(protectedRoutes, noteHandler)
-	ImportRoutes(protectedRoutes, importHandler)
+	TeamRoutes(protectedRoutes, teamHandler, limiter)
+	InvitationRoutes(protectedRoutes, invitationHandler, limiter)
+	JoinRequestRoutes(protectedRoutes, joinRequestHandler, limiter)
+	FolderRoutes(protectedRoutes, folderHandler, noteHandler, limiter)
+	NoteRoutes(protectedRoutes, noteHandler, limiter)
+	ImportRoutes(protectedRoutes, importHandler, limiter)
+	AdminRoutes(protectedRoutes, adminHandler)
+	AuthRoutes(protectedRoutes, authHandler)
+	TokenRoutes(protectedRoutes, tokenHandler)
 }
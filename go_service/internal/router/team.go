@@ -1,27 +1,57 @@
 package router
 
 import (
+	"go_service/internal/auth"
 	"go_service/internal/handlers"
+	"go_service/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // TeamRoutes sets up routes for team-related operations
-func TeamRoutes(rg *gin.RouterGroup, h *handlers.TeamHandler) {
-	teams := rg.Group("/teams")
+func TeamRoutes(rg *gin.RouterGroup, h *handlers.TeamHandler, limiter *middleware.RateLimiter) {
+	read := middleware.RequireScope(auth.ScopeTeamsRead)
+	write := middleware.RequireScope(auth.ScopeTeamsWrite)
+	assets := limiter.Limit("assets")
+
+	teams := rg.Group("/teams", limiter.Limit("teams"))
 	{
-		teams.POST("", h.CreateTeam)
-		teams.POST("/:teamId/members", h.AddMemberToTeam)
-		teams.GET("/:teamId/members", h.GetTeamMembers)
-		teams.DELETE("/:teamId/members/:memberId", h.RemoveMemberFromTeam)
-		teams.POST("/:teamId/managers", h.AddManagerToTeam)
-		teams.DELETE("/:teamId/managers/:managerId", h.RemoveManagerFromTeam)
-		teams.GET("/:teamId/assets", h.GetTeamAssets)
+		teams.GET("", read, h.ListTeams)
+		teams.POST("", write, h.CreateTeam)
+		teams.GET("/fields", read, h.ListTeamFields)
+		teams.POST("/fields", write, h.CreateTeamField)
+		teams.DELETE("/fields/:fieldKey", write, h.DeleteTeamField)
+		teams.GET("/export", read, h.ExportTeams)
+		teams.POST("/import", limiter.Limit("import"), write, h.ImportTeams)
+		teams.GET("/:teamId", read, h.GetTeam)
+		teams.PATCH("/:teamId", write, h.UpdateTeam)
+		teams.PATCH("/:teamId/metadata", write, h.UpdateTeamMetadata)
+		teams.DELETE("/:teamId", write, h.DeleteTeam)
+		teams.POST("/:teamId/archive", write, h.ArchiveTeam)
+		teams.POST("/:teamId/restore", write, h.RestoreTeam)
+		teams.PUT("/:teamId/parent", write, h.AttachTeam)
+		teams.DELETE("/:teamId/parent", write, h.DetachTeam)
+		teams.GET("/:teamId/subtree", read, h.GetTeamSubtree)
+		teams.POST("/:teamId/members", write, h.AddMemberToTeam)
+		teams.GET("/:teamId/members", read, h.GetTeamMembers)
+		teams.GET("/:teamId/roster", read, h.GetRosterAt)
+		teams.PUT("/:teamId/roster", write, h.SyncRoster)
+		teams.DELETE("/:teamId/members/:memberId", write, h.RemoveMemberFromTeam)
+		teams.PUT("/:teamId/members/:memberId/role", write, h.ChangeMemberRole)
+		teams.PUT("/:teamId/owner", write, h.TransferOwnership)
+		teams.GET("/:teamId/activity", read, h.GetTeamActivity)
+		teams.GET("/:teamId/usage", read, h.GetTeamUsage)
+		teams.PUT("/:teamId/quota", write, h.UpdateTeamQuota)
+		// The managers routes predate roster roles; they promote to and demote from leader
+		teams.POST("/:teamId/managers", write, h.AddManagerToTeam)
+		teams.DELETE("/:teamId/managers/:managerId", write, h.RemoveManagerFromTeam)
+		teams.GET("/:teamId/assets", assets, middleware.RequireScope(auth.ScopeAssetsRead), h.GetTeamAssets)
 	}
 
-	// User assets route - manager only
+	// User routes: assets are for managers, memberships for the user too
 	users := rg.Group("/users")
 	{
-		users.GET("/:userId/assets", h.GetUserAssets)
+		users.GET("/:userId/assets", assets, middleware.RequireScope(auth.ScopeAssetsRead), h.GetUserAssets)
+		users.GET("/:userId/teams", read, h.GetUserTeamsAt)
 	}
 }
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"go_service/internal/auth"
+	"go_service/internal/handlers"
+	"go_service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenRoutes defines routes for personal access tokens and service accounts
+func TokenRoutes(rg *gin.RouterGroup, tokenHandler *handlers.TokenHandler) {
+	tokens := rg.Group("/tokens")
+	{
+		tokens.POST("", tokenHandler.CreatePersonalToken)
+		tokens.GET("", tokenHandler.ListPersonalTokens)
+		tokens.DELETE("/:tokenId", tokenHandler.RevokePersonalToken)
+	}
+
+	accounts := rg.Group("/admin/service-accounts", middleware.RequireScope(auth.ScopeAdmin))
+	{
+		accounts.POST("", tokenHandler.CreateServiceAccount)
+		accounts.GET("", tokenHandler.ListServiceAccounts)
+		accounts.DELETE("/:accountId", tokenHandler.DisableServiceAccount)
+
+		// Credentials
+		accounts.POST("/:accountId/tokens", tokenHandler.CreateServiceAccountToken)
+		accounts.GET("/:accountId/tokens", tokenHandler.ListServiceAccountTokens)
+		accounts.DELETE("/:accountId/tokens/:tokenId", tokenHandler.RevokeServiceAccountToken)
+	}
+}
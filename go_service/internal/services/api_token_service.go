@@ -0,0 +1,266 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go_service/internal/auth"
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Raw token prefixes, used to tell API tokens apart from JWTs
+const (
+	PersonalTokenPrefix       = "pat_"
+	ServiceAccountTokenPrefix = "sat_"
+)
+
+const (
+	defaultTokenLifetime = 90 * 24 * time.Hour
+	maxTokenLifetime     = 365 * 24 * time.Hour
+	lastUsedResolution   = time.Minute
+)
+
+var (
+	// ErrInvalidAPIToken is returned for unknown, expired or revoked tokens
+	ErrInvalidAPIToken = errors.New("invalid API token")
+	// ErrUnknownScope is returned when a token is requested with an unsupported scope
+	ErrUnknownScope = errors.New("unknown scope")
+)
+
+// TokenPrincipal is the identity behind an authenticated API token
+type TokenPrincipal struct {
+	Token          *models.APIToken
+	UserID         uuid.UUID
+	ServiceAccount *models.ServiceAccount
+	Scopes         []string
+}
+
+// APITokenService manages personal access tokens and service account credentials
+type APITokenService struct {
+	db *gorm.DB
+}
+
+// NewAPITokenService creates a new API token service
+func NewAPITokenService(db *gorm.DB) *APITokenService {
+	return &APITokenService{db: db}
+}
+
+// IsAPIToken reports whether a bearer credential is an API token rather than a JWT
+func IsAPIToken(raw string) bool {
+	return strings.HasPrefix(raw, PersonalTokenPrefix) || strings.HasPrefix(raw, ServiceAccountTokenPrefix)
+}
+
+// CreatePersonalToken issues a token acting as userID. The plaintext token is
+// returned once and never stored.
+func (s *APITokenService) CreatePersonalToken(userID uuid.UUID, name string, scopes []string, lifetime time.Duration) (string, *models.APIToken, error) {
+	token := &models.APIToken{
+		Name:        name,
+		UserID:      &userID,
+		CreatedByID: userID,
+	}
+	raw, err := s.issue(token, PersonalTokenPrefix, scopes, lifetime)
+	if err != nil {
+		return "", nil, err
+	}
+	return raw, token, nil
+}
+
+// CreateServiceAccountToken issues a credential for a service account
+func (s *APITokenService) CreateServiceAccountToken(account *models.ServiceAccount, createdBy uuid.UUID, name string, scopes []string, lifetime time.Duration) (string, *models.APIToken, error) {
+	if account.DisabledAt != nil {
+		return "", nil, fmt.Errorf("service account %s is disabled", account.Name)
+	}
+
+	token := &models.APIToken{
+		Name:             name,
+		ServiceAccountID: &account.ID,
+		CreatedByID:      createdBy,
+	}
+	raw, err := s.issue(token, ServiceAccountTokenPrefix, scopes, lifetime)
+	if err != nil {
+		return "", nil, err
+	}
+	return raw, token, nil
+}
+
+func (s *APITokenService) issue(token *models.APIToken, prefix string, scopes []string, lifetime time.Duration) (string, error) {
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("%w: at least one scope is required", ErrUnknownScope)
+	}
+	for _, scope := range scopes {
+		if !auth.IsKnownScope(scope) {
+			return "", fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+	}
+
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+	if lifetime > maxTokenLifetime {
+		lifetime = maxTokenLifetime
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	raw := prefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	token.ID = uuid.New()
+	token.Prefix = raw[:len(prefix)+6]
+	token.TokenHash = hashToken(raw)
+	token.Scopes = scopes
+	token.ExpiresAt = time.Now().Add(lifetime)
+
+	if err := s.db.Create(token).Error; err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return raw, nil
+}
+
+// Authenticate resolves a raw API token to its principal and records its use
+func (s *APITokenService) Authenticate(raw string) (*TokenPrincipal, error) {
+	var token models.APIToken
+	if err := s.db.Preload("ServiceAccount").Where("token_hash = ?", hashToken(raw)).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || now.After(token.ExpiresAt) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	principal := &TokenPrincipal{Token: &token, Scopes: token.Scopes}
+	switch {
+	case token.UserID != nil:
+		principal.UserID = *token.UserID
+	case token.ServiceAccount != nil:
+		if token.ServiceAccount.DisabledAt != nil {
+			return nil, ErrInvalidAPIToken
+		}
+		principal.UserID = token.ServiceAccount.ID
+		principal.ServiceAccount = token.ServiceAccount
+	default:
+		return nil, ErrInvalidAPIToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+		go s.touch(token.ID, now)
+	}
+
+	return principal, nil
+}
+
+// touch records the last-used time, at most once per lastUsedResolution
+func (s *APITokenService) touch(tokenID uuid.UUID, at time.Time) {
+	if err := s.db.Model(&models.APIToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", tokenID, at.Add(-lastUsedResolution)).
+		Update("last_used_at", at).Error; err != nil {
+		log.Printf("Failed to record last use of token %s: %v", tokenID, err)
+	}
+}
+
+// ListUserTokens returns the personal access tokens owned by userID
+func (s *APITokenService) ListUserTokens(userID uuid.UUID) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// ListServiceAccountTokens returns the credentials of a service account
+func (s *APITokenService) ListServiceAccountTokens(accountID uuid.UUID) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := s.db.Where("service_account_id = ?", accountID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeUserToken revokes one of userID's personal access tokens
+func (s *APITokenService) RevokeUserToken(userID, tokenID uuid.UUID) error {
+	return s.revoke(s.db.Where("id = ? AND user_id = ?", tokenID, userID))
+}
+
+// RevokeServiceAccountToken revokes one credential of a service account
+func (s *APITokenService) RevokeServiceAccountToken(accountID, tokenID uuid.UUID) error {
+	return s.revoke(s.db.Where("id = ? AND service_account_id = ?", tokenID, accountID))
+}
+
+func (s *APITokenService) revoke(scope *gorm.DB) error {
+	result := scope.Model(&models.APIToken{}).Where("revoked_at IS NULL").Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateServiceAccount registers a new service account
+func (s *APITokenService) CreateServiceAccount(name, description, role string, createdBy uuid.UUID) (*models.ServiceAccount, error) {
+	account := &models.ServiceAccount{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: description,
+		Role:        role,
+		CreatedByID: createdBy,
+	}
+	if err := s.db.Create(account).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// GetServiceAccount loads a service account by ID
+func (s *APITokenService) GetServiceAccount(accountID uuid.UUID) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	if err := s.db.First(&account, "id = ?", accountID).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ListServiceAccounts returns every service account
+func (s *APITokenService) ListServiceAccounts() ([]models.ServiceAccount, error) {
+	var accounts []models.ServiceAccount
+	err := s.db.Order("name").Find(&accounts).Error
+	return accounts, err
+}
+
+// DisableServiceAccount disables an account and revokes all of its credentials
+func (s *APITokenService) DisableServiceAccount(accountID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.ServiceAccount{}).
+			Where("id = ? AND disabled_at IS NULL", accountID).
+			Update("disabled_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Model(&models.APIToken{}).
+			Where("service_account_id = ? AND revoked_at IS NULL", accountID).
+			Update("revoked_at", now).Error
+	})
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
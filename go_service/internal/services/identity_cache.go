@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go_service/internal/cache"
+	"go_service/internal/kafka"
+	"go_service/internal/redisclient"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IdentityCacheConfig controls how long user identities are trusted
+type IdentityCacheConfig struct {
+	// Capacity is the number of identities kept in process
+	Capacity int
+	// TTL is how long a cached identity is served without asking the user service
+	TTL time.Duration
+	// MaxStale is how long an expired identity may still be served while
+	// the user service is unavailable
+	MaxStale time.Duration
+}
+
+// IdentityCacheConfigFromEnv builds an IdentityCacheConfig from environment variables
+func IdentityCacheConfigFromEnv() IdentityCacheConfig {
+	cfg := IdentityCacheConfig{
+		Capacity: 10000,
+		TTL:      time.Minute,
+		MaxStale: 15 * time.Minute,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("IDENTITY_CACHE_SIZE")); err == nil && v > 0 {
+		cfg.Capacity = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("IDENTITY_CACHE_TTL")); err == nil && v > 0 {
+		cfg.TTL = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("IDENTITY_CACHE_MAX_STALE")); err == nil && v >= 0 {
+		cfg.MaxStale = v
+	}
+
+	return cfg
+}
+
+// IdentityCache resolves users through an in-process LRU, then an optional
+// shared Redis tier, and only then the user service
+type IdentityCache struct {
+	users UserDirectory
+	local *cache.LRU[string, User]
+	store *redisclient.IdentityStore
+	cfg   IdentityCacheConfig
+
+	// mu guards epoch, which every invalidation moves on so a lookup that
+	// started before it does not cache what it read
+	mu    sync.Mutex
+	epoch uint64
+}
+
+// NewIdentityCache creates an identity cache; store may be nil to run with
+// the in-process tier only
+func NewIdentityCache(users UserDirectory, store *redisclient.IdentityStore, cfg IdentityCacheConfig) *IdentityCache {
+	return &IdentityCache{
+		users: users,
+		local: cache.NewLRU[string, User](cfg.Capacity),
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// GetUser returns the identity for userID, serving a stale copy for up to
+// MaxStale when the user service cannot be reached
+func (c *IdentityCache) GetUser(ctx context.Context, userID string) (*User, error) {
+	epoch := c.currentEpoch()
+
+	// In-process tier
+	cached, fetchedAt, found := c.local.Get(userID)
+	if found && time.Since(fetchedAt) < c.cfg.TTL {
+		return &cached, nil
+	}
+
+	// Redis tier
+	if c.store != nil {
+		identity, err := c.store.Get(ctx, userID)
+		if err != nil {
+			log.Printf("Identity cache read failed for %s: %v", userID, err)
+		} else if identity != nil && (!found || identity.FetchedAt.After(fetchedAt)) {
+			cached = userFromIdentity(identity)
+			fetchedAt = identity.FetchedAt
+			found = true
+			c.storeLocal(userID, cached, fetchedAt, epoch)
+			if time.Since(fetchedAt) < c.cfg.TTL {
+				return &cached, nil
+			}
+		}
+	}
+
+	// Read before fetching, so an invalidation during the fetch keeps its
+	// result out of the shared tier
+	var generation int64
+	shareFetched := false
+	if c.store != nil {
+		var err error
+		if generation, err = c.store.Generation(ctx, userID); err != nil {
+			log.Printf("Identity cache read failed for %s: %v", userID, err)
+		}
+		shareFetched = err == nil
+	}
+
+	// Source of truth
+	fetched, err := c.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			c.evict(ctx, userID)
+			return nil, err
+		}
+		if found && time.Since(fetchedAt) < c.cfg.TTL+c.cfg.MaxStale {
+			log.Printf("User service unavailable, serving stale identity for %s (age %s): %v",
+				userID, time.Since(fetchedAt).Round(time.Second), err)
+			return &cached, nil
+		}
+		return nil, err
+	}
+
+	user := *fetched
+	now := time.Now()
+	c.storeLocal(userID, user, now, epoch)
+	if shareFetched {
+		if err := c.store.Set(ctx, identityFromUser(user, now), c.cfg.TTL+c.cfg.MaxStale, generation); err != nil {
+			log.Printf("Identity cache write failed for %s: %v", userID, err)
+		}
+	}
+
+	return &user, nil
+}
+
+// Invalidate drops userID from every tier, e.g. after a role change, and
+// tells the other instances to do the same
+func (c *IdentityCache) Invalidate(ctx context.Context, userID string) error {
+	c.dropLocal(userID)
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Invalidate(ctx, userID)
+}
+
+// RecordRoleChange emits USER_ROLE_CHANGED in tx, so every consumer drops the
+// user's cached identity too. The event belongs to no team, so its TeamID is 0.
+func RecordRoleChange(tx *gorm.DB, userID, performedBy uuid.UUID) error {
+	return AddTeamEvent(tx, kafka.NewTeamEvent(kafka.EventUserRoleChanged, 0, performedBy, userID, nil))
+}
+
+// Listen applies invalidations published by other instances until ctx is cancelled
+func (c *IdentityCache) Listen(ctx context.Context) {
+	if c.store == nil {
+		return
+	}
+	c.store.SubscribeInvalidations(ctx, c.dropLocal)
+}
+
+func (c *IdentityCache) currentEpoch() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch
+}
+
+// storeLocal caches user in process unless an invalidation happened since
+// epoch was read
+func (c *IdentityCache) storeLocal(userID string, user User, fetchedAt time.Time, epoch uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.epoch == epoch {
+		c.local.Set(userID, user, fetchedAt)
+	}
+}
+
+func (c *IdentityCache) dropLocal(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+	c.local.Delete(userID)
+}
+
+func (c *IdentityCache) evict(ctx context.Context, userID string) {
+	c.dropLocal(userID)
+	if c.store != nil {
+		if err := c.store.Invalidate(ctx, userID); err != nil {
+			log.Printf("Identity cache eviction failed for %s: %v", userID, err)
+		}
+	}
+}
+
+func userFromIdentity(identity *redisclient.Identity) User {
+	return User{
+		ID:       identity.UserID,
+		Username: identity.Username,
+		Email:    identity.Email,
+		Role:     identity.Role,
+	}
+}
+
+func identityFromUser(user User, fetchedAt time.Time) *redisclient.Identity {
+	return &redisclient.Identity{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		FetchedAt: fetchedAt,
+	}
+}
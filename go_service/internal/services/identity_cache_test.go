@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// racingDirectory answers GetUserByID with role, running during in the middle
+// of the first call
+type racingDirectory struct {
+	UserDirectory
+	role   string
+	calls  int
+	during func()
+}
+
+func (d *racingDirectory) GetUserByID(ctx context.Context, userID string) (*User, error) {
+	d.calls++
+	user := &User{ID: userID, Role: d.role}
+	if d.during != nil {
+		during := d.during
+		d.during = nil
+		during()
+	}
+	return user, nil
+}
+
+// TestInvalidateDuringFetch checks that a lookup overtaken by an invalidation
+// does not cache the identity it read before the change
+func TestInvalidateDuringFetch(t *testing.T) {
+	users := &racingDirectory{role: "member"}
+	identities := NewIdentityCache(users, nil, IdentityCacheConfig{Capacity: 10, TTL: time.Minute})
+	users.during = func() {
+		users.role = "manager"
+		if err := identities.Invalidate(context.Background(), "u1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user, err := identities.GetUser(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != "member" {
+		t.Fatalf("first lookup returned role %q, want the one it read", user.Role)
+	}
+
+	user, err = identities.GetUser(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != "manager" || users.calls != 2 {
+		t.Errorf("second lookup returned role %q after %d fetches, want a fresh fetch of manager", user.Role, users.calls)
+	}
+
+	if _, err := identities.GetUser(context.Background(), "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if users.calls != 2 {
+		t.Errorf("third lookup fetched again (%d fetches), want it served from cache", users.calls)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type impersonatorKey struct{}
+
+// WithImpersonator marks ctx as a request made by actorID while impersonating
+// another user. Events recorded in a transaction begun under ctx name actorID
+// alongside the impersonated user.
+func WithImpersonator(ctx context.Context, actorID uuid.UUID) context.Context {
+	return context.WithValue(ctx, impersonatorKey{}, actorID)
+}
+
+// impersonatorOf returns the manager behind tx's request, nil when the
+// request is not impersonated
+func impersonatorOf(tx *gorm.DB) *uuid.UUID {
+	if tx.Statement == nil || tx.Statement.Context == nil {
+		return nil
+	}
+	actorID, ok := tx.Statement.Context.Value(impersonatorKey{}).(uuid.UUID)
+	if !ok {
+		return nil
+	}
+	return &actorID
+}
+
+// ImpersonationService stores the audit trail of impersonated requests
+type ImpersonationService struct {
+	db *gorm.DB
+}
+
+func NewImpersonationService(db *gorm.DB) *ImpersonationService {
+	return &ImpersonationService{db: db}
+}
+
+// Record saves one audit entry
+func (s *ImpersonationService) Record(entry *models.ImpersonationAudit) error {
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	return s.db.Create(entry).Error
+}
+
+// ImpersonationFilter narrows ListAudit; zero values match everything
+type ImpersonationFilter struct {
+	ActorID  uuid.UUID
+	TargetID uuid.UUID
+	Since    time.Time
+	Limit    int
+}
+
+// ListAudit returns audit entries newest first
+func (s *ImpersonationService) ListAudit(filter ImpersonationFilter) ([]models.ImpersonationAudit, error) {
+	query := s.db.Model(&models.ImpersonationAudit{})
+	if filter.ActorID != uuid.Nil {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetID != uuid.Nil {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	var entries []models.ImpersonationAudit
+	err := query.Order("created_at DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestImpersonatorOf(t *testing.T) {
+	manager := uuid.New()
+	tx := &gorm.DB{Statement: &gorm.Statement{Context: WithImpersonator(context.Background(), manager)}}
+	if got := impersonatorOf(tx); got == nil || *got != manager {
+		t.Errorf("impersonatorOf = %v, want %s", got, manager)
+	}
+
+	plain := &gorm.DB{Statement: &gorm.Statement{Context: context.Background()}}
+	if got := impersonatorOf(plain); got != nil {
+		t.Errorf("impersonatorOf = %s for a request that is not impersonated, want nil", got)
+	}
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	invitationTokenPrefix = "inv_"
+	defaultInvitationTTL  = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInvitationExists is returned when the invitee already has a pending invitation to the team
+	ErrInvitationExists = errors.New("a pending invitation already exists")
+	// ErrInvitationNotPending is returned when acting on an invitation that was already answered or cancelled
+	ErrInvitationNotPending = errors.New("invitation is no longer pending")
+	// ErrInvitationExpired is returned when answering an invitation after it expired
+	ErrInvitationExpired = errors.New("invitation has expired")
+	// ErrNotInvitee is returned when someone other than the invitee answers an invitation
+	ErrNotInvitee = errors.New("invitation was sent to someone else")
+)
+
+// Invitee identifies the user answering an invitation
+type Invitee struct {
+	UserID uuid.UUID
+	Email  string
+}
+
+// matches reports whether the invitation was addressed to the invitee
+func (i Invitee) matches(invitation *models.TeamInvitation) bool {
+	if invitation.InviteeID != nil {
+		return *invitation.InviteeID == i.UserID
+	}
+	return i.Email != "" && strings.EqualFold(invitation.Email, i.Email)
+}
+
+// InvitationTTLFromEnv reads TEAM_INVITATION_TTL, defaulting to seven days
+func InvitationTTLFromEnv() time.Duration {
+	return envDuration("TEAM_INVITATION_TTL", defaultInvitationTTL)
+}
+
+// InvitationService manages invitations to join a team
+type InvitationService struct {
+	db    *gorm.DB
+	teams *TeamService
+	ttl   time.Duration
+}
+
+func NewInvitationService(db *gorm.DB, teams *TeamService, ttl time.Duration) *InvitationService {
+	if ttl <= 0 {
+		ttl = defaultInvitationTTL
+	}
+	return &InvitationService{db: db, teams: teams, ttl: ttl}
+}
+
+// WithContext returns a copy of s whose queries run under ctx; see
+// TeamService.WithContext
+func (s *InvitationService) WithContext(ctx context.Context) *InvitationService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	scoped.teams = s.teams.WithContext(ctx)
+	return &scoped
+}
+
+// Invite creates a pending invitation for inviteeID or, when it is nil, for
+// email. It returns the invitation and its token, which is only shown once.
+func (s *InvitationService) Invite(teamID int, inviteeID *uuid.UUID, email string, invitedBy uuid.UUID) (*models.TeamInvitation, string, error) {
+	team, err := s.teams.repo.FindTeam(teamID)
+	if err != nil {
+		return nil, "", err
+	}
+	if team.ArchivedAt != nil {
+		return nil, "", ErrTeamArchived
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if inviteeID != nil {
+		member, err := s.teams.repo.IsMember(teamID, *inviteeID)
+		if err != nil {
+			return nil, "", err
+		}
+		if member {
+			return nil, "", ErrAlreadyMember
+		}
+	}
+
+	s.expireStale(s.db.Where("team_id = ?", teamID))
+
+	pending := s.db.Model(&models.TeamInvitation{}).Where("team_id = ? AND status = ?", teamID, models.InvitationPending)
+	if inviteeID != nil {
+		pending = pending.Where("invitee_id = ?", *inviteeID)
+	} else {
+		pending = pending.Where("email = ?", email)
+	}
+	var count int64
+	if err := pending.Count(&count).Error; err != nil {
+		return nil, "", err
+	}
+	if count > 0 {
+		return nil, "", ErrInvitationExists
+	}
+
+	token, hash, err := newInvitationToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	invitation := &models.TeamInvitation{
+		ID:          uuid.New(),
+		TeamID:      teamID,
+		InviteeID:   inviteeID,
+		Email:       email,
+		InvitedByID: invitedBy,
+		Status:      models.InvitationPending,
+		TokenHash:   hash,
+		SendCount:   1,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invitation).Error; err != nil {
+			return err
+		}
+		return recordInvitationEvent(tx, kafka.EventInvitationCreated, invitation, invitedBy)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return invitation, token, nil
+}
+
+// ListTeamInvitations returns a team's invitations newest first, optionally filtered by status
+func (s *InvitationService) ListTeamInvitations(teamID int, status models.InvitationStatus) ([]models.TeamInvitation, error) {
+	s.expireStale(s.db.Where("team_id = ?", teamID))
+
+	query := s.db.Where("team_id = ?", teamID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var invitations []models.TeamInvitation
+	err := query.Order("created_at DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// ListPending returns the pending invitations addressed to the invitee
+func (s *InvitationService) ListPending(invitee Invitee) ([]models.TeamInvitation, error) {
+	addressed := s.db.Where("invitee_id = ?", invitee.UserID)
+	if invitee.Email != "" {
+		addressed = addressed.Or("invitee_id IS NULL AND email = ?", strings.ToLower(invitee.Email))
+	}
+
+	var invitations []models.TeamInvitation
+	err := s.db.Preload("Team").
+		Where(addressed).
+		Where("status = ? AND expires_at > ?", models.InvitationPending, time.Now()).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// Resend issues a new token for a pending or expired invitation and restarts its expiry
+func (s *InvitationService) Resend(teamID int, invitationID, performedBy uuid.UUID) (*models.TeamInvitation, string, error) {
+	invitation, err := s.findTeamInvitation(teamID, invitationID)
+	if err != nil {
+		return nil, "", err
+	}
+	if invitation.Status != models.InvitationPending && invitation.Status != models.InvitationExpired {
+		return nil, "", ErrInvitationNotPending
+	}
+
+	token, hash, err := newInvitationToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(invitation).Updates(map[string]interface{}{
+			"status":     models.InvitationPending,
+			"token_hash": hash,
+			"send_count": gorm.Expr("send_count + 1"),
+			"expires_at": time.Now().Add(s.ttl),
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.First(invitation, "id = ?", invitation.ID).Error; err != nil {
+			return err
+		}
+		return recordInvitationEvent(tx, kafka.EventInvitationResent, invitation, performedBy)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return invitation, token, nil
+}
+
+// Cancel withdraws a pending invitation
+func (s *InvitationService) Cancel(teamID int, invitationID, performedBy uuid.UUID) (*models.TeamInvitation, error) {
+	invitation, err := s.findTeamInvitation(teamID, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status != models.InvitationPending {
+		return nil, ErrInvitationNotPending
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.finish(tx, invitation, models.InvitationCancelled, nil); err != nil {
+			return err
+		}
+		return recordInvitationEvent(tx, kafka.EventInvitationCancelled, invitation, performedBy)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// Respond accepts or declines the invitation with the given ID
+func (s *InvitationService) Respond(invitationID uuid.UUID, invitee Invitee, accept bool) (*models.TeamInvitation, error) {
+	var invitation models.TeamInvitation
+	if err := s.db.First(&invitation, "id = ?", invitationID).Error; err != nil {
+		return nil, err
+	}
+	return s.respond(&invitation, invitee, accept)
+}
+
+// Redeem accepts or declines the invitation a token was issued for
+func (s *InvitationService) Redeem(token string, invitee Invitee, accept bool) (*models.TeamInvitation, error) {
+	var invitation models.TeamInvitation
+	if err := s.db.Where("token_hash = ?", hashToken(token)).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return s.respond(&invitation, invitee, accept)
+}
+
+func (s *InvitationService) respond(invitation *models.TeamInvitation, invitee Invitee, accept bool) (*models.TeamInvitation, error) {
+	if !invitee.matches(invitation) {
+		return nil, ErrNotInvitee
+	}
+	if invitation.Status == models.InvitationPending && time.Now().After(invitation.ExpiresAt) {
+		s.expire(invitation)
+		return nil, ErrInvitationExpired
+	}
+	if invitation.Status == models.InvitationExpired {
+		return nil, ErrInvitationExpired
+	}
+	if invitation.Status != models.InvitationPending {
+		return nil, ErrInvitationNotPending
+	}
+
+	if !accept {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := s.finish(tx, invitation, models.InvitationDeclined, &invitee.UserID); err != nil {
+				return err
+			}
+			return recordInvitationEvent(tx, kafka.EventInvitationDeclined, invitation, invitee.UserID)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return invitation, nil
+	}
+
+	team, err := s.teams.repo.FindTeam(invitation.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.ArchivedAt != nil {
+		return nil, ErrTeamArchived
+	}
+
+	// Accepting when already on the roster still closes the invitation
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := s.teams.AddMember(tx, invitation.TeamID, invitee.UserID, invitation.InvitedByID)
+		if err != nil && !errors.Is(err, ErrAlreadyMember) {
+			return err
+		}
+		if err := s.finish(tx, invitation, models.InvitationAccepted, &invitee.UserID); err != nil {
+			return err
+		}
+		return recordInvitationEvent(tx, kafka.EventInvitationAccepted, invitation, invitee.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func (s *InvitationService) findTeamInvitation(teamID int, invitationID uuid.UUID) (*models.TeamInvitation, error) {
+	var invitation models.TeamInvitation
+	if err := s.db.Where("id = ? AND team_id = ?", invitationID, teamID).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// finish moves an invitation to a final status, recording who answered it
+func (s *InvitationService) finish(db *gorm.DB, invitation *models.TeamInvitation, status models.InvitationStatus, answeredBy *uuid.UUID) error {
+	now := time.Now()
+	updates := map[string]interface{}{"status": status, "responded_at": now}
+	if answeredBy != nil && invitation.InviteeID == nil {
+		updates["invitee_id"] = *answeredBy
+		invitation.InviteeID = answeredBy
+	}
+
+	// The status guard makes concurrent answers to the same invitation lose cleanly
+	result := db.Model(&models.TeamInvitation{}).
+		Where("id = ? AND status = ?", invitation.ID, models.InvitationPending).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotPending
+	}
+
+	invitation.Status = status
+	invitation.RespondedAt = &now
+	return nil
+}
+
+// expireStale marks the pending invitations in scope whose time has run out as expired
+func (s *InvitationService) expireStale(scope *gorm.DB) {
+	var stale []models.TeamInvitation
+	if err := scope.Where("status = ? AND expires_at <= ?", models.InvitationPending, time.Now()).Find(&stale).Error; err != nil {
+		return
+	}
+	for i := range stale {
+		s.expire(&stale[i])
+	}
+}
+
+func (s *InvitationService) expire(invitation *models.TeamInvitation) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TeamInvitation{}).
+			Where("id = ? AND status = ?", invitation.ID, models.InvitationPending).
+			Update("status", models.InvitationExpired)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		invitation.Status = models.InvitationExpired
+		return recordInvitationEvent(tx, kafka.EventInvitationExpired, invitation, uuid.Nil)
+	})
+	if err != nil {
+		log.Printf("Failed to expire invitation %s: %v", invitation.ID, err)
+	}
+}
+
+// recordInvitationEvent stores an invitation event in tx's outbox
+func recordInvitationEvent(tx *gorm.DB, eventType string, invitation *models.TeamInvitation, performedBy uuid.UUID) error {
+	var target uuid.UUID
+	if invitation.InviteeID != nil {
+		target = *invitation.InviteeID
+	}
+	details := map[string]string{
+		"invitationId": invitation.ID.String(),
+		"invitedBy":    invitation.InvitedByID.String(),
+		"expiresAt":    invitation.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+	if invitation.Email != "" {
+		details["email"] = invitation.Email
+	}
+	return recordTeamEvent(tx, eventType, invitation.TeamID, performedBy, target, details)
+}
+
+// newInvitationToken returns a random token and the hash that is stored for it
+func newInvitationToken() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	token := invitationTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return token, hashToken(token), nil
+}
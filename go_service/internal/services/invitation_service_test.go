@@ -0,0 +1,48 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestInviteeMatches(t *testing.T) {
+	userID := uuid.New()
+	invitee := Invitee{UserID: userID, Email: "Dev@Example.com"}
+
+	byID := &models.TeamInvitation{InviteeID: &userID, Email: "someone@example.com"}
+	if !invitee.matches(byID) {
+		t.Error("invitation by user ID should match that user")
+	}
+
+	other := uuid.New()
+	if invitee.matches(&models.TeamInvitation{InviteeID: &other, Email: "dev@example.com"}) {
+		t.Error("invitation to another user ID should not match on email")
+	}
+
+	if !invitee.matches(&models.TeamInvitation{Email: "dev@example.com"}) {
+		t.Error("email invitation should match case-insensitively")
+	}
+
+	if (Invitee{UserID: userID}).matches(&models.TeamInvitation{Email: ""}) {
+		t.Error("an empty email should never match")
+	}
+}
+
+func TestNewInvitationToken(t *testing.T) {
+	token, hash, err := newInvitationToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, invitationTokenPrefix) || hash != hashToken(token) {
+		t.Errorf("unexpected token %q / hash %q", token, hash)
+	}
+
+	other, _, _ := newInvitationToken()
+	if other == token {
+		t.Error("tokens should be random")
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTeamInviteOnly is returned when asking to join a team that only admits invited users
+	ErrTeamInviteOnly = errors.New("team is invite-only")
+	// ErrJoinRequestExists is returned when the user already has a pending request for the team
+	ErrJoinRequestExists = errors.New("a pending join request already exists")
+	// ErrJoinRequestNotPending is returned when deciding a request that was already decided or withdrawn
+	ErrJoinRequestNotPending = errors.New("join request is no longer pending")
+	// ErrRejectReasonRequired is returned when rejecting a join request without saying why
+	ErrRejectReasonRequired = errors.New("a reason is required to reject a join request")
+)
+
+// JoinRequestService lets users ask to join teams and leaders decide on those requests
+type JoinRequestService struct {
+	db    *gorm.DB
+	teams *TeamService
+}
+
+func NewJoinRequestService(db *gorm.DB, teams *TeamService) *JoinRequestService {
+	return &JoinRequestService{db: db, teams: teams}
+}
+
+// WithContext returns a copy of s whose queries run under ctx; see
+// TeamService.WithContext
+func (s *JoinRequestService) WithContext(ctx context.Context) *JoinRequestService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	scoped.teams = s.teams.WithContext(ctx)
+	return &scoped
+}
+
+// Join puts userID on an open team straight away, returning a nil request, or
+// files a pending request for a team with the request join policy
+func (s *JoinRequestService) Join(teamID int, userID uuid.UUID, message string) (*models.TeamJoinRequest, error) {
+	team, err := s.teams.repo.FindTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.ArchivedAt != nil {
+		return nil, ErrTeamArchived
+	}
+
+	switch team.JoinPolicy {
+	case models.JoinPolicyOpen:
+		return nil, s.teams.AddMember(nil, teamID, userID, userID)
+	case models.JoinPolicyRequest:
+	default:
+		return nil, ErrTeamInviteOnly
+	}
+
+	member, err := s.teams.repo.IsMember(teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member {
+		return nil, ErrAlreadyMember
+	}
+
+	var pending int64
+	err = s.db.Model(&models.TeamJoinRequest{}).
+		Where("team_id = ? AND user_id = ? AND status = ?", teamID, userID, models.JoinRequestPending).
+		Count(&pending).Error
+	if err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, ErrJoinRequestExists
+	}
+
+	request := &models.TeamJoinRequest{
+		ID:      uuid.New(),
+		TeamID:  teamID,
+		UserID:  userID,
+		Status:  models.JoinRequestPending,
+		Message: message,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(request).Error; err != nil {
+			return err
+		}
+		return recordJoinRequestEvent(tx, kafka.EventJoinRequested, request, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// ListTeamRequests returns a team's join requests oldest first, optionally filtered by status
+func (s *JoinRequestService) ListTeamRequests(teamID int, status models.JoinRequestStatus) ([]models.TeamJoinRequest, error) {
+	query := s.db.Where("team_id = ?", teamID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var requests []models.TeamJoinRequest
+	err := query.Order("created_at ASC").Find(&requests).Error
+	return requests, err
+}
+
+// ListUserRequests returns the join requests a user has made, newest first
+func (s *JoinRequestService) ListUserRequests(userID uuid.UUID) ([]models.TeamJoinRequest, error) {
+	var requests []models.TeamJoinRequest
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&requests).Error
+	return requests, err
+}
+
+// Approve adds the requester to the team through the regular member path and closes the request
+func (s *JoinRequestService) Approve(teamID int, requestID, decidedBy uuid.UUID, reason string) (*models.TeamJoinRequest, error) {
+	request, err := s.findPending(teamID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	team, err := s.teams.repo.FindTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.ArchivedAt != nil {
+		return nil, ErrTeamArchived
+	}
+
+	// Approving someone who joined by other means still closes the request
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := s.teams.AddMember(tx, teamID, request.UserID, decidedBy)
+		if err != nil && !errors.Is(err, ErrAlreadyMember) {
+			return err
+		}
+		if err := s.decide(tx, request, models.JoinRequestApproved, &decidedBy, reason); err != nil {
+			return err
+		}
+		return recordJoinRequestEvent(tx, kafka.EventJoinRequestApproved, request, decidedBy)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// Reject turns down a pending join request, which needs a reason
+func (s *JoinRequestService) Reject(teamID int, requestID, decidedBy uuid.UUID, reason string) (*models.TeamJoinRequest, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrRejectReasonRequired
+	}
+
+	request, err := s.findPending(teamID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.decide(tx, request, models.JoinRequestRejected, &decidedBy, reason); err != nil {
+			return err
+		}
+		return recordJoinRequestEvent(tx, kafka.EventJoinRequestRejected, request, decidedBy)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// Cancel withdraws the requester's own pending request
+func (s *JoinRequestService) Cancel(teamID int, requestID, userID uuid.UUID) (*models.TeamJoinRequest, error) {
+	request, err := s.findPending(teamID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.decide(tx, request, models.JoinRequestCancelled, nil, ""); err != nil {
+			return err
+		}
+		return recordJoinRequestEvent(tx, kafka.EventJoinRequestCancelled, request, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func (s *JoinRequestService) findPending(teamID int, requestID uuid.UUID) (*models.TeamJoinRequest, error) {
+	var request models.TeamJoinRequest
+	if err := s.db.Where("id = ? AND team_id = ?", requestID, teamID).First(&request).Error; err != nil {
+		return nil, err
+	}
+	if request.Status != models.JoinRequestPending {
+		return nil, ErrJoinRequestNotPending
+	}
+	return &request, nil
+}
+
+// decide moves a pending request to its final status; the status guard makes
+// concurrent decisions on the same request lose cleanly
+func (s *JoinRequestService) decide(db *gorm.DB, request *models.TeamJoinRequest, status models.JoinRequestStatus, decidedBy *uuid.UUID, reason string) error {
+	now := time.Now()
+	result := db.Model(&models.TeamJoinRequest{}).
+		Where("id = ? AND status = ?", request.ID, models.JoinRequestPending).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"reason":        reason,
+			"decided_by_id": decidedBy,
+			"decided_at":    now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJoinRequestNotPending
+	}
+
+	request.Status = status
+	request.Reason = reason
+	request.DecidedByID = decidedBy
+	request.DecidedAt = &now
+	return nil
+}
+
+// recordJoinRequestEvent stores a join request event in tx's outbox
+func recordJoinRequestEvent(tx *gorm.DB, eventType string, request *models.TeamJoinRequest, performedBy uuid.UUID) error {
+	details := map[string]string{"requestId": request.ID.String()}
+	if request.Reason != "" {
+		details["reason"] = request.Reason
+	}
+	return recordTeamEvent(tx, eventType, request.TeamID, performedBy, request.UserID, details)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"go_service/internal/models"
+	"go_service/internal/testutil"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// testJoinRequests returns a join request service on a rolled-back
+// transaction; see testutil.DB
+func testJoinRequests(t *testing.T) (*JoinRequestService, *gorm.DB) {
+	t.Helper()
+	db := testutil.DB(t)
+	return NewJoinRequestService(db, NewTeamService(db, nil, SuccessionBlock, nil)), db
+}
+
+func TestJoinOpenTeamAddsMember(t *testing.T) {
+	requests, db := testJoinRequests(t)
+	team := testutil.Team(t, db, models.JoinPolicyOpen)
+	user := uuid.New()
+
+	request, err := requests.Join(team.ID, user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request != nil {
+		t.Errorf("joining an open team filed request %+v, want none", request)
+	}
+	member, err := requests.teams.repo.IsMember(team.ID, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !member {
+		t.Error("joining an open team should put the user on the roster")
+	}
+
+	var pending int64
+	if err := db.Model(&models.TeamJoinRequest{}).Where("team_id = ?", team.ID).Count(&pending).Error; err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 {
+		t.Errorf("got %d join requests, want none", pending)
+	}
+}
+
+func TestJoinInviteOnlyTeamRejected(t *testing.T) {
+	requests, db := testJoinRequests(t)
+	team := testutil.Team(t, db, models.JoinPolicyInviteOnly)
+	user := uuid.New()
+
+	if _, err := requests.Join(team.ID, user, "let me in"); !errors.Is(err, ErrTeamInviteOnly) {
+		t.Fatalf("Join = %v, want %v", err, ErrTeamInviteOnly)
+	}
+	if member, _ := requests.teams.repo.IsMember(team.ID, user); member {
+		t.Error("an invite-only team should not admit a user who asks")
+	}
+}
+
+func TestRejectRequiresReason(t *testing.T) {
+	// The reason is checked before the request is even looked up
+	requests := NewJoinRequestService(nil, nil)
+	for _, reason := range []string{"", "  \t"} {
+		if _, err := requests.Reject(1, uuid.New(), uuid.New(), reason); !errors.Is(err, ErrRejectReasonRequired) {
+			t.Errorf("Reject(reason %q) = %v, want %v", reason, err, ErrRejectReasonRequired)
+		}
+	}
+}
+
+func TestApproveDecidedRequest(t *testing.T) {
+	requests, db := testJoinRequests(t)
+	team := testutil.Team(t, db, models.JoinPolicyRequest)
+	user, leader := uuid.New(), uuid.New()
+
+	request, err := requests.Join(team.ID, user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requests.Reject(team.ID, request.ID, leader, "team is full"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := requests.Approve(team.ID, request.ID, leader, ""); !errors.Is(err, ErrJoinRequestNotPending) {
+		t.Fatalf("Approve = %v, want %v", err, ErrJoinRequestNotPending)
+	}
+	if member, _ := requests.teams.repo.IsMember(team.ID, user); member {
+		t.Error("approving a rejected request should not add the user")
+	}
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+	"go_service/internal/resilience"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// outboxLockID is the Postgres advisory lock that lets only one relay publish
+// at a time, which keeps each key's events in order across instances
+const outboxLockID = 7_301_415
+
+// outboxKeyLockSpace is the first half of the two-part advisory lock taken on
+// an event's key, the second half being the key's hash
+const outboxKeyLockSpace = 7_301_418
+
+var (
+	outboxPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbox_events_published_total",
+		Help: "Outbox publish attempts by result (delivered, failed, dead)",
+	}, []string{"result"})
+	outboxLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "outbox_oldest_pending_seconds",
+		Help: "Age of the oldest event the relay has not delivered yet",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(outboxPublished, outboxLag)
+}
+
+// AddTeamEvent stores a team event in tx. It is published once tx commits,
+// so the event exists if and only if the change it describes does. Events of
+// one key are written one transaction at a time, so the relay, which
+// publishes in ID order, sees them in commit order.
+func AddTeamEvent(tx *gorm.DB, event kafka.TeamEvent) error {
+	event.ImpersonatedBy = impersonatorOf(tx)
+	return addOutboxEvent(tx, kafka.TeamTopic, event.Key(), event.EventType, event.EventID, event)
+}
+
+// AddAssetEvent stores a folder or note event in tx; see AddTeamEvent
+func AddAssetEvent(tx *gorm.DB, event kafka.AssetEvent) error {
+	event.ImpersonatedBy = impersonatorOf(tx)
+	return addOutboxEvent(tx, kafka.AssetTopic, event.Key(), event.EventType, event.EventID, event)
+}
+
+func addOutboxEvent(tx *gorm.DB, topic, key, eventType, eventID string, event interface{}) error {
+	id, err := uuid.Parse(eventID)
+	if err != nil {
+		return fmt.Errorf("invalid event ID %q: %w", eventID, err)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	// IDs are handed out on insert, not on commit. Holding the key's lock until
+	// tx ends keeps a later transaction from taking an ID for the key and
+	// committing first; the nested transaction keeps it held across the
+	// insert when tx is not a transaction itself.
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", outboxKeyLockSpace, key).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OutboxEvent{
+			EventID:       id,
+			Topic:         topic,
+			Key:           key,
+			EventType:     eventType,
+			Payload:       string(payload),
+			NextAttemptAt: time.Now(),
+		}).Error
+	})
+}
+
+// Publisher delivers one message and returns once the broker has accepted it
+type Publisher interface {
+	Publish(ctx context.Context, topic, key string, value []byte) error
+}
+
+// OutboxRelayConfig tunes the outbox relay
+type OutboxRelayConfig struct {
+	BatchSize      int
+	PollInterval   time.Duration
+	PublishTimeout time.Duration
+	// Backoff spaces out retries of an event that failed to publish
+	Backoff resilience.Backoff
+	// MaxAttempts is how many failed publishes dead-letter an event; 0
+	// retries forever
+	MaxAttempts int
+	// Retention is how long delivered events are kept before being deleted
+	Retention time.Duration
+}
+
+// OutboxRelayConfigFromEnv reads OUTBOX_* settings
+func OutboxRelayConfigFromEnv() OutboxRelayConfig {
+	return OutboxRelayConfig{
+		BatchSize:      envInt("OUTBOX_BATCH_SIZE", 100),
+		PollInterval:   envDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		PublishTimeout: envDuration("OUTBOX_PUBLISH_TIMEOUT", 10*time.Second),
+		Backoff: resilience.Backoff{
+			Base: envDuration("OUTBOX_RETRY_BACKOFF", time.Second),
+			Max:  envDuration("OUTBOX_RETRY_MAX_BACKOFF", 5*time.Minute),
+		},
+		MaxAttempts: envInt("OUTBOX_MAX_ATTEMPTS", 20),
+		Retention:   envDuration("OUTBOX_RETENTION", 72*time.Hour),
+	}
+}
+
+// OutboxRelay publishes stored events to Kafka. Events sharing a key are
+// published strictly in the order they were stored: while one is waiting to
+// be retried, the events after it stay queued. An event that fails
+// MaxAttempts times is dead-lettered and stops holding its key back.
+// Delivery is at least once; consumers drop duplicates by event ID.
+type OutboxRelay struct {
+	db        *gorm.DB
+	publisher Publisher
+	cfg       OutboxRelayConfig
+}
+
+func NewOutboxRelay(db *gorm.DB, publisher Publisher, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.PublishTimeout <= 0 {
+		cfg.PublishTimeout = 10 * time.Second
+	}
+	return &OutboxRelay{db: db, publisher: publisher, cfg: cfg}
+}
+
+// Run relays events until ctx is cancelled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Now()
+
+	for {
+		// Keep going while full batches come back so a backlog drains quickly
+		for ctx.Err() == nil {
+			n, err := r.RelayOnce(ctx)
+			if err != nil {
+				log.Printf("Outbox relay failed: %v", err)
+				break
+			}
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		if r.cfg.Retention > 0 && time.Since(lastCleanup) > time.Hour {
+			r.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes one batch of due events and returns how many it tried.
+// The batch is claimed in one short transaction and the outcomes recorded in
+// another, so no transaction stays open while publishing.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.claim(ctx)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	results := r.publish(ctx, events)
+	attempted := 0
+	for _, result := range results {
+		if result.Attempted {
+			attempted++
+		}
+	}
+	if err := r.record(ctx, results); err != nil {
+		return attempted, err
+	}
+
+	r.updateLag(ctx)
+	return attempted, nil
+}
+
+// claim picks a batch of due events and leases them by pushing their next
+// attempt past the time publishing them can take. Until the lease runs out no
+// relay picks them again, nor the later events of their keys, so a relay
+// that dies mid-batch only delays them. ID order is commit order within a key
+// because addOutboxEvent serializes each key's writers.
+func (r *OutboxRelay) claim(ctx context.Context) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxLockID).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			// Another instance is claiming
+			return nil
+		}
+
+		now := time.Now()
+		err := tx.Raw(`SELECT * FROM outbox_events o
+			WHERE o.delivered_at IS NULL AND o.dead_at IS NULL AND o.next_attempt_at <= ?
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox_events e
+				WHERE e.key = o.key AND e.id < o.id AND e.delivered_at IS NULL AND e.dead_at IS NULL AND e.next_attempt_at > ?
+			)
+			ORDER BY o.id
+			LIMIT ?`, now, now, r.cfg.BatchSize).Scan(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]uint64, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		lease := now.Add(r.cfg.PublishTimeout * time.Duration(len(events)+1))
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).Update("next_attempt_at", lease).Error
+	})
+	return events, err
+}
+
+// outboxResult is what publishing did to one claimed event
+type outboxResult struct {
+	ID uint64
+	// Attempted is false for an event held back behind a failed one of its key
+	Attempted bool
+	Delivered bool
+	// Dead is set when the event failed for the last time
+	Dead bool
+	// Attempts, NextAttemptAt and Error describe a failure; NextAttemptAt is
+	// also when a held back event becomes due again
+	Attempts      int
+	NextAttemptAt time.Time
+	Error         string
+}
+
+// publish sends claimed events in order. Once an event fails, the later
+// events of its key in the batch are held back rather than sent out of order.
+func (r *OutboxRelay) publish(ctx context.Context, events []models.OutboxEvent) []outboxResult {
+	results := make([]outboxResult, len(events))
+	blocked := make(map[string]bool)
+	for i := range events {
+		event := &events[i]
+		result := &results[i]
+		result.ID = event.ID
+		if blocked[event.Key] {
+			// Due again at once; the failed event keeps it queued until it is delivered
+			result.NextAttemptAt = time.Now()
+			continue
+		}
+		result.Attempted = true
+
+		publishCtx, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout)
+		err := r.publisher.Publish(publishCtx, event.Topic, event.Key, []byte(event.Payload))
+		cancel()
+		if err == nil {
+			result.Delivered = true
+			outboxPublished.WithLabelValues("delivered").Inc()
+			continue
+		}
+
+		result.Attempts = event.Attempts + 1
+		result.Error = err.Error()
+		if len(result.Error) > 1000 {
+			result.Error = result.Error[:1000]
+		}
+		if r.cfg.MaxAttempts > 0 && result.Attempts >= r.cfg.MaxAttempts {
+			result.Dead = true
+			outboxPublished.WithLabelValues("dead").Inc()
+			log.Printf("Giving up on outbox event %d (%s, key %s) after %d attempts: %v",
+				event.ID, event.EventType, event.Key, result.Attempts, err)
+			continue
+		}
+
+		blocked[event.Key] = true
+		result.NextAttemptAt = time.Now().Add(r.cfg.Backoff.Base + r.cfg.Backoff.Delay(event.Attempts))
+		outboxPublished.WithLabelValues("failed").Inc()
+		log.Printf("Failed to publish outbox event %d (%s, key %s, attempt %d): %v",
+			event.ID, event.EventType, event.Key, result.Attempts, err)
+	}
+	return results
+}
+
+// record stores the outcome of a published batch
+func (r *OutboxRelay) record(ctx context.Context, results []outboxResult) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, result := range results {
+			var updates map[string]interface{}
+			switch {
+			case result.Delivered:
+				updates = map[string]interface{}{"delivered_at": now}
+			case result.Dead:
+				updates = map[string]interface{}{"attempts": result.Attempts, "last_error": result.Error, "dead_at": now}
+			case result.Attempted:
+				updates = map[string]interface{}{"attempts": result.Attempts, "last_error": result.Error, "next_attempt_at": result.NextAttemptAt}
+			default:
+				updates = map[string]interface{}{"next_attempt_at": result.NextAttemptAt}
+			}
+			if err := tx.Model(&models.OutboxEvent{ID: result.ID}).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *OutboxRelay) updateLag(ctx context.Context) {
+	var oldest models.OutboxEvent
+	err := r.db.WithContext(ctx).Where("delivered_at IS NULL AND dead_at IS NULL").Order("id").Limit(1).Find(&oldest).Error
+	if err != nil {
+		return
+	}
+	if oldest.ID == 0 {
+		outboxLag.Set(0)
+		return
+	}
+	outboxLag.Set(time.Since(oldest.CreatedAt).Seconds())
+}
+
+// cleanup deletes delivered events older than the retention period
+func (r *OutboxRelay) cleanup(ctx context.Context) {
+	result := r.db.WithContext(ctx).
+		Where("delivered_at IS NOT NULL AND delivered_at < ?", time.Now().Add(-r.cfg.Retention)).
+		Delete(&models.OutboxEvent{})
+	if result.Error != nil {
+		log.Printf("Failed to clean up delivered outbox events: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Deleted %d delivered outbox events", result.RowsAffected)
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+	"go_service/internal/resilience"
+	"go_service/internal/testutil"
+
+	"github.com/google/uuid"
+)
+
+// fakePublisher records what it publishes and fails the payloads in fail
+type fakePublisher struct {
+	published []string
+	fail      map[string]bool
+}
+
+func (p *fakePublisher) Publish(_ context.Context, _, _ string, value []byte) error {
+	if p.fail[string(value)] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, string(value))
+	return nil
+}
+
+func outboxEvent(id uint64, key string, attempts int) models.OutboxEvent {
+	return models.OutboxEvent{ID: id, Key: key, Topic: "teams", EventType: "TEAM_UPDATED", Payload: fmt.Sprintf("%s-%d", key, id), Attempts: attempts}
+}
+
+func newTestRelay(publisher Publisher, maxAttempts int) *OutboxRelay {
+	return NewOutboxRelay(nil, publisher, OutboxRelayConfig{
+		Backoff:     resilience.Backoff{Base: time.Second, Max: time.Minute},
+		MaxAttempts: maxAttempts,
+	})
+}
+
+func TestOutboxRelayPublishesEachKeyInOrder(t *testing.T) {
+	publisher := &fakePublisher{}
+	events := []models.OutboxEvent{outboxEvent(1, "team-7", 0), outboxEvent(2, "team-8", 0), outboxEvent(3, "team-7", 0)}
+
+	results := newTestRelay(publisher, 0).publish(context.Background(), events)
+
+	want := []string{"team-7-1", "team-8-2", "team-7-3"}
+	if len(publisher.published) != len(want) {
+		t.Fatalf("published %v, want %v", publisher.published, want)
+	}
+	for i := range want {
+		if publisher.published[i] != want[i] {
+			t.Fatalf("published %v, want %v", publisher.published, want)
+		}
+		if !results[i].Attempted || !results[i].Delivered {
+			t.Errorf("result %d = %+v, want delivered", i, results[i])
+		}
+	}
+}
+
+func TestOutboxRelayHoldsBackFailedKey(t *testing.T) {
+	publisher := &fakePublisher{fail: map[string]bool{"team-7-1": true}}
+	events := []models.OutboxEvent{outboxEvent(1, "team-7", 0), outboxEvent(2, "team-8", 0), outboxEvent(3, "team-7", 0)}
+
+	before := time.Now()
+	results := newTestRelay(publisher, 0).publish(context.Background(), events)
+
+	if len(publisher.published) != 1 || publisher.published[0] != "team-8-2" {
+		t.Fatalf("published %v, want only the other key's event", publisher.published)
+	}
+	failed := results[0]
+	if !failed.Attempted || failed.Delivered || failed.Dead || failed.Attempts != 1 || failed.Error == "" {
+		t.Errorf("failed event result = %+v", failed)
+	}
+	if failed.NextAttemptAt.Before(before.Add(time.Second)) {
+		t.Errorf("failed event retried at %v, want at least the base backoff from now", failed.NextAttemptAt)
+	}
+	if !results[1].Delivered {
+		t.Errorf("other key result = %+v, want delivered", results[1])
+	}
+	held := results[2]
+	if held.Attempted || held.Delivered || held.Attempts != 0 {
+		t.Errorf("held back event result = %+v, want it neither attempted nor charged an attempt", held)
+	}
+}
+
+func TestOutboxRelayBacksOffExponentially(t *testing.T) {
+	publisher := &fakePublisher{fail: map[string]bool{"team-7-1": true}}
+	relay := newTestRelay(publisher, 0)
+
+	for attempts, ceiling := range map[int]time.Duration{0: time.Second, 3: 8 * time.Second, 10: time.Minute} {
+		before := time.Now()
+		result := relay.publish(context.Background(), []models.OutboxEvent{outboxEvent(1, "team-7", attempts)})[0]
+		after := time.Now()
+
+		if result.Attempts != attempts+1 {
+			t.Errorf("after %d attempts: attempts = %d, want %d", attempts, result.Attempts, attempts+1)
+		}
+		earliest, latest := before.Add(time.Second), after.Add(time.Second+ceiling)
+		if result.NextAttemptAt.Before(earliest) || result.NextAttemptAt.After(latest) {
+			t.Errorf("after %d attempts: next attempt %v, want between %v and %v", attempts, result.NextAttemptAt, earliest, latest)
+		}
+	}
+}
+
+func TestOutboxRelayDeadLettersAfterMaxAttempts(t *testing.T) {
+	publisher := &fakePublisher{fail: map[string]bool{"team-7-1": true}}
+	events := []models.OutboxEvent{outboxEvent(1, "team-7", 2), outboxEvent(2, "team-7", 0)}
+
+	results := newTestRelay(publisher, 3).publish(context.Background(), events)
+
+	if !results[0].Dead || results[0].Attempts != 3 {
+		t.Errorf("result = %+v, want dead after 3 attempts", results[0])
+	}
+	// A dead event no longer holds its key back
+	if !results[1].Delivered || len(publisher.published) != 1 || publisher.published[0] != "team-7-2" {
+		t.Errorf("next event of the key: result %+v, published %v", results[1], publisher.published)
+	}
+}
+
+// TestOutboxKeyLockHeldUntilCommit checks that a second writer of a key waits
+// for the first transaction, so the key's IDs follow commit order
+func TestOutboxKeyLockHeldUntilCommit(t *testing.T) {
+	tx := testutil.DB(t)
+	event := kafka.NewTeamEvent(kafka.EventTeamUpdated, 42, uuid.New(), uuid.Nil, nil)
+	if err := AddTeamEvent(tx, event); err != nil {
+		t.Fatal(err)
+	}
+
+	other := testutil.Conn(t).Begin()
+	defer other.Rollback()
+	var locked bool
+	if err := other.Raw("SELECT pg_try_advisory_xact_lock(?, hashtext(?))", outboxKeyLockSpace, event.Key()).Scan(&locked).Error; err != nil {
+		t.Fatal(err)
+	}
+	if locked {
+		t.Error("another transaction took the key's lock while an event for it was uncommitted")
+	}
+}
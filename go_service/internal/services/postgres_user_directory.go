@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"unicode"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// bcryptCost matches bcrypt.genSalt(10) in the Node user-service, so either
+// service can verify passwords written by the other
+const bcryptCost = 10
+
+// PostgresUserDirectory reads and writes the shared Users table directly, so
+// the service can run without the Node user-service
+type PostgresUserDirectory struct {
+	db *gorm.DB
+}
+
+var _ UserDirectory = (*PostgresUserDirectory)(nil)
+
+// NewPostgresUserDirectory creates a UserDirectory backed by db
+func NewPostgresUserDirectory(db *gorm.DB) *PostgresUserDirectory {
+	return &PostgresUserDirectory{db: db}
+}
+
+func (d *PostgresUserDirectory) GetUserByID(ctx context.Context, userID string) (*User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w with ID: %s", ErrUserNotFound, userID)
+	}
+
+	var row models.User
+	if err := d.db.WithContext(ctx).First(&row, "\"userId\" = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w with ID: %s", ErrUserNotFound, userID)
+		}
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	return userFromRow(row), nil
+}
+
+func (d *PostgresUserDirectory) GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]*User, error) {
+	ids := make([]uuid.UUID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		// Malformed IDs cannot exist, so they are simply left out
+		if id, err := uuid.Parse(userID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	users := make(map[string]*User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	var rows []models.User
+	if err := d.db.WithContext(ctx).Where("\"userId\" IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+
+	for _, row := range rows {
+		user := userFromRow(row)
+		users[user.ID] = user
+	}
+
+	return users, nil
+}
+
+// CreateUser applies the same validation as the Node model before inserting
+func (d *PostgresUserDirectory) CreateUser(ctx context.Context, username, email, password, role string) (*User, error) {
+	role = strings.ToUpper(role)
+	if role != "MANAGER" && role != "MEMBER" {
+		return nil, fmt.Errorf("%w: role must be MANAGER or MEMBER", ErrUserRejected)
+	}
+	if strings.TrimSpace(username) == "" {
+		return nil, fmt.Errorf("%w: username is required", ErrUserRejected)
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, fmt.Errorf("%w: invalid email", ErrUserRejected)
+	}
+	if !strongPassword(password) {
+		return nil, fmt.Errorf("%w: Password should have 1 lowercase letter, 1 uppercase letter, 1 number, 1 special character and be at least 8 characters long", ErrUserRejected)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	row := models.User{
+		ID:       uuid.New(),
+		Username: username,
+		Email:    email,
+		Password: string(hash),
+		Role:     role,
+	}
+
+	db := d.db.WithContext(ctx)
+
+	var existing int64
+	if err := db.Model(&models.User{}).Where("email = ?", email).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("%w: email must be unique", ErrUserRejected)
+	}
+
+	if err := db.Create(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("%w: email must be unique", ErrUserRejected)
+		}
+		log.Printf("Failed to insert user %s: %v", email, err)
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return userFromRow(row), nil
+}
+
+// strongPassword mirrors the Node model's password rule
+func strongPassword(password string) bool {
+	var lower, upper, digit, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case strings.ContainsRune("!@#$%^&*()\\[]{}-_+=~`|:;\"'<>,./?", r):
+			special = true
+		}
+	}
+	return len(password) >= 8 && lower && upper && digit && special
+}
+
+func userFromRow(row models.User) *User {
+	return &User{
+		ID:       row.ID.String(),
+		Username: row.Username,
+		Email:    row.Email,
+		Role:     row.Role,
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go_service/internal/resilience"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrUserDirectoryUnavailable marks calls that failed fast without reaching
+// the user service, or that failed after every retry
+var ErrUserDirectoryUnavailable = errors.New("user directory unavailable")
+
+// ResilienceConfig tunes the retry, circuit breaker and bulkhead around a UserDirectory
+type ResilienceConfig struct {
+	// Retries is how many times a failed query is retried; mutations are never retried
+	Retries     int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// FailureThreshold consecutive failures open the breaker for BreakerCooldown
+	FailureThreshold int
+	BreakerCooldown  time.Duration
+	// MaxConcurrent caps in-flight calls; extra callers wait up to MaxQueueWait
+	MaxConcurrent int
+	MaxQueueWait  time.Duration
+}
+
+// ResilienceConfigFromEnv reads the USER_SERVICE_* resilience settings
+func ResilienceConfigFromEnv() ResilienceConfig {
+	cfg := ResilienceConfig{
+		Retries:          2,
+		BaseBackoff:      100 * time.Millisecond,
+		MaxBackoff:       time.Second,
+		FailureThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+		MaxConcurrent:    20,
+		MaxQueueWait:     500 * time.Millisecond,
+	}
+
+	cfg.Retries = envInt("USER_SERVICE_RETRIES", cfg.Retries)
+	cfg.BaseBackoff = envDuration("USER_SERVICE_RETRY_BACKOFF", cfg.BaseBackoff)
+	cfg.MaxBackoff = envDuration("USER_SERVICE_RETRY_MAX_BACKOFF", cfg.MaxBackoff)
+	cfg.FailureThreshold = envInt("USER_SERVICE_BREAKER_THRESHOLD", cfg.FailureThreshold)
+	cfg.BreakerCooldown = envDuration("USER_SERVICE_BREAKER_COOLDOWN", cfg.BreakerCooldown)
+	cfg.MaxConcurrent = envInt("USER_SERVICE_MAX_CONCURRENT", cfg.MaxConcurrent)
+	cfg.MaxQueueWait = envDuration("USER_SERVICE_QUEUE_WAIT", cfg.MaxQueueWait)
+
+	return cfg
+}
+
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s %q, using %d", name, v, def)
+		return def
+	}
+	return n
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("Invalid %s %q, using %s", name, v, def)
+		return def
+	}
+	return d
+}
+
+var (
+	directoryCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_directory_calls_total",
+		Help: "User directory calls by method and outcome (success, rejected, error, circuit_open, bulkhead_full)",
+	}, []string{"method", "outcome"})
+	directoryRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_directory_retries_total",
+		Help: "User directory retry attempts by method",
+	}, []string{"method"})
+	directoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "user_directory_call_duration_seconds",
+		Help:    "User directory call latency including retries",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	directoryInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "user_directory_in_flight",
+		Help: "User directory calls currently holding a bulkhead slot",
+	})
+	directoryBreakerState = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "user_directory_breaker_state",
+		Help: "User directory circuit breaker state (0 closed, 1 half-open, 2 open)",
+	})
+	directoryBreakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_directory_breaker_transitions_total",
+		Help: "User directory circuit breaker transitions by target state",
+	}, []string{"state"})
+)
+
+func init() {
+	prometheus.MustRegister(directoryCalls, directoryRetries, directoryDuration,
+		directoryInFlight, directoryBreakerState, directoryBreakerTransitions)
+}
+
+// ResilientUserDirectory decorates a UserDirectory with retries, a circuit
+// breaker and a bulkhead so a slow user service cannot stall every request
+type ResilientUserDirectory struct {
+	next     UserDirectory
+	cfg      ResilienceConfig
+	breaker  *resilience.Breaker
+	bulkhead *resilience.Bulkhead
+}
+
+var _ UserDirectory = (*ResilientUserDirectory)(nil)
+
+// NewResilientUserDirectory wraps next
+func NewResilientUserDirectory(next UserDirectory, cfg ResilienceConfig) *ResilientUserDirectory {
+	breaker := resilience.NewBreaker(cfg.FailureThreshold, cfg.BreakerCooldown, func(from, to resilience.State) {
+		log.Printf("User directory circuit breaker %s -> %s", from, to)
+		directoryBreakerState.Set(float64(to))
+		directoryBreakerTransitions.WithLabelValues(to.String()).Inc()
+	})
+
+	return &ResilientUserDirectory{
+		next:     next,
+		cfg:      cfg,
+		breaker:  breaker,
+		bulkhead: resilience.NewBulkhead(cfg.MaxConcurrent, cfg.MaxQueueWait),
+	}
+}
+
+// BreakerState exposes the breaker state, e.g. for health checks
+func (d *ResilientUserDirectory) BreakerState() resilience.State {
+	return d.breaker.State()
+}
+
+func (d *ResilientUserDirectory) GetUserByID(ctx context.Context, userID string) (*User, error) {
+	var user *User
+	err := d.call(ctx, "GetUserByID", true, func() error {
+		var err error
+		user, err = d.next.GetUserByID(ctx, userID)
+		return err
+	})
+	return user, err
+}
+
+func (d *ResilientUserDirectory) GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]*User, error) {
+	var users map[string]*User
+	err := d.call(ctx, "GetUsersByIDs", true, func() error {
+		var err error
+		users, err = d.next.GetUsersByIDs(ctx, userIDs)
+		return err
+	})
+	return users, err
+}
+
+func (d *ResilientUserDirectory) CreateUser(ctx context.Context, username, email, password, role string) (*User, error) {
+	var user *User
+	err := d.call(ctx, "CreateUser", false, func() error {
+		var err error
+		user, err = d.next.CreateUser(ctx, username, email, password, role)
+		return err
+	})
+	return user, err
+}
+
+// call runs fn inside the bulkhead and breaker. Each attempt is reported to
+// the breaker separately so retries against a dead service open it sooner.
+func (d *ResilientUserDirectory) call(ctx context.Context, method string, idempotent bool, fn func() error) error {
+	start := time.Now()
+	defer func() { directoryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds()) }()
+
+	// Fail fast rather than queue for a slot we cannot use
+	if d.breaker.State() == resilience.StateOpen {
+		directoryCalls.WithLabelValues(method, "circuit_open").Inc()
+		return fmt.Errorf("%w: %w", ErrUserDirectoryUnavailable, resilience.ErrCircuitOpen)
+	}
+
+	release, err := d.bulkhead.Acquire(ctx)
+	if err != nil {
+		directoryCalls.WithLabelValues(method, "bulkhead_full").Inc()
+		return fmt.Errorf("%w: %w", ErrUserDirectoryUnavailable, err)
+	}
+	directoryInFlight.Inc()
+	defer func() {
+		directoryInFlight.Dec()
+		release()
+	}()
+
+	retries := 0
+	if idempotent {
+		retries = d.cfg.Retries
+	}
+
+	backoff := resilience.Backoff{Base: d.cfg.BaseBackoff, Max: d.cfg.MaxBackoff}
+	err = resilience.Retry(ctx, retries, backoff, retryable, func(attempt int, err error) {
+		directoryRetries.WithLabelValues(method).Inc()
+		log.Printf("Retrying user directory %s (attempt %d): %v", method, attempt, err)
+	}, func() error {
+		if err := d.breaker.Allow(); err != nil {
+			return err
+		}
+		err := fn()
+		d.breaker.Done(err == nil || isAnswer(err))
+		return err
+	})
+
+	switch {
+	case err == nil:
+		directoryCalls.WithLabelValues(method, "success").Inc()
+		return nil
+	case isAnswer(err):
+		directoryCalls.WithLabelValues(method, "rejected").Inc()
+		return err
+	case errors.Is(err, resilience.ErrCircuitOpen):
+		directoryCalls.WithLabelValues(method, "circuit_open").Inc()
+	default:
+		directoryCalls.WithLabelValues(method, "error").Inc()
+	}
+	return fmt.Errorf("%w: %w", ErrUserDirectoryUnavailable, err)
+}
+
+// isAnswer reports whether err is a definite reply from a healthy service
+func isAnswer(err error) bool {
+	return errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrUserRejected)
+}
+
+// retryable skips failures that another attempt would not change
+func retryable(err error) bool {
+	return !isAnswer(err) &&
+		!errors.Is(err, resilience.ErrCircuitOpen) &&
+		!errors.Is(err, context.Canceled)
+}
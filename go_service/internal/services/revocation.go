@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go_service/internal/auth"
+	"go_service/internal/cache"
+	"go_service/internal/redisclient"
+)
+
+// ErrTokenRevoked is returned for tokens that were revoked before they expired
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// RevocationConfig controls how revocation decisions are cached
+type RevocationConfig struct {
+	// Capacity is the number of cached decisions per kind
+	Capacity int
+	// DecisionTTL is how long a decision is reused before asking Redis again
+	DecisionTTL time.Duration
+	// DefaultTokenTTL bounds revocation entries for tokens without exp
+	DefaultTokenTTL time.Duration
+	// FailClosed rejects tokens when Redis cannot be reached
+	FailClosed bool
+}
+
+// RevocationConfigFromEnv builds a RevocationConfig from environment variables
+func RevocationConfigFromEnv() RevocationConfig {
+	cfg := RevocationConfig{
+		Capacity:        10000,
+		DecisionTTL:     30 * time.Second,
+		DefaultTokenTTL: 24 * time.Hour,
+		FailClosed:      os.Getenv("REVOCATION_FAIL_CLOSED") == "true",
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("REVOCATION_CACHE_SIZE")); err == nil && v > 0 {
+		cfg.Capacity = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("REVOCATION_CACHE_TTL")); err == nil && v >= 0 {
+		cfg.DecisionTTL = v
+	}
+
+	return cfg
+}
+
+// RevocationService enforces jti revocations and per-user "issued before"
+// watermarks, caching decisions in process
+type RevocationService struct {
+	store      *redisclient.RevocationStore
+	tokens     *cache.LRU[string, bool]
+	watermarks *cache.LRU[string, time.Time]
+	cfg        RevocationConfig
+}
+
+// NewRevocationService creates a revocation service backed by store
+func NewRevocationService(store *redisclient.RevocationStore, cfg RevocationConfig) *RevocationService {
+	return &RevocationService{
+		store:      store,
+		tokens:     cache.NewLRU[string, bool](cfg.Capacity),
+		watermarks: cache.NewLRU[string, time.Time](cfg.Capacity),
+		cfg:        cfg,
+	}
+}
+
+// Check returns ErrTokenRevoked if the token's jti was revoked or it was
+// issued before the user's watermark
+func (s *RevocationService) Check(ctx context.Context, claims *auth.Claims) error {
+	if claims.ID != "" {
+		revoked, err := s.isTokenRevoked(ctx, claims.ID)
+		if err != nil {
+			return s.onStoreError(err)
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	return s.CheckIssuedAt(ctx, claims.UserID.String(), issuedAt)
+}
+
+// CheckIssuedAt returns ErrTokenRevoked if a credential of userID issued at
+// issuedAt falls before the user's watermark. A zero issuedAt is treated as
+// revoked once any watermark is set.
+func (s *RevocationService) CheckIssuedAt(ctx context.Context, userID string, issuedAt time.Time) error {
+	watermark, err := s.userWatermark(ctx, userID)
+	if err != nil {
+		return s.onStoreError(err)
+	}
+	if revokedBy(issuedAt, watermark) {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// revokedBy reports whether a credential issued at issuedAt predates
+// watermark. A JWT's iat has whole seconds, so such a time is compared with
+// the watermark's second: a token from that second cannot be told apart from
+// a fresh login straight after the revocation, and is kept rather than
+// locking the user out until the next second.
+func revokedBy(issuedAt, watermark time.Time) bool {
+	if watermark.IsZero() {
+		return false
+	}
+	if issuedAt.Equal(issuedAt.Truncate(time.Second)) {
+		watermark = watermark.Truncate(time.Second)
+	}
+	return issuedAt.Before(watermark)
+}
+
+// RevokeToken revokes a single token by jti until expiresAt
+func (s *RevocationService) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := s.cfg.DefaultTokenTTL
+	if !expiresAt.IsZero() {
+		ttl = time.Until(expiresAt)
+		if ttl <= 0 {
+			// Already expired, nothing to enforce
+			return nil
+		}
+	}
+
+	if err := s.store.RevokeToken(ctx, jti, ttl); err != nil {
+		return err
+	}
+	s.tokens.Set(jti, true, time.Now())
+	return nil
+}
+
+// RevokeUserTokens invalidates every token issued to userID before the given time
+func (s *RevocationService) RevokeUserTokens(ctx context.Context, userID string, before time.Time) error {
+	if err := s.store.SetUserWatermark(ctx, userID, before); err != nil {
+		return err
+	}
+	s.watermarks.Delete(userID)
+	return nil
+}
+
+// Listen drops cached decisions for revocations made on other instances
+func (s *RevocationService) Listen(ctx context.Context) {
+	s.store.SubscribeRevocations(ctx,
+		func(jti string) { s.tokens.Delete(jti) },
+		func(userID string) { s.watermarks.Delete(userID) },
+	)
+}
+
+func (s *RevocationService) isTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if revoked, checkedAt, ok := s.tokens.Get(jti); ok && (revoked || time.Since(checkedAt) < s.cfg.DecisionTTL) {
+		return revoked, nil
+	}
+
+	revoked, err := s.store.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+	s.tokens.Set(jti, revoked, time.Now())
+	return revoked, nil
+}
+
+func (s *RevocationService) userWatermark(ctx context.Context, userID string) (time.Time, error) {
+	if watermark, checkedAt, ok := s.watermarks.Get(userID); ok && time.Since(checkedAt) < s.cfg.DecisionTTL {
+		return watermark, nil
+	}
+
+	watermark, err := s.store.GetUserWatermark(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	s.watermarks.Set(userID, watermark, time.Now())
+	return watermark, nil
+}
+
+func (s *RevocationService) onStoreError(err error) error {
+	if s.cfg.FailClosed {
+		return err
+	}
+	log.Printf("Revocation check skipped, store unavailable: %v", err)
+	return nil
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevokedBy(t *testing.T) {
+	watermark := time.Date(2024, 3, 1, 12, 0, 0, 700e6, time.UTC)
+
+	tests := []struct {
+		name      string
+		issuedAt  time.Time
+		watermark time.Time
+		want      bool
+	}{
+		{"no watermark", watermark.Add(-time.Hour), time.Time{}, false},
+		{"token from an earlier second", watermark.Add(-time.Second).Truncate(time.Second), watermark, true},
+		{"re-login in the watermark's second", watermark.Truncate(time.Second), watermark, false},
+		{"token from a later second", watermark.Add(time.Second).Truncate(time.Second), watermark, false},
+		{"API token just before", watermark.Add(-time.Microsecond), watermark, true},
+		{"API token just after", watermark.Add(time.Microsecond), watermark, false},
+		{"missing issue time", time.Time{}, watermark, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := revokedBy(tt.issuedAt, tt.watermark); got != tt.want {
+				t.Errorf("revokedBy(%s, %s) = %t, want %t", tt.issuedAt, tt.watermark, got, tt.want)
+			}
+		})
+	}
+}
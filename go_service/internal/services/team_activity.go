@@ -0,0 +1,40 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// TeamActivityFromEvent converts a consumed team event into an activity feed
+// entry. Events with an unreadable timestamp are dated now.
+func TeamActivityFromEvent(event kafka.TeamEvent) *models.TeamActivity {
+	activity := &models.TeamActivity{
+		EventType:      event.EventType,
+		TeamID:         int(event.TeamID),
+		PerformedBy:    event.PerformedBy,
+		ImpersonatedBy: event.ImpersonatedBy,
+		Details:        event.Details,
+	}
+	if event.EventID != "" {
+		eventID := event.EventID
+		activity.EventID = &eventID
+	}
+	if event.TargetUserID != uuid.Nil {
+		targetUserID := event.TargetUserID
+		activity.TargetUserID = &targetUserID
+	}
+
+	occurredAt, err := time.Parse(time.RFC3339, event.Timestamp)
+	if err != nil {
+		log.Printf("Event %s has invalid timestamp %q, dating it now", event.EventID, event.Timestamp)
+		occurredAt = time.Now().UTC()
+	}
+	activity.OccurredAt = occurredAt
+
+	return activity
+}
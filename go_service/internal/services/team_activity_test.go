@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go_service/internal/kafka"
+
+	"github.com/google/uuid"
+)
+
+func TestTeamActivityFromEvent(t *testing.T) {
+	performer, target, manager := uuid.New(), uuid.New(), uuid.New()
+	event := kafka.TeamEvent{
+		EventID:        "evt-1",
+		EventType:      kafka.EventMemberAdded,
+		TeamID:         7,
+		PerformedBy:    performer,
+		TargetUserID:   target,
+		ImpersonatedBy: &manager,
+		Timestamp:      "2024-03-01T10:00:00.250Z",
+		Details:        map[string]string{"role": "member"},
+	}
+
+	activity := TeamActivityFromEvent(event)
+	if activity.EventID == nil || *activity.EventID != "evt-1" {
+		t.Errorf("EventID = %v, want evt-1", activity.EventID)
+	}
+	if activity.TeamID != 7 || activity.PerformedBy != performer || activity.Details["role"] != "member" {
+		t.Errorf("activity = %+v, want the event's team, performer and details", activity)
+	}
+	if activity.TargetUserID == nil || *activity.TargetUserID != target {
+		t.Errorf("TargetUserID = %v, want %s", activity.TargetUserID, target)
+	}
+	if activity.ImpersonatedBy == nil || *activity.ImpersonatedBy != manager {
+		t.Errorf("ImpersonatedBy = %v, want %s", activity.ImpersonatedBy, manager)
+	}
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 250e6, time.UTC); !activity.OccurredAt.Equal(want) {
+		t.Errorf("OccurredAt = %s, want %s", activity.OccurredAt, want)
+	}
+
+	// Events from older producers carry neither an ID nor a target
+	bare := TeamActivityFromEvent(kafka.TeamEvent{EventType: kafka.EventTeamCreated, TeamID: 7, Timestamp: "not a time"})
+	if bare.EventID != nil || bare.TargetUserID != nil || bare.ImpersonatedBy != nil {
+		t.Errorf("bare activity = %+v, want no event ID, target or impersonator", bare)
+	}
+	if time.Since(bare.OccurredAt) > time.Minute {
+		t.Errorf("OccurredAt = %s, want about now for an unreadable timestamp", bare.OccurredAt)
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The built-in columns of the team CSV; every other column is a custom field key
+const (
+	teamColumnID         = "teamId"
+	teamColumnName       = "teamName"
+	teamColumnJoinPolicy = "joinPolicy"
+	teamColumnLabels     = "labels"
+)
+
+const (
+	// maxTeamImportRows caps the size of one team import
+	maxTeamImportRows = 1000
+	// maxTeamNameLength matches the limit on renaming a team
+	maxTeamNameLength = 150
+)
+
+// ErrInvalidTeamCSV is returned when a team CSV cannot be imported at all
+var ErrInvalidTeamCSV = errors.New("invalid team CSV")
+
+// TeamImportStatus is the outcome of one row of a team import
+type TeamImportStatus string
+
+const (
+	TeamImportCreated TeamImportStatus = "created"
+	TeamImportUpdated TeamImportStatus = "updated"
+	TeamImportFailed  TeamImportStatus = "failed"
+)
+
+// TeamImportResult reports what happened to one row of a team import
+type TeamImportResult struct {
+	// Row is the line of the CSV, the header being line 1
+	Row      int              `json:"row"`
+	TeamID   int              `json:"teamId,omitempty"`
+	TeamName string           `json:"teamName"`
+	Status   TeamImportStatus `json:"status"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// escapedCellPrefixes are the first characters escapeCSVCell guards
+const escapedCellPrefixes = "=+-@\t\r'"
+
+// escapeCSVCell keeps a spreadsheet from running a cell as a formula by
+// prefixing "'" to one that starts like a formula. Cells already starting
+// with "'" get one too, so unescapeCSVCell can always strip it again.
+func escapeCSVCell(value string) string {
+	if value != "" && strings.ContainsRune(escapedCellPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeCSVCell undoes escapeCSVCell
+func unescapeCSVCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(escapedCellPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// ExportTeams writes the active teams as CSV: teamId, teamName, joinPolicy,
+// labels (separated by ";") and one column per custom field. Cells that a
+// spreadsheet would read as a formula are escaped.
+func (s *TeamService) ExportTeams(w io.Writer) error {
+	definitions, err := s.repo.ListFieldDefinitions()
+	if err != nil {
+		return err
+	}
+
+	out := csv.NewWriter(w)
+	header := []string{teamColumnID, teamColumnName, teamColumnJoinPolicy, teamColumnLabels}
+	for _, def := range definitions {
+		header = append(header, def.Key)
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	var teams []models.Team
+	err = s.db.Where("\"archivedAt\" IS NULL").
+		FindInBatches(&teams, 500, func(tx *gorm.DB, batch int) error {
+			teamIDs := make([]int, len(teams))
+			for i, team := range teams {
+				teamIDs[i] = team.ID
+			}
+			metadata, err := s.repo.Metadata(teamIDs)
+			if err != nil {
+				return err
+			}
+			for _, team := range teams {
+				record := []string{
+					strconv.Itoa(team.ID),
+					team.TeamName,
+					string(team.JoinPolicy),
+					strings.Join(metadata[team.ID].Labels, labelCSVSeparator),
+				}
+				for _, def := range definitions {
+					record = append(record, metadata[team.ID].Fields[def.Key])
+				}
+				for i := range record {
+					record[i] = escapeCSVCell(record[i])
+				}
+				if err := out.Write(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// ImportTeams creates and updates teams from a CSV laid out like the export.
+// Rows are matched on teamId when given, otherwise on teamName; unmatched rows
+// create a team owned by performedBy. Only the columns present are changed: an
+// empty joinPolicy is left alone, while an empty labels or field cell clears
+// it. Each row is applied in a transaction of its own, so a bad row fails
+// without touching its team; a bad header or too many rows fails the whole
+// import before any row is applied.
+func (s *TeamService) ImportTeams(r io.Reader, performedBy uuid.UUID) ([]TeamImportResult, error) {
+	definitions, err := s.fieldsByKey()
+	if err != nil {
+		return nil, err
+	}
+
+	in := csv.NewReader(r)
+	in.TrimLeadingSpace = true
+	header, err := in.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidTeamCSV, err)
+	}
+	// Every record must have as many cells as the header
+	in.FieldsPerRecord = len(header)
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		switch column {
+		case teamColumnID, teamColumnName, teamColumnJoinPolicy, teamColumnLabels:
+		default:
+			if definitions[column] == nil {
+				return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidTeamCSV, column)
+			}
+		}
+		if _, dup := columns[column]; dup {
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidTeamCSV, column)
+		}
+		columns[column] = i
+	}
+	if _, ok := columns[teamColumnName]; !ok {
+		return nil, fmt.Errorf("%w: header must contain %s", ErrInvalidTeamCSV, teamColumnName)
+	}
+
+	// Read every row before applying any, so an oversized file changes nothing
+	type row struct {
+		record []string
+		err    error
+	}
+	var rows []row
+	for {
+		record, err := in.Read()
+		if err == io.EOF {
+			break
+		}
+		if len(rows) == maxTeamImportRows {
+			return nil, fmt.Errorf("%w: at most %d teams per import", ErrInvalidTeamCSV, maxTeamImportRows)
+		}
+		rows = append(rows, row{record: record, err: err})
+	}
+
+	results := make([]TeamImportResult, len(rows))
+	for i, row := range rows {
+		// Line 1 is the header
+		results[i] = TeamImportResult{Row: i + 2}
+		err := row.err
+		if err == nil {
+			err = s.importTeam(&results[i], columns, definitions, row.record, performedBy)
+		}
+		if err != nil {
+			// A team the row created was rolled back with it
+			if results[i].Status == TeamImportCreated {
+				results[i].TeamID = 0
+			}
+			results[i].Status, results[i].Error = TeamImportFailed, err.Error()
+		}
+	}
+	return results, nil
+}
+
+// importTeam applies one CSV record in a transaction, filling in result
+func (s *TeamService) importTeam(result *TeamImportResult, columns map[string]int, definitions map[string]*models.TeamFieldDefinition, record []string, performedBy uuid.UUID) error {
+	cell := func(column string) (string, bool) {
+		i, ok := columns[column]
+		if !ok {
+			return "", false
+		}
+		return unescapeCSVCell(strings.TrimSpace(record[i])), true
+	}
+
+	result.TeamName, _ = cell(teamColumnName)
+	if result.TeamName == "" || utf8.RuneCountInString(result.TeamName) > maxTeamNameLength {
+		return fmt.Errorf("teamName must be 1 to %d characters", maxTeamNameLength)
+	}
+
+	var update TeamUpdate
+	if policy, _ := cell(teamColumnJoinPolicy); policy != "" {
+		joinPolicy := models.JoinPolicy(policy)
+		if !joinPolicy.Valid() {
+			return fmt.Errorf("invalid joinPolicy %q", policy)
+		}
+		update.JoinPolicy = &joinPolicy
+	}
+	metadata := MetadataUpdate{Fields: map[string]*string{}}
+	if labels, ok := cell(teamColumnLabels); ok {
+		split := strings.Split(labels, labelCSVSeparator)
+		metadata.Labels = &split
+	}
+	for key := range definitions {
+		if value, ok := cell(key); ok {
+			if value == "" {
+				metadata.Fields[key] = nil
+			} else {
+				metadata.Fields[key] = &value
+			}
+		}
+	}
+	change, err := prepareMetadata(definitions, metadata)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		scoped := s.withTx(tx)
+		team, err := scoped.matchImportedTeam(result, cell)
+		if err != nil {
+			return err
+		}
+		if team == nil {
+			if team, _, err = scoped.CreateTeam(result.TeamName, nil, performedBy); err != nil {
+				return err
+			}
+			result.Status = TeamImportCreated
+		} else {
+			if team.TeamName != result.TeamName {
+				update.TeamName = &result.TeamName
+			}
+			result.Status = TeamImportUpdated
+		}
+		result.TeamID = team.ID
+
+		if _, err := scoped.UpdateTeam(team.ID, update, performedBy); err != nil {
+			return err
+		}
+		_, err = scoped.applyMetadata(team.ID, change, performedBy)
+		return err
+	})
+}
+
+// matchImportedTeam finds the team a CSV record refers to, nil when the
+// record names a new team
+func (s *TeamService) matchImportedTeam(result *TeamImportResult, cell func(string) (string, bool)) (*models.Team, error) {
+	var team *models.Team
+	var err error
+	if id, _ := cell(teamColumnID); id != "" {
+		teamID, convErr := strconv.Atoi(id)
+		if convErr != nil {
+			return nil, fmt.Errorf("invalid teamId %q", id)
+		}
+		if team, err = s.repo.FindTeam(teamID); errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("team %d not found", teamID)
+		}
+	} else if team, err = s.repo.FindTeamByName(result.TeamName); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if team.ArchivedAt != nil {
+		return nil, ErrTeamArchived
+	}
+	return team, nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import "testing"
+
+func TestEscapeCSVCell(t *testing.T) {
+	tests := []struct {
+		value, escaped string
+	}{
+		{"Payments", "Payments"},
+		{"", ""},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1", "'+1"},
+		{"-3.5", "'-3.5"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"'quoted", "''quoted"},
+		{"it's fine", "it's fine"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeCSVCell(tt.value); got != tt.escaped {
+			t.Errorf("escapeCSVCell(%q) = %q, want %q", tt.value, got, tt.escaped)
+		}
+		if got := unescapeCSVCell(tt.escaped); got != tt.value {
+			t.Errorf("unescapeCSVCell(%q) = %q, want %q", tt.escaped, got, tt.value)
+		}
+	}
+
+	// A hand-written value with a lone apostrophe is left alone
+	if got := unescapeCSVCell("'Ops"); got != "'Ops" {
+		t.Errorf("unescapeCSVCell(%q) = %q, want it unchanged", "'Ops", got)
+	}
+}
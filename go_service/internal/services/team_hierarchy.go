@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+	"go_service/internal/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// teamHierarchyLockID serializes changes to team parents so two concurrent
+// moves cannot combine into a cycle
+const teamHierarchyLockID = 7_301_416
+
+var (
+	// ErrTeamCycle is returned when a team would end up below itself
+	ErrTeamCycle = errors.New("team cannot be placed under itself or one of its sub-teams")
+	// ErrTeamNotAttached is returned when detaching a team that has no parent
+	ErrTeamNotAttached = errors.New("team has no parent team")
+)
+
+// TeamTree is a team with its sub-teams nested below it
+type TeamTree struct {
+	repositories.TeamNode
+	Children []*TeamTree `json:"children"`
+}
+
+// AttachTeam places a team under parentID, emitting TEAM_PARENT_CHANGED
+func (s *TeamService) AttachTeam(teamID, parentID int, performedBy uuid.UUID) (*models.Team, error) {
+	if teamID == parentID {
+		return nil, ErrTeamCycle
+	}
+
+	var team *models.Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", teamHierarchyLockID).Error; err != nil {
+			return err
+		}
+		repo := s.repo.WithTx(tx)
+
+		var err error
+		team, err = repo.FindTeam(teamID)
+		if err != nil {
+			return err
+		}
+		parent, err := repo.FindTeam(parentID)
+		if err != nil {
+			return err
+		}
+		if team.ArchivedAt != nil || parent.ArchivedAt != nil {
+			return ErrTeamArchived
+		}
+		if team.ParentTeamID != nil && *team.ParentTeamID == parentID {
+			return nil
+		}
+
+		below, err := repo.SubtreeIDs(teamID)
+		if err != nil {
+			return err
+		}
+		for _, id := range below {
+			if id == parentID {
+				return ErrTeamCycle
+			}
+		}
+
+		previous := team.ParentTeamID
+		if err := repo.SetParent(team, &parentID); err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventTeamParentChanged, team.ID, performedBy, uuid.Nil, parentDetails(previous, team.ParentTeamID))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+// DetachTeam moves a team back to the top level
+func (s *TeamService) DetachTeam(teamID int, performedBy uuid.UUID) (*models.Team, error) {
+	var team *models.Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", teamHierarchyLockID).Error; err != nil {
+			return err
+		}
+		repo := s.repo.WithTx(tx)
+
+		var err error
+		team, err = repo.FindTeam(teamID)
+		if err != nil {
+			return err
+		}
+		if team.ParentTeamID == nil {
+			return ErrTeamNotAttached
+		}
+
+		previous := team.ParentTeamID
+		if err := repo.SetParent(team, nil); err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventTeamParentChanged, team.ID, performedBy, uuid.Nil, parentDetails(previous, nil))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+// Subtree returns rootID with its sub-teams nested below it
+func (s *TeamService) Subtree(rootID int, includeArchived bool) (*TeamTree, error) {
+	nodes, err := s.repo.Subtree(rootID, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return buildTeamTree(nodes), nil
+}
+
+// SubtreeTeamIDs returns rootID and the IDs of every team below it
+func (s *TeamService) SubtreeTeamIDs(rootID int) ([]int, error) {
+	return s.repo.SubtreeIDs(rootID)
+}
+
+// buildTeamTree nests nodes under their parents; nodes must start with the
+// root and list every parent before its children
+func buildTeamTree(nodes []repositories.TeamNode) *TeamTree {
+	byID := make(map[int]*TeamTree, len(nodes))
+	root := &TeamTree{TeamNode: nodes[0], Children: []*TeamTree{}}
+	byID[root.ID] = root
+
+	for _, node := range nodes[1:] {
+		tree := &TeamTree{TeamNode: node, Children: []*TeamTree{}}
+		byID[node.ID] = tree
+		if node.ParentTeamID == nil {
+			continue
+		}
+		if parent, ok := byID[*node.ParentTeamID]; ok {
+			parent.Children = append(parent.Children, tree)
+		}
+	}
+	return root
+}
+
+func parentDetails(previous, current *int) map[string]string {
+	details := map[string]string{}
+	if previous != nil {
+		details["previousParentTeamId"] = strconv.Itoa(*previous)
+	}
+	if current != nil {
+		details["parentTeamId"] = strconv.Itoa(*current)
+	}
+	return details
+}
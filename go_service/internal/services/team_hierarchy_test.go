@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+
+	"go_service/internal/repositories"
+)
+
+func TestBuildTeamTree(t *testing.T) {
+	parent := func(id int) *int { return &id }
+	nodes := []repositories.TeamNode{
+		{ID: 1, TeamName: "Engineering", ParentTeamID: parent(10), Depth: 0},
+		{ID: 2, TeamName: "Platform", ParentTeamID: parent(1), Depth: 1},
+		{ID: 3, TeamName: "Web", ParentTeamID: parent(1), Depth: 1},
+		{ID: 4, TeamName: "Databases", ParentTeamID: parent(2), Depth: 2},
+	}
+
+	root := buildTeamTree(nodes)
+	if root.ID != 1 || len(root.Children) != 2 {
+		t.Fatalf("root = %d with %d children, want 1 with 2", root.ID, len(root.Children))
+	}
+	platform := root.Children[0]
+	if platform.ID != 2 || len(platform.Children) != 1 || platform.Children[0].ID != 4 {
+		t.Fatalf("Platform should hold Databases, got %+v", platform)
+	}
+	if web := root.Children[1]; web.ID != 3 || web.Children == nil || len(web.Children) != 0 {
+		t.Fatalf("Web should be a leaf with an empty child list, got %+v", web)
+	}
+}
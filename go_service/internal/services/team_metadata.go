@@ -0,0 +1,337 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+	"go_service/internal/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTeamLabels     = 50
+	maxLabelLength    = 100
+	maxFieldNameLen   = 150
+	maxFieldValueLen  = 500
+	fieldDateLayout   = "2006-01-02"
+	labelCSVSeparator = ";"
+)
+
+var (
+	// ErrUnknownField is returned when a custom team field does not exist
+	ErrUnknownField = errors.New("unknown team field")
+	// ErrFieldExists is returned when defining a field whose key is in use
+	ErrFieldExists = errors.New("team field already exists")
+	// ErrInvalidField is returned for a malformed field definition
+	ErrInvalidField = errors.New("invalid team field")
+	// ErrInvalidFieldValue is returned when a value does not fit its field's type
+	ErrInvalidFieldValue = errors.New("invalid team field value")
+	// ErrInvalidLabel is returned for an empty, overlong or malformed label
+	ErrInvalidLabel = errors.New("invalid team label")
+)
+
+// fieldKeyPattern is the shape of a field key: it is used as a CSV header and
+// in ?field[key]= filters, so it stays lowercase snake_case
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// reservedFieldKeys would clash with the built-in columns of the team CSV
+var reservedFieldKeys = map[string]bool{"labels": true, "teamid": true, "teamname": true, "joinpolicy": true}
+
+// validateFieldDefinition checks a new field definition, trimming its name
+// and enum options in place
+func validateFieldDefinition(def *models.TeamFieldDefinition) error {
+	if !fieldKeyPattern.MatchString(def.Key) {
+		return fmt.Errorf("%w: key must be lowercase letters, digits and underscores, starting with a letter", ErrInvalidField)
+	}
+	if reservedFieldKeys[def.Key] {
+		return fmt.Errorf("%w: key %q is reserved", ErrInvalidField, def.Key)
+	}
+	def.Name = strings.TrimSpace(def.Name)
+	if def.Name == "" || utf8.RuneCountInString(def.Name) > maxFieldNameLen {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidField, maxFieldNameLen)
+	}
+	if !def.Type.Valid() {
+		return fmt.Errorf("%w: type must be string, number, enum or date", ErrInvalidField)
+	}
+
+	if def.Type != models.FieldTypeEnum {
+		if len(def.Options) > 0 {
+			return fmt.Errorf("%w: only enum fields take options", ErrInvalidField)
+		}
+		return nil
+	}
+	if len(def.Options) == 0 {
+		return fmt.Errorf("%w: enum fields need at least one option", ErrInvalidField)
+	}
+	seen := make(map[string]bool, len(def.Options))
+	for i, option := range def.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > maxFieldValueLen {
+			return fmt.Errorf("%w: options must be 1 to %d characters", ErrInvalidField, maxFieldValueLen)
+		}
+		if seen[strings.ToLower(option)] {
+			return fmt.Errorf("%w: duplicate option %q", ErrInvalidField, option)
+		}
+		seen[strings.ToLower(option)] = true
+		def.Options[i] = option
+	}
+	return nil
+}
+
+// normalizeFieldValue checks raw against def's type and returns its canonical
+// form, the one stored and compared by list filters
+func normalizeFieldValue(def *models.TeamFieldDefinition, raw string) (string, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrInvalidFieldValue, def.Key)
+	}
+
+	switch def.Type {
+	case models.FieldTypeNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+			return "", fmt.Errorf("%w: %s must be a number", ErrInvalidFieldValue, def.Key)
+		}
+		value = strconv.FormatFloat(n, 'f', -1, 64)
+	case models.FieldTypeDate:
+		at, err := time.Parse(fieldDateLayout, value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s must be a date (YYYY-MM-DD)", ErrInvalidFieldValue, def.Key)
+		}
+		value = at.Format(fieldDateLayout)
+	case models.FieldTypeEnum:
+		for _, option := range def.Options {
+			if strings.EqualFold(option, value) {
+				return option, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %s must be one of %s", ErrInvalidFieldValue, def.Key, strings.Join(def.Options, ", "))
+	}
+
+	if utf8.RuneCountInString(value) > maxFieldValueLen {
+		return "", fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidFieldValue, def.Key, maxFieldValueLen)
+	}
+	return value, nil
+}
+
+// normalizeLabels trims and lowercases labels, dropping blanks and duplicates,
+// and returns them sorted
+func normalizeLabels(labels []string) ([]string, error) {
+	seen := make(map[string]bool, len(labels))
+	normalized := []string{}
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		if utf8.RuneCountInString(label) > maxLabelLength {
+			return nil, fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidLabel, label, maxLabelLength)
+		}
+		// The CSV export joins labels with the separator
+		if strings.Contains(label, labelCSVSeparator) {
+			return nil, fmt.Errorf("%w: %q contains %q", ErrInvalidLabel, label, labelCSVSeparator)
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	if len(normalized) > maxTeamLabels {
+		return nil, fmt.Errorf("%w: a team takes at most %d labels", ErrInvalidLabel, maxTeamLabels)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// FieldDefinitions returns the custom team fields, ordered by key
+func (s *TeamService) FieldDefinitions() ([]models.TeamFieldDefinition, error) {
+	return s.repo.ListFieldDefinitions()
+}
+
+// fieldsByKey returns the custom team fields keyed by key
+func (s *TeamService) fieldsByKey() (map[string]*models.TeamFieldDefinition, error) {
+	definitions, err := s.repo.ListFieldDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*models.TeamFieldDefinition, len(definitions))
+	for i := range definitions {
+		byKey[definitions[i].Key] = &definitions[i]
+	}
+	return byKey, nil
+}
+
+// DefineField adds a custom field that every team may carry
+func (s *TeamService) DefineField(def models.TeamFieldDefinition) (*models.TeamFieldDefinition, error) {
+	if err := validateFieldDefinition(&def); err != nil {
+		return nil, err
+	}
+	existing, err := s.fieldsByKey()
+	if err != nil {
+		return nil, err
+	}
+	if existing[def.Key] != nil {
+		return nil, ErrFieldExists
+	}
+	if err := s.repo.CreateFieldDefinition(&def); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// DeleteField removes a custom field along with every team's value for it
+func (s *TeamService) DeleteField(key string) error {
+	existing, err := s.fieldsByKey()
+	if err != nil {
+		return err
+	}
+	def := existing[key]
+	if def == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownField, key)
+	}
+	return s.repo.DeleteFieldDefinition(def)
+}
+
+// FieldFilters turns ?field[key]=value list filters into canonical values, so
+// "1.50" finds teams whose number field holds 1.5
+func (s *TeamService) FieldFilters(raw map[string]string) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	definitions, err := s.fieldsByKey()
+	if err != nil {
+		return nil, err
+	}
+	filters := make(map[string]string, len(raw))
+	for key, value := range raw {
+		def := definitions[key]
+		if def == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, key)
+		}
+		if filters[key], err = normalizeFieldValue(def, value); err != nil {
+			return nil, err
+		}
+	}
+	return filters, nil
+}
+
+// MetadataUpdate lists the metadata to change on a team. Nil Labels leaves
+// the labels alone; otherwise they are replaced. Fields maps field keys to new
+// values, nil clearing the field; fields not listed are left alone.
+type MetadataUpdate struct {
+	Labels *[]string
+	Fields map[string]*string
+}
+
+// metadataChange is a MetadataUpdate checked against the field definitions
+type metadataChange struct {
+	labels *[]string
+	// values holds canonical values by field, nil to clear
+	values map[*models.TeamFieldDefinition]*string
+}
+
+// prepareMetadata validates update and normalizes its labels and values
+func prepareMetadata(definitions map[string]*models.TeamFieldDefinition, update MetadataUpdate) (*metadataChange, error) {
+	change := &metadataChange{values: make(map[*models.TeamFieldDefinition]*string, len(update.Fields))}
+	if update.Labels != nil {
+		labels, err := normalizeLabels(*update.Labels)
+		if err != nil {
+			return nil, err
+		}
+		change.labels = &labels
+	}
+	for key, raw := range update.Fields {
+		def := definitions[key]
+		if def == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, key)
+		}
+		if raw == nil {
+			change.values[def] = nil
+			continue
+		}
+		value, err := normalizeFieldValue(def, *raw)
+		if err != nil {
+			return nil, err
+		}
+		change.values[def] = &value
+	}
+	return change, nil
+}
+
+// SetMetadata changes a team's labels and custom field values, emitting
+// TEAM_UPDATED with whatever changed, and returns the team's metadata
+func (s *TeamService) SetMetadata(teamID int, update MetadataUpdate, performedBy uuid.UUID) (*repositories.TeamMetadata, error) {
+	definitions, err := s.fieldsByKey()
+	if err != nil {
+		return nil, err
+	}
+	change, err := prepareMetadata(definitions, update)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyMetadata(teamID, change, performedBy)
+}
+
+func (s *TeamService) applyMetadata(teamID int, change *metadataChange, performedBy uuid.UUID) (*repositories.TeamMetadata, error) {
+	team, err := s.repo.FindTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.ArchivedAt != nil {
+		return nil, ErrTeamArchived
+	}
+
+	var metadata *repositories.TeamMetadata
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		current, err := repo.Metadata([]int{teamID})
+		if err != nil {
+			return err
+		}
+		metadata = current[teamID]
+
+		details := map[string]string{}
+		if change.labels != nil && strings.Join(*change.labels, ",") != strings.Join(metadata.Labels, ",") {
+			if err := repo.SetLabels(teamID, *change.labels); err != nil {
+				return err
+			}
+			details["labels"] = strings.Join(*change.labels, ",")
+			metadata.Labels = *change.labels
+		}
+		for def, value := range change.values {
+			previous, had := metadata.Fields[def.Key]
+			switch {
+			case value == nil && had:
+				if err := repo.ClearFieldValue(teamID, def.ID); err != nil {
+					return err
+				}
+				delete(metadata.Fields, def.Key)
+				details["field."+def.Key] = ""
+			case value != nil && (!had || previous != *value):
+				if err := repo.SetFieldValue(teamID, def.ID, *value); err != nil {
+					return err
+				}
+				metadata.Fields[def.Key] = *value
+				details["field."+def.Key] = *value
+			}
+		}
+
+		if len(details) == 0 {
+			return nil
+		}
+		return recordTeamEvent(tx, kafka.EventTeamUpdated, teamID, performedBy, uuid.Nil, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go_service/internal/models"
+)
+
+func TestNormalizeFieldValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     models.TeamFieldDefinition
+		raw, want string
+		invalid   bool
+	}{
+		{"string is trimmed", models.TeamFieldDefinition{Type: models.FieldTypeString}, "  Berlin ", "Berlin", false},
+		{"empty value", models.TeamFieldDefinition{Type: models.FieldTypeString}, "  ", "", true},
+		{"number is canonical", models.TeamFieldDefinition{Type: models.FieldTypeNumber}, "1.50", "1.5", false},
+		{"exponent number", models.TeamFieldDefinition{Type: models.FieldTypeNumber}, "1e3", "1000", false},
+		{"not a number", models.TeamFieldDefinition{Type: models.FieldTypeNumber}, "ten", "", true},
+		{"infinity is not a number", models.TeamFieldDefinition{Type: models.FieldTypeNumber}, "Inf", "", true},
+		{"date", models.TeamFieldDefinition{Type: models.FieldTypeDate}, "2026-03-01", "2026-03-01", false},
+		{"date with time", models.TeamFieldDefinition{Type: models.FieldTypeDate}, "2026-03-01T10:00:00Z", "", true},
+		{"impossible date", models.TeamFieldDefinition{Type: models.FieldTypeDate}, "2026-02-30", "", true},
+		{"enum takes the option's spelling", models.TeamFieldDefinition{Type: models.FieldTypeEnum, Options: []string{"EMEA", "APAC"}}, "emea", "EMEA", false},
+		{"enum outside the options", models.TeamFieldDefinition{Type: models.FieldTypeEnum, Options: []string{"EMEA", "APAC"}}, "AMER", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.field.Key = "region"
+			got, err := normalizeFieldValue(&tt.field, tt.raw)
+			if invalid := errors.Is(err, ErrInvalidFieldValue); invalid != tt.invalid {
+				t.Fatalf("normalizeFieldValue(%q) error = %v, want invalid %v", tt.raw, err, tt.invalid)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeFieldValue(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLabels(t *testing.T) {
+	got, err := normalizeLabels([]string{" Berlin", "payments", "", "berlin", "Cost-Centre 42"})
+	if err != nil {
+		t.Fatalf("normalizeLabels error = %v", err)
+	}
+	if want := []string{"berlin", "cost-centre 42", "payments"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeLabels = %v, want %v", got, want)
+	}
+
+	if _, err := normalizeLabels([]string{"a;b"}); !errors.Is(err, ErrInvalidLabel) {
+		t.Errorf("label with the CSV separator: error = %v, want ErrInvalidLabel", err)
+	}
+}
+
+func TestValidateFieldDefinition(t *testing.T) {
+	tests := []struct {
+		name  string
+		field models.TeamFieldDefinition
+		valid bool
+	}{
+		{"string field", models.TeamFieldDefinition{Key: "cost_centre", Name: "Cost centre", Type: models.FieldTypeString}, true},
+		{"enum field", models.TeamFieldDefinition{Key: "region", Name: "Region", Type: models.FieldTypeEnum, Options: []string{"EMEA", " APAC "}}, true},
+		{"uppercase key", models.TeamFieldDefinition{Key: "CostCentre", Name: "Cost centre", Type: models.FieldTypeString}, false},
+		{"reserved key", models.TeamFieldDefinition{Key: "labels", Name: "Labels", Type: models.FieldTypeString}, false},
+		{"blank name", models.TeamFieldDefinition{Key: "site", Name: " ", Type: models.FieldTypeString}, false},
+		{"unknown type", models.TeamFieldDefinition{Key: "site", Name: "Site", Type: "bool"}, false},
+		{"enum without options", models.TeamFieldDefinition{Key: "region", Name: "Region", Type: models.FieldTypeEnum}, false},
+		{"duplicate options", models.TeamFieldDefinition{Key: "region", Name: "Region", Type: models.FieldTypeEnum, Options: []string{"EMEA", "emea"}}, false},
+		{"options on a number", models.TeamFieldDefinition{Key: "floor", Name: "Floor", Type: models.FieldTypeNumber, Options: []string{"1"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFieldDefinition(&tt.field)
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("validateFieldDefinition error = %v, want valid %v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidField) {
+				t.Errorf("validateFieldDefinition error = %v, want ErrInvalidField", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SuccessionPolicy decides what happens when a team loses its last leader
+type SuccessionPolicy string
+
+const (
+	// SuccessionPromote makes the longest-standing member the new owner
+	SuccessionPromote SuccessionPolicy = "promote_longest_tenured"
+	// SuccessionEscalate leaves the team without a leader and asks managers to
+	// appoint an owner through the transfer endpoint
+	SuccessionEscalate SuccessionPolicy = "escalate"
+	// SuccessionBlock refuses to remove the last leader
+	SuccessionBlock SuccessionPolicy = "block"
+)
+
+// Valid reports whether p is a known succession policy
+func (p SuccessionPolicy) Valid() bool {
+	switch p {
+	case SuccessionPromote, SuccessionEscalate, SuccessionBlock:
+		return true
+	}
+	return false
+}
+
+// SuccessionPolicyFromEnv reads TEAM_SUCCESSION_POLICY, defaulting to block
+// so that, as before, the last leader cannot be removed or demoted
+func SuccessionPolicyFromEnv() SuccessionPolicy {
+	v := os.Getenv("TEAM_SUCCESSION_POLICY")
+	if v == "" {
+		return SuccessionBlock
+	}
+	policy := SuccessionPolicy(v)
+	if !policy.Valid() {
+		log.Printf("Invalid TEAM_SUCCESSION_POLICY %q, using %s", v, SuccessionBlock)
+		return SuccessionBlock
+	}
+	return policy
+}
+
+// SuccessionOutcome is what a succession did to the team's leadership
+type SuccessionOutcome string
+
+const (
+	SuccessionOutcomePromoted  SuccessionOutcome = "promoted"
+	SuccessionOutcomeEscalated SuccessionOutcome = "escalated"
+	SuccessionOutcomeBlocked   SuccessionOutcome = "blocked"
+)
+
+var (
+	// ErrSuccessionBlocked is returned when the succession policy keeps the last leader on the team
+	ErrSuccessionBlocked = errors.New("the team's last leader cannot be removed")
+	// ErrOwnerRemoval is returned when the owner is removed by someone who may
+	// not transfer ownership
+	ErrOwnerRemoval = errors.New("the team owner cannot be removed without transferring ownership")
+)
+
+// Succession describes how a team's leadership was filled after a leader left
+type Succession struct {
+	Policy      SuccessionPolicy  `json:"policy"`
+	Outcome     SuccessionOutcome `json:"outcome"`
+	SuccessorID *uuid.UUID        `json:"successorId,omitempty"`
+}
+
+// MemberRemoval is the result of taking a user off a team roster
+type MemberRemoval struct {
+	Roster models.Roster
+	// Succession is nil when the team's leadership did not need filling
+	Succession *Succession
+}
+
+// RemoveMember takes userID off the roster, emitting MEMBER_REMOVED. When the
+// owner or the last leader leaves, the succession policy fills the gap in the
+// same transaction and emits LEADER_SUCCEEDED or LEADER_SUCCESSION_ESCALATED.
+// A blocked removal changes nothing, returns ErrSuccessionBlocked and emits
+// LEADER_SUCCESSION_BLOCKED. Removing the owner hands the team to a successor,
+// so it fails with ErrOwnerRemoval unless mayRemoveOwner says performedBy may
+// transfer ownership.
+func (s *TeamService) RemoveMember(teamID int, userID, performedBy uuid.UUID, mayRemoveOwner bool) (*MemberRemoval, error) {
+	var removal *MemberRemoval
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if _, err := repo.LockTeam(teamID); err != nil {
+			return err
+		}
+
+		roster, err := repo.FindRoster(teamID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkOwnerRemoval(*roster, mayRemoveOwner); err != nil {
+			return err
+		}
+		if err := repo.RemoveRoster(roster); err != nil {
+			return err
+		}
+		if err := recordTeamEvent(tx, kafka.EventMemberRemoved, teamID, performedBy, userID, nil); err != nil {
+			return err
+		}
+
+		succession, err := s.succeed(tx, *roster, performedBy)
+		if err != nil {
+			return err
+		}
+		removal = &MemberRemoval{Roster: *roster, Succession: succession}
+		return nil
+	})
+	if errors.Is(err, ErrSuccessionBlocked) {
+		s.recordBlockedSuccession(teamID, userID, performedBy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return removal, nil
+}
+
+// checkOwnerRemoval refuses to take the owner off the roster unless the
+// remover may transfer ownership
+func checkOwnerRemoval(target models.Roster, mayRemoveOwner bool) error {
+	if target.Role == models.RosterRoleOwner && !mayRemoveOwner {
+		return ErrOwnerRemoval
+	}
+	return nil
+}
+
+// DemoteLeader steps a leader down to member, emitting MANAGER_REMOVED. When
+// they were the last leader the succession policy fills the gap as it does for
+// RemoveMember; a blocked demotion changes nothing and returns
+// ErrSuccessionBlocked. The owner cannot be demoted (ErrOwnerRole), nor can
+// the only member of a team (ErrLastLeader) or anyone on an archived team.
+func (s *TeamService) DemoteLeader(teamID int, userID, performedBy uuid.UUID) (*Succession, error) {
+	var succession *Succession
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		team, err := repo.LockTeam(teamID)
+		if err != nil {
+			return err
+		}
+		if team.ArchivedAt != nil {
+			return ErrTeamArchived
+		}
+
+		roster, err := repo.FindRoster(teamID, userID)
+		if err != nil {
+			return err
+		}
+		if roster.Role == models.RosterRoleOwner {
+			return ErrOwnerRole
+		}
+		if !roster.Role.IsLeader() {
+			return ErrNotLeader
+		}
+		rosters, err := repo.ListRosters(teamID)
+		if err != nil {
+			return err
+		}
+		if len(rosters) == 1 {
+			return ErrLastLeader
+		}
+
+		departed := *roster
+		if err := repo.SetRole(roster, models.RosterRoleMember); err != nil {
+			return err
+		}
+		if err := recordTeamEvent(tx, kafka.EventManagerRemoved, teamID, performedBy, userID, nil); err != nil {
+			return err
+		}
+
+		succession, err = s.succeed(tx, departed, performedBy)
+		return err
+	})
+	if errors.Is(err, ErrSuccessionBlocked) {
+		s.recordBlockedSuccession(teamID, userID, performedBy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return succession, nil
+}
+
+// recordBlockedSuccession emits LEADER_SUCCESSION_BLOCKED outside the rolled
+// back transaction of the change the policy refused
+func (s *TeamService) recordBlockedSuccession(teamID int, userID, performedBy uuid.UUID) {
+	details := map[string]string{"policy": string(s.succession)}
+	if err := recordTeamEvent(s.db, kafka.EventSuccessionBlocked, teamID, performedBy, userID, details); err != nil {
+		log.Printf("Failed to record blocked succession on team %d: %v", teamID, err)
+	}
+}
+
+// TeamDeparture is the outcome of removing a user from one of their teams
+type TeamDeparture struct {
+	TeamID     int         `json:"teamId"`
+	Role       string      `json:"role"`
+	Succession *Succession `json:"succession,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// RemoveFromAllTeams takes a departing user off every roster they are on,
+// applying the succession policy team by team. A team that fails or blocks
+// the removal keeps the user and is reported with its error.
+func (s *TeamService) RemoveFromAllTeams(userID, performedBy uuid.UUID) ([]TeamDeparture, error) {
+	var rosters []models.Roster
+	if err := s.db.Where("\"userId\" = ?", userID).Order("\"teamId\"").Find(&rosters).Error; err != nil {
+		return nil, err
+	}
+
+	departures := make([]TeamDeparture, 0, len(rosters))
+	for _, roster := range rosters {
+		departure := TeamDeparture{TeamID: roster.TeamID, Role: string(roster.Role)}
+		removal, err := s.RemoveMember(roster.TeamID, userID, performedBy, true)
+		if err != nil {
+			log.Printf("Failed to remove user %s from team %d: %v", userID, roster.TeamID, err)
+			departure.Error = err.Error()
+		} else {
+			departure.Succession = removal.Succession
+		}
+		departures = append(departures, departure)
+	}
+	return departures, nil
+}
+
+// succeed applies the succession policy after departed has left the roster
+// or stepped down from leading it
+func (s *TeamService) succeed(tx *gorm.DB, departed models.Roster, performedBy uuid.UUID) (*Succession, error) {
+	repo := s.repo.WithTx(tx)
+	rosters, err := repo.ListRosters(departed.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	// A demoted leader is still on the roster but cannot succeed themselves
+	remaining := rosters[:0]
+	for _, roster := range rosters {
+		if roster.UserID != departed.UserID {
+			remaining = append(remaining, roster)
+		}
+	}
+
+	outcome, successor := planSuccession(departed, remaining, s.succession)
+	switch outcome {
+	case "":
+		return nil, nil
+	case SuccessionOutcomeBlocked:
+		return nil, ErrSuccessionBlocked
+	case SuccessionOutcomeEscalated:
+		err := recordTeamEvent(tx, kafka.EventSuccessionEscalated, departed.TeamID, performedBy, departed.UserID, map[string]string{
+			"policy":       string(s.succession),
+			"previousRole": string(departed.Role),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &Succession{Policy: s.succession, Outcome: outcome}, nil
+	}
+
+	previousRole := successor.Role
+	if err := repo.SetRole(successor, models.RosterRoleOwner); err != nil {
+		return nil, err
+	}
+	err = recordTeamEvent(tx, kafka.EventLeaderSucceeded, departed.TeamID, performedBy, successor.UserID, map[string]string{
+		"policy":         string(s.succession),
+		"previousUserId": departed.UserID.String(),
+		"previousRole":   string(previousRole),
+		"role":           string(models.RosterRoleOwner),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Succession{Policy: s.succession, Outcome: outcome, SuccessorID: &successor.UserID}, nil
+}
+
+// planSuccession decides who, if anyone, takes over when departed leaves.
+// A departing owner is replaced by the longest-standing remaining leader
+// whatever the policy; the policy only applies once no leader is left. An
+// empty outcome means leadership needs no change. Viewers are never promoted.
+func planSuccession(departed models.Roster, remaining []models.Roster, policy SuccessionPolicy) (SuccessionOutcome, *models.Roster) {
+	if !departed.Role.IsLeader() || len(remaining) == 0 {
+		return "", nil
+	}
+
+	if leader := longestStanding(remaining, models.RosterRoleOwner, models.RosterRoleLeader); leader != nil {
+		if departed.Role == models.RosterRoleOwner && leader.Role != models.RosterRoleOwner {
+			return SuccessionOutcomePromoted, leader
+		}
+		return "", nil
+	}
+
+	switch policy {
+	case SuccessionBlock:
+		return SuccessionOutcomeBlocked, nil
+	case SuccessionPromote:
+		if member := longestStanding(remaining, models.RosterRoleMember); member != nil {
+			return SuccessionOutcomePromoted, member
+		}
+	}
+	return SuccessionOutcomeEscalated, nil
+}
+
+// longestStanding returns the entry with the lowest roster ID among those
+// holding one of roles, or nil
+func longestStanding(rosters []models.Roster, roles ...models.RosterRole) *models.Roster {
+	var found *models.Roster
+	for i := range rosters {
+		for _, role := range roles {
+			if rosters[i].Role == role && (found == nil || rosters[i].ID < found.ID) {
+				found = &rosters[i]
+			}
+		}
+	}
+	return found
+}
+
+// TransferOwnership makes newOwnerID the team's owner, adding them to the
+// roster if needed, and turns the previous owner into a leader. It emits
+// OWNERSHIP_TRANSFERRED and returns the new owner's entry with the previous
+// owner, if the team had one.
+func (s *TeamService) TransferOwnership(teamID int, newOwnerID, performedBy uuid.UUID) (*models.Roster, *uuid.UUID, error) {
+	var owner *models.Roster
+	var previousOwner *uuid.UUID
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		team, err := repo.LockTeam(teamID)
+		if err != nil {
+			return err
+		}
+		if team.ArchivedAt != nil {
+			return ErrTeamArchived
+		}
+
+		current, err := repo.FindOwner(teamID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if current != nil && current.UserID == newOwnerID {
+			owner = current
+			return nil
+		}
+
+		owner, err = repo.FindRoster(teamID, newOwnerID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := s.AddMember(tx, teamID, newOwnerID, performedBy); err != nil {
+				return err
+			}
+			owner, err = repo.FindRoster(teamID, newOwnerID)
+		}
+		if err != nil {
+			return err
+		}
+
+		details := map[string]string{"previousRole": string(owner.Role)}
+		// Only one owner may exist at a time, so step the current one down first
+		if current != nil {
+			if err := repo.SetRole(current, models.RosterRoleLeader); err != nil {
+				return err
+			}
+			previousOwner = &current.UserID
+			details["previousOwnerId"] = current.UserID.String()
+		}
+		if err := repo.SetRole(owner, models.RosterRoleOwner); err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventOwnershipTransferred, teamID, performedBy, newOwnerID, details)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return owner, previousOwner, nil
+}
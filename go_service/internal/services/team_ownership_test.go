@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestPlanSuccession(t *testing.T) {
+	roster := func(id int, role models.RosterRole) models.Roster {
+		return models.Roster{ID: id, TeamID: 7, UserID: uuid.New(), Role: role}
+	}
+	owner := roster(1, models.RosterRoleOwner)
+	leader := roster(2, models.RosterRoleLeader)
+	junior := roster(5, models.RosterRoleLeader)
+	member := roster(3, models.RosterRoleMember)
+	newer := roster(4, models.RosterRoleMember)
+	viewer := roster(0, models.RosterRoleViewer)
+
+	tests := []struct {
+		name      string
+		departed  models.Roster
+		remaining []models.Roster
+		policy    SuccessionPolicy
+		outcome   SuccessionOutcome
+		successor int
+	}{
+		{"member leaving changes nothing", member, []models.Roster{owner, leader}, SuccessionBlock, "", 0},
+		{"leader leaving an owned team changes nothing", leader, []models.Roster{owner, member}, SuccessionBlock, "", 0},
+		{"owner is replaced by the longest-standing leader", owner, []models.Roster{junior, member, leader}, SuccessionBlock, SuccessionOutcomePromoted, 2},
+		{"last leader is replaced by the longest-standing member", owner, []models.Roster{viewer, newer, member}, SuccessionPromote, SuccessionOutcomePromoted, 3},
+		{"viewers are not promoted", owner, []models.Roster{viewer}, SuccessionPromote, SuccessionOutcomeEscalated, 0},
+		{"escalate leaves the team to managers", owner, []models.Roster{member}, SuccessionEscalate, SuccessionOutcomeEscalated, 0},
+		{"block keeps the last leader", leader, []models.Roster{member}, SuccessionBlock, SuccessionOutcomeBlocked, 0},
+		{"emptied team needs no successor", owner, nil, SuccessionBlock, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, successor := planSuccession(tt.departed, tt.remaining, tt.policy)
+			if outcome != tt.outcome {
+				t.Fatalf("outcome = %q, want %q", outcome, tt.outcome)
+			}
+			got := 0
+			if successor != nil {
+				got = successor.ID
+			}
+			if got != tt.successor {
+				t.Fatalf("successor = %d, want %d", got, tt.successor)
+			}
+		})
+	}
+}
+
+func TestCheckOwnerRemoval(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           models.RosterRole
+		mayRemoveOwner bool
+		wantErr        error
+	}{
+		{"co-leader cannot remove the owner", models.RosterRoleOwner, false, ErrOwnerRemoval},
+		{"whoever may transfer ownership removes the owner", models.RosterRoleOwner, true, nil},
+		{"co-leader removes another leader", models.RosterRoleLeader, false, nil},
+		{"co-leader removes a member", models.RosterRoleMember, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOwnerRemoval(models.Roster{TeamID: 7, UserID: uuid.New(), Role: tt.role}, tt.mayRemoveOwner)
+			if err != tt.wantErr {
+				t.Fatalf("checkOwnerRemoval error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSuccessionPolicyFromEnvDefaultsToBlock(t *testing.T) {
+	t.Setenv("TEAM_SUCCESSION_POLICY", "")
+	if got := SuccessionPolicyFromEnv(); got != SuccessionBlock {
+		t.Errorf("unset policy = %q, want %q", got, SuccessionBlock)
+	}
+	t.Setenv("TEAM_SUCCESSION_POLICY", "bogus")
+	if got := SuccessionPolicyFromEnv(); got != SuccessionBlock {
+		t.Errorf("invalid policy = %q, want %q", got, SuccessionBlock)
+	}
+	t.Setenv("TEAM_SUCCESSION_POLICY", string(SuccessionPromote))
+	if got := SuccessionPolicyFromEnv(); got != SuccessionPromote {
+		t.Errorf("policy = %q, want %q", got, SuccessionPromote)
+	}
+}
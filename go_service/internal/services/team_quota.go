@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+	"go_service/internal/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QuotaKind names one of the limits a team quota sets
+type QuotaKind string
+
+const (
+	QuotaMembers          QuotaKind = "members"
+	QuotaFoldersPerMember QuotaKind = "folders_per_member"
+	QuotaNotesPerMember   QuotaKind = "notes_per_member"
+	QuotaNoteBytes        QuotaKind = "note_bytes"
+)
+
+// ErrQuotaExceeded matches every QuotaError
+var ErrQuotaExceeded = errors.New("team quota exceeded")
+
+// QuotaError reports the team quota a change would take past its limit
+type QuotaError struct {
+	Quota  QuotaKind `json:"quota"`
+	TeamID int       `json:"teamId"`
+	Limit  int       `json:"limit"`
+	// Current is the usage before the change and Requested what it would add
+	Current   int `json:"current"`
+	Requested int `json:"requested"`
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("team %d %s quota exceeded: %d in use, %d requested, limit %d", e.TeamID, e.Quota, e.Current, e.Requested, e.Limit)
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// QuotaLimits are the limits that apply to a team; zero means unlimited
+type QuotaLimits struct {
+	MaxMembers          int `json:"maxMembers"`
+	MaxFoldersPerMember int `json:"maxFoldersPerMember"`
+	MaxNotesPerMember   int `json:"maxNotesPerMember"`
+	MaxNoteBytes        int `json:"maxNoteBytes"`
+}
+
+// QuotaDefaultsFromEnv reads the limits for teams without overrides from
+// TEAM_QUOTA_MAX_MEMBERS, TEAM_QUOTA_MAX_FOLDERS_PER_MEMBER,
+// TEAM_QUOTA_MAX_NOTES_PER_MEMBER and TEAM_QUOTA_MAX_NOTE_BYTES. Unset
+// limits are unlimited.
+func QuotaDefaultsFromEnv() QuotaLimits {
+	return QuotaLimits{
+		MaxMembers:          envInt("TEAM_QUOTA_MAX_MEMBERS", 0),
+		MaxFoldersPerMember: envInt("TEAM_QUOTA_MAX_FOLDERS_PER_MEMBER", 0),
+		MaxNotesPerMember:   envInt("TEAM_QUOTA_MAX_NOTES_PER_MEMBER", 0),
+		MaxNoteBytes:        envInt("TEAM_QUOTA_MAX_NOTE_BYTES", 0),
+	}
+}
+
+// withOverride returns l with the limits a team overrides replaced
+func (l QuotaLimits) withOverride(override *models.TeamQuota) QuotaLimits {
+	if override == nil {
+		return l
+	}
+	if override.MaxMembers != nil {
+		l.MaxMembers = *override.MaxMembers
+	}
+	if override.MaxFoldersPerMember != nil {
+		l.MaxFoldersPerMember = *override.MaxFoldersPerMember
+	}
+	if override.MaxNotesPerMember != nil {
+		l.MaxNotesPerMember = *override.MaxNotesPerMember
+	}
+	if override.MaxNoteBytes != nil {
+		l.MaxNoteBytes = *override.MaxNoteBytes
+	}
+	return l
+}
+
+// perMember returns the per-member limit of kind
+func (l QuotaLimits) perMember(kind QuotaKind) int {
+	if kind == QuotaFoldersPerMember {
+		return l.MaxFoldersPerMember
+	}
+	return l.MaxNotesPerMember
+}
+
+// checkQuota fails when adding requested to current would pass limit. Usage
+// already over a lowered limit only blocks growth, never shrinking.
+func checkQuota(kind QuotaKind, teamID, limit, current, requested int) error {
+	if limit <= 0 || requested <= 0 || current+requested <= limit {
+		return nil
+	}
+	return &QuotaError{Quota: kind, TeamID: teamID, Limit: limit, Current: current, Requested: requested}
+}
+
+// QuotaService enforces the per-team limits on roster size, assets per
+// member and note storage
+type QuotaService struct {
+	db       *gorm.DB
+	defaults QuotaLimits
+}
+
+func NewQuotaService(db *gorm.DB, defaults QuotaLimits) *QuotaService {
+	return &QuotaService{db: db, defaults: defaults}
+}
+
+// WithContext returns a copy of s whose queries run under ctx; see
+// TeamService.WithContext
+func (s *QuotaService) WithContext(ctx context.Context) *QuotaService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// Limits returns the limits in force for teamID and its overrides, which are
+// nil when the team uses the defaults
+func (s *QuotaService) Limits(teamID int) (QuotaLimits, *models.TeamQuota, error) {
+	return s.limits(s.db, teamID)
+}
+
+func (s *QuotaService) limits(db *gorm.DB, teamID int) (QuotaLimits, *models.TeamQuota, error) {
+	var overrides []models.TeamQuota
+	if err := db.Where("team_id = ?", teamID).Limit(1).Find(&overrides).Error; err != nil {
+		return QuotaLimits{}, nil, err
+	}
+	if len(overrides) == 0 {
+		return s.defaults, nil, nil
+	}
+	return s.defaults.withOverride(&overrides[0]), &overrides[0], nil
+}
+
+// CheckMembers fails with a QuotaError when adding members to teamID's
+// roster would pass its member limit. Run it in the transaction that adds
+// them: it locks the team so concurrent additions are counted.
+func (s *QuotaService) CheckMembers(tx *gorm.DB, teamID, adding int) error {
+	return s.checkMembers(tx, teamID, adding, true)
+}
+
+// PreviewMembers is CheckMembers without the lock, for dry runs that change
+// nothing and run outside a transaction
+func (s *QuotaService) PreviewMembers(db *gorm.DB, teamID, adding int) error {
+	return s.checkMembers(db, teamID, adding, false)
+}
+
+func (s *QuotaService) checkMembers(db *gorm.DB, teamID, adding int, lock bool) error {
+	limits, _, err := s.limits(db, teamID)
+	if err != nil || limits.MaxMembers <= 0 {
+		return err
+	}
+	if lock {
+		if _, err := repositories.NewTeamRepository(db).LockTeam(teamID); err != nil {
+			return err
+		}
+	}
+	var count int64
+	if err := db.Model(&models.Roster{}).Where("\"teamId\" = ?", teamID).Count(&count).Error; err != nil {
+		return err
+	}
+	return checkQuota(QuotaMembers, teamID, limits.MaxMembers, int(count), adding)
+}
+
+// CheckOwnedAssets fails with a QuotaError when userID already owns as many
+// folders, or notes, as one of their teams allows per member. kind is
+// QuotaFoldersPerMember or QuotaNotesPerMember. Run it in the transaction
+// that creates the asset: it locks the team whose limit applies so
+// concurrent creations are counted.
+func (s *QuotaService) CheckOwnedAssets(tx *gorm.DB, userID uuid.UUID, kind QuotaKind) error {
+	var teamIDs []int
+	if err := tx.Model(&models.Roster{}).Where("\"userId\" = ?", userID).Pluck("teamId", &teamIDs).Error; err != nil {
+		return err
+	}
+	if len(teamIDs) == 0 {
+		return nil
+	}
+	var overrides []models.TeamQuota
+	if err := tx.Where("team_id IN ?", teamIDs).Find(&overrides).Error; err != nil {
+		return err
+	}
+	byTeam := make(map[int]*models.TeamQuota, len(overrides))
+	for i := range overrides {
+		byTeam[overrides[i].TeamID] = &overrides[i]
+	}
+
+	// The strictest of the user's teams decides
+	strictest, limit := 0, 0
+	for _, teamID := range teamIDs {
+		teamLimit := s.defaults.withOverride(byTeam[teamID]).perMember(kind)
+		if teamLimit > 0 && (limit == 0 || teamLimit < limit) {
+			strictest, limit = teamID, teamLimit
+		}
+	}
+	if limit == 0 {
+		return nil
+	}
+	if _, err := repositories.NewTeamRepository(tx).LockTeam(strictest); err != nil {
+		return err
+	}
+
+	model := interface{}(&models.Folder{})
+	if kind == QuotaNotesPerMember {
+		model = &models.Note{}
+	}
+	var owned int64
+	if err := tx.Model(model).Where("owner_id = ?", userID).Count(&owned).Error; err != nil {
+		return err
+	}
+	return checkQuota(kind, strictest, limit, int(owned), 1)
+}
+
+// CheckNoteBytes fails with a QuotaError when adding bytes of note content
+// to folderID would take its team past the note storage limit. Personal
+// folders have no limit. Run it in the transaction that writes the note: it
+// locks the team so concurrent writes are counted.
+func (s *QuotaService) CheckNoteBytes(tx *gorm.DB, folderID uuid.UUID, adding int) error {
+	var folder models.Folder
+	if err := tx.Select("team_id").First(&folder, "id = ?", folderID).Error; err != nil {
+		return err
+	}
+	if folder.TeamID == nil {
+		return nil
+	}
+	limits, _, err := s.limits(tx, *folder.TeamID)
+	if err != nil || limits.MaxNoteBytes <= 0 {
+		return err
+	}
+	if _, err := repositories.NewTeamRepository(tx).LockTeam(*folder.TeamID); err != nil {
+		return err
+	}
+	used, err := noteBytes(tx, *folder.TeamID)
+	if err != nil {
+		return err
+	}
+	return checkQuota(QuotaNoteBytes, *folder.TeamID, limits.MaxNoteBytes, used, adding)
+}
+
+func noteBytes(db *gorm.DB, teamID int) (int, error) {
+	var used int
+	err := db.Model(&models.Note{}).
+		Joins("JOIN folders ON folders.id = notes.folder_id").
+		Where("folders.team_id = ?", teamID).
+		Select("COALESCE(SUM(OCTET_LENGTH(notes.content)), 0)").
+		Scan(&used).Error
+	return used, err
+}
+
+// MemberUsage is what one team member owns, counted against the per-member limits
+type MemberUsage struct {
+	UserID  uuid.UUID `json:"userId"`
+	Folders int       `json:"folders"`
+	Notes   int       `json:"notes"`
+}
+
+// QuotaUsage is a team's consumption next to its limits
+type QuotaUsage struct {
+	TeamID int         `json:"teamId"`
+	Limits QuotaLimits `json:"limits"`
+	// Overrides is nil when the team uses the defaults
+	Overrides    *models.TeamQuota `json:"overrides"`
+	Members      int               `json:"members"`
+	NoteBytes    int               `json:"noteBytes"`
+	MemberAssets []MemberUsage     `json:"memberAssets"`
+}
+
+// Usage reports teamID's current consumption against its limits
+func (s *QuotaService) Usage(teamID int) (*QuotaUsage, error) {
+	limits, overrides, err := s.Limits(teamID)
+	if err != nil {
+		return nil, err
+	}
+	usage := &QuotaUsage{TeamID: teamID, Limits: limits, Overrides: overrides, MemberAssets: []MemberUsage{}}
+
+	err = s.db.Raw(`SELECT r."userId" AS user_id,
+			(SELECT COUNT(*) FROM folders f WHERE f.owner_id = r."userId") AS folders,
+			(SELECT COUNT(*) FROM notes n WHERE n.owner_id = r."userId") AS notes
+		FROM "Rosters" r WHERE r."teamId" = ? ORDER BY r."rosterId"`, teamID).
+		Scan(&usage.MemberAssets).Error
+	if err != nil {
+		return nil, err
+	}
+	usage.Members = len(usage.MemberAssets)
+
+	if usage.NoteBytes, err = noteBytes(s.db, teamID); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// SetOverrides replaces teamID's overrides, emitting TEAM_QUOTA_UPDATED. Nil
+// limits in quota go back to the defaults.
+func (s *QuotaService) SetOverrides(teamID int, quota models.TeamQuota, performedBy uuid.UUID) (*models.TeamQuota, error) {
+	quota.TeamID = teamID
+	quota.UpdatedByID = performedBy
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&quota).Error; err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventTeamQuotaUpdated, teamID, performedBy, uuid.Nil, quotaDetails(&quota))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// quotaDetails lists the limits a team overrides for its event
+func quotaDetails(quota *models.TeamQuota) map[string]string {
+	details := map[string]string{}
+	for name, limit := range map[string]*int{
+		"maxMembers":          quota.MaxMembers,
+		"maxFoldersPerMember": quota.MaxFoldersPerMember,
+		"maxNotesPerMember":   quota.MaxNotesPerMember,
+		"maxNoteBytes":        quota.MaxNoteBytes,
+	} {
+		if limit != nil {
+			details[name] = strconv.Itoa(*limit)
+		}
+	}
+	return details
+}
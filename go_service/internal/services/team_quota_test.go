@@ -0,0 +1,45 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"go_service/internal/models"
+)
+
+func TestQuotaLimitsWithOverride(t *testing.T) {
+	defaults := QuotaLimits{MaxMembers: 50, MaxFoldersPerMember: 20, MaxNotesPerMember: 200, MaxNoteBytes: 1 << 20}
+	unlimited, larger := 0, 100
+
+	got := defaults.withOverride(&models.TeamQuota{MaxMembers: &larger, MaxNoteBytes: &unlimited})
+	want := QuotaLimits{MaxMembers: 100, MaxFoldersPerMember: 20, MaxNotesPerMember: 200, MaxNoteBytes: 0}
+	if got != want {
+		t.Errorf("withOverride = %+v, want %+v", got, want)
+	}
+	if got := defaults.withOverride(nil); got != defaults {
+		t.Errorf("withOverride(nil) = %+v, want the defaults", got)
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	tests := []struct {
+		name                      string
+		limit, current, requested int
+		exceeded                  bool
+	}{
+		{"unlimited", 0, 1000, 1, false},
+		{"room left", 10, 9, 1, false},
+		{"full", 10, 10, 1, true},
+		{"batch does not fit", 10, 8, 3, true},
+		{"over a lowered limit may shrink", 5, 8, -2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkQuota(QuotaMembers, 7, tt.limit, tt.current, tt.requested)
+			if exceeded := errors.Is(err, ErrQuotaExceeded); exceeded != tt.exceeded {
+				t.Errorf("checkQuota(%d, %d, %d) = %v, want exceeded %v", tt.limit, tt.current, tt.requested, err, tt.exceeded)
+			}
+		})
+	}
+}
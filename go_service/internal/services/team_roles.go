@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOwnerRole is returned when a role change would grant or take away team ownership
+	ErrOwnerRole = errors.New("ownership cannot be granted or removed by a role change")
+	// ErrLastLeader is returned when a change would leave the team without a leader
+	ErrLastLeader = errors.New("team must keep at least one leader")
+	// ErrNotLeader is returned when demoting a member who does not lead the team
+	ErrNotLeader = errors.New("user is not a leader of the team")
+)
+
+// ChangeRole sets a member's roster role, emitting MEMBER_ROLE_CHANGED. It
+// returns the updated entry and the role it had before. Roles on an archived
+// team cannot change.
+func (s *TeamService) ChangeRole(teamID int, userID uuid.UUID, role models.RosterRole, performedBy uuid.UUID) (*models.Roster, models.RosterRole, error) {
+	if role == models.RosterRoleOwner {
+		return nil, "", ErrOwnerRole
+	}
+
+	var roster *models.Roster
+	var previous models.RosterRole
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		team, err := repo.LockTeam(teamID)
+		if err != nil {
+			return err
+		}
+		if team.ArchivedAt != nil {
+			return ErrTeamArchived
+		}
+
+		roster, err = repo.FindRoster(teamID, userID)
+		if err != nil {
+			return err
+		}
+		previous = roster.Role
+		if previous == role {
+			return nil
+		}
+		if previous == models.RosterRoleOwner {
+			return ErrOwnerRole
+		}
+
+		if previous.IsLeader() && !role.IsLeader() {
+			leaders, err := repo.CountLeaders(teamID)
+			if err != nil {
+				return err
+			}
+			if leaders <= 1 {
+				return ErrLastLeader
+			}
+		}
+
+		if err := repo.SetRole(roster, role); err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventMemberRoleChanged, teamID, performedBy, userID, map[string]string{
+			"previousRole": string(previous),
+			"role":         string(role),
+		})
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return roster, previous, nil
+}
+
+// RoleOutcome is what SetRoles did for one user
+type RoleOutcome string
+
+const (
+	// RoleChanged means the user now holds the role
+	RoleChanged RoleOutcome = "changed"
+	// RoleUnchanged means the user already held the role, or already led the
+	// team when the role is leader
+	RoleUnchanged RoleOutcome = "unchanged"
+	// RoleOwnerKept means the user owns the team; ownership only moves by transfer
+	RoleOwnerKept RoleOutcome = "owner"
+	// RoleNotMember means the user is not on the team's roster
+	RoleNotMember RoleOutcome = "not_member"
+)
+
+// SetRoles gives each of userIDs the role on teamID, emitting MANAGER_ADDED
+// for a promotion to leader and MEMBER_ROLE_CHANGED otherwise, and reports
+// the outcome per user. Users who are not on the roster or own the team are
+// left alone. It fails as a whole with ErrTeamArchived, with ErrOwnerRole
+// when role is owner and with ErrLastLeader when no leader would remain. Pass
+// a transaction as tx to make the change part of a larger one, or nil to run
+// in a transaction of its own.
+func (s *TeamService) SetRoles(tx *gorm.DB, teamID int, userIDs []uuid.UUID, role models.RosterRole, performedBy uuid.UUID) (map[uuid.UUID]RoleOutcome, error) {
+	if role == models.RosterRoleOwner {
+		return nil, ErrOwnerRole
+	}
+	if tx == nil {
+		var outcomes map[uuid.UUID]RoleOutcome
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			outcomes, err = s.SetRoles(tx, teamID, userIDs, role, performedBy)
+			return err
+		})
+		return outcomes, err
+	}
+
+	repo := s.repo.WithTx(tx)
+	team, err := repo.LockTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.ArchivedAt != nil {
+		return nil, ErrTeamArchived
+	}
+
+	outcomes := make(map[uuid.UUID]RoleOutcome, len(userIDs))
+	demoted := false
+	for _, userID := range userIDs {
+		if _, done := outcomes[userID]; done {
+			continue
+		}
+		roster, err := repo.FindRoster(teamID, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			outcomes[userID] = RoleNotMember
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		previous := roster.Role
+		switch {
+		case previous == models.RosterRoleOwner:
+			outcomes[userID] = RoleOwnerKept
+			continue
+		case previous == role, role == models.RosterRoleLeader && previous.IsLeader():
+			outcomes[userID] = RoleUnchanged
+			continue
+		}
+
+		if err := repo.SetRole(roster, role); err != nil {
+			return nil, err
+		}
+		if role == models.RosterRoleLeader {
+			err = recordTeamEvent(tx, kafka.EventManagerAdded, teamID, performedBy, userID, nil)
+		} else {
+			err = recordTeamEvent(tx, kafka.EventMemberRoleChanged, teamID, performedBy, userID, map[string]string{
+				"previousRole": string(previous),
+				"role":         string(role),
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		demoted = demoted || (previous.IsLeader() && !role.IsLeader())
+		outcomes[userID] = RoleChanged
+	}
+
+	if demoted {
+		leaders, err := repo.CountLeaders(teamID)
+		if err != nil {
+			return nil, err
+		}
+		if leaders == 0 {
+			return nil, ErrLastLeader
+		}
+	}
+	return outcomes, nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"go_service/internal/models"
+	"go_service/internal/testutil"
+
+	"github.com/google/uuid"
+)
+
+func TestSetRolesPromotesLeaders(t *testing.T) {
+	db := testutil.DB(t)
+	teams := NewTeamService(db, nil, SuccessionBlock, nil)
+	owner := uuid.New()
+	team, _, err := teams.CreateTeam("roles-"+uuid.NewString(), nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, leader, stranger := uuid.New(), uuid.New(), uuid.New()
+	for _, userID := range []uuid.UUID{member, leader} {
+		if err := teams.AddMember(nil, team.ID, userID, owner); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := teams.SetRoles(nil, team.ID, []uuid.UUID{leader}, models.RosterRoleLeader, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	outcomes, err := teams.SetRoles(nil, team.ID, []uuid.UUID{member, leader, owner, stranger}, models.RosterRoleLeader, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uuid.UUID]RoleOutcome{
+		member:   RoleChanged,
+		leader:   RoleUnchanged,
+		owner:    RoleOwnerKept,
+		stranger: RoleNotMember,
+	}
+	for userID, outcome := range want {
+		if outcomes[userID] != outcome {
+			t.Errorf("outcome for %s = %q, want %q", userID, outcomes[userID], outcome)
+		}
+	}
+	roster, err := teams.repo.FindRoster(team.ID, member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roster.Role != models.RosterRoleLeader || !roster.IsLeader {
+		t.Errorf("promoted member's roster = %+v, want a leader", roster)
+	}
+}
+
+func TestSetRolesRefusesOwnerAndArchived(t *testing.T) {
+	if _, err := (&TeamService{}).SetRoles(nil, 1, []uuid.UUID{uuid.New()}, models.RosterRoleOwner, uuid.New()); !errors.Is(err, ErrOwnerRole) {
+		t.Errorf("SetRoles(owner) = %v, want %v", err, ErrOwnerRole)
+	}
+
+	teams, team, member := testArchivedTeam(t)
+	if _, err := teams.SetRoles(nil, team.ID, []uuid.UUID{member}, models.RosterRoleLeader, uuid.New()); !errors.Is(err, ErrTeamArchived) {
+		t.Errorf("SetRoles on an archived team = %v, want %v", err, ErrTeamArchived)
+	}
+}
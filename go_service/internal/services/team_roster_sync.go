@@ -0,0 +1,280 @@
+package services
+
+import (
+	"errors"
+
+	"go_service/internal/kafka"
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrRoleChangeNotAllowed is returned when a roster sync would change roles
+// the caller may not change
+var ErrRoleChangeNotAllowed = errors.New("roster sync would change team roles")
+
+// RosterSync is the roster a team should end up with. Leaders are members
+// too and need not be listed twice; current viewers listed as members stay
+// viewers.
+type RosterSync struct {
+	Members []uuid.UUID
+	Leaders []uuid.UUID
+}
+
+// RosterSyncOptions controls how SyncRoster applies a diff
+type RosterSyncOptions struct {
+	// DryRun computes the diff without applying it
+	DryRun bool
+	// AllowRoleChanges permits promotions, demotions, leader additions and
+	// ownership moves; without it such a diff fails with ErrRoleChangeNotAllowed
+	AllowRoleChanges bool
+}
+
+// RosterChangeType is the kind of a single roster change
+type RosterChangeType string
+
+const (
+	RosterChangeAdd      RosterChangeType = "add"
+	RosterChangeRemove   RosterChangeType = "remove"
+	RosterChangePromote  RosterChangeType = "promote"
+	RosterChangeDemote   RosterChangeType = "demote"
+	RosterChangeTransfer RosterChangeType = "transfer_ownership"
+)
+
+// RosterChange is one step of a roster diff
+type RosterChange struct {
+	Type   RosterChangeType `json:"type"`
+	UserID uuid.UUID        `json:"userId"`
+	// Role is the user's role after the change; empty for removals
+	Role         models.RosterRole `json:"role,omitempty"`
+	PreviousRole models.RosterRole `json:"previousRole,omitempty"`
+}
+
+// RosterDiff lists the changes that turn the current roster into the desired
+// one, in the order they are applied
+type RosterDiff struct {
+	Changes   []RosterChange `json:"changes"`
+	Unchanged int            `json:"unchanged"`
+}
+
+// ChangesRoles reports whether applying the diff changes anyone's leadership
+func (d *RosterDiff) ChangesRoles() bool {
+	for _, change := range d.Changes {
+		switch change.Type {
+		case RosterChangePromote, RosterChangeDemote, RosterChangeTransfer:
+			return true
+		case RosterChangeAdd:
+			if change.Role.IsLeader() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// netAdded returns how much the diff grows the roster by
+func (d *RosterDiff) netAdded() int {
+	n := 0
+	for _, change := range d.Changes {
+		switch change.Type {
+		case RosterChangeAdd:
+			n++
+		case RosterChangeRemove:
+			n--
+		}
+	}
+	return n
+}
+
+// SyncRoster brings a team's roster in line with desired in one transaction,
+// emitting an event per change: MEMBER_ADDED, MEMBER_REMOVED,
+// MEMBER_ROLE_CHANGED and, when the owner is removed or demoted,
+// OWNERSHIP_TRANSFERRED to the longest-standing remaining leader.
+func (s *TeamService) SyncRoster(teamID int, desired RosterSync, options RosterSyncOptions, performedBy uuid.UUID) (*RosterDiff, error) {
+	if options.DryRun {
+		return s.loadRosterDiff(s.db, teamID, desired, options)
+	}
+
+	var diff *RosterDiff
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		diff, err = s.loadRosterDiff(tx, teamID, desired, options)
+		if err != nil {
+			return err
+		}
+		for _, change := range diff.Changes {
+			if err := s.applyRosterChange(tx, teamID, change, performedBy); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+func (s *TeamService) loadRosterDiff(db *gorm.DB, teamID int, desired RosterSync, options RosterSyncOptions) (*RosterDiff, error) {
+	repo := s.repo.WithTx(db)
+	var team *models.Team
+	var err error
+	if options.DryRun {
+		team, err = repo.FindTeam(teamID)
+	} else {
+		team, err = repo.LockTeam(teamID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if team.ArchivedAt != nil {
+		return nil, ErrTeamArchived
+	}
+
+	current, err := repo.ListRosters(teamID)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := diffRoster(current, desired)
+	if err != nil {
+		return nil, err
+	}
+	if !options.AllowRoleChanges && diff.ChangesRoles() {
+		return nil, ErrRoleChangeNotAllowed
+	}
+	check := s.checkMembers
+	if options.DryRun {
+		// A dry run holds no transaction for the lock to live in
+		check = s.previewMembers
+	}
+	if err := check(db, teamID, diff.netAdded()); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+func (s *TeamService) applyRosterChange(tx *gorm.DB, teamID int, change RosterChange, performedBy uuid.UUID) error {
+	repo := s.repo.WithTx(tx)
+	details := map[string]string{"source": "roster_sync"}
+
+	if change.Type == RosterChangeAdd {
+		if err := repo.AddMemberToTeam(models.NewRoster(teamID, change.UserID, change.Role)); err != nil {
+			return err
+		}
+		details["role"] = string(change.Role)
+		return recordTeamEvent(tx, kafka.EventMemberAdded, teamID, performedBy, change.UserID, details)
+	}
+
+	roster, err := repo.FindRoster(teamID, change.UserID)
+	if err != nil {
+		return err
+	}
+
+	switch change.Type {
+	case RosterChangeRemove:
+		if err := repo.RemoveRoster(roster); err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventMemberRemoved, teamID, performedBy, change.UserID, details)
+	case RosterChangeTransfer:
+		details["previousRole"] = string(roster.Role)
+		if err := repo.SetRole(roster, models.RosterRoleOwner); err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventOwnershipTransferred, teamID, performedBy, change.UserID, details)
+	}
+
+	details["previousRole"] = string(change.PreviousRole)
+	details["role"] = string(change.Role)
+	if err := repo.SetRole(roster, change.Role); err != nil {
+		return err
+	}
+	return recordTeamEvent(tx, kafka.EventMemberRoleChanged, teamID, performedBy, change.UserID, details)
+}
+
+// diffRoster works out the changes that turn current, ordered by roster ID,
+// into desired. Removals and demotions come first so that a departing owner
+// has stepped down before ownership moves to the longest-standing leader
+// that remains, or failing that the first new leader.
+func diffRoster(current []models.Roster, desired RosterSync) (*RosterDiff, error) {
+	wanted := make(map[uuid.UUID]models.RosterRole, len(desired.Members)+len(desired.Leaders))
+	order := make([]uuid.UUID, 0, len(desired.Members)+len(desired.Leaders))
+	for _, userID := range desired.Leaders {
+		if _, seen := wanted[userID]; !seen {
+			order = append(order, userID)
+		}
+		wanted[userID] = models.RosterRoleLeader
+	}
+	for _, userID := range desired.Members {
+		if _, seen := wanted[userID]; !seen {
+			wanted[userID] = models.RosterRoleMember
+			order = append(order, userID)
+		}
+	}
+	if len(desired.Leaders) == 0 {
+		return nil, ErrLastLeader
+	}
+
+	diff := &RosterDiff{Changes: []RosterChange{}}
+	var removes, demotes, promotes, adds []RosterChange
+	var owner *models.Roster
+	var successors []uuid.UUID
+	onRoster := make(map[uuid.UUID]bool, len(current))
+
+	for i := range current {
+		roster := current[i]
+		onRoster[roster.UserID] = true
+		if roster.Role == models.RosterRoleOwner {
+			owner = &current[i]
+		}
+
+		role, keep := wanted[roster.UserID]
+		switch {
+		case !keep:
+			removes = append(removes, RosterChange{Type: RosterChangeRemove, UserID: roster.UserID, PreviousRole: roster.Role})
+		case role.IsLeader() && roster.Role.IsLeader():
+			diff.Unchanged++
+			if roster.Role != models.RosterRoleOwner {
+				successors = append(successors, roster.UserID)
+			}
+		case role.IsLeader():
+			promotes = append(promotes, RosterChange{Type: RosterChangePromote, UserID: roster.UserID, Role: role, PreviousRole: roster.Role})
+		case roster.Role.IsLeader():
+			demotes = append(demotes, RosterChange{Type: RosterChangeDemote, UserID: roster.UserID, Role: role, PreviousRole: roster.Role})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, change := range promotes {
+		successors = append(successors, change.UserID)
+	}
+
+	for _, userID := range order {
+		if onRoster[userID] {
+			continue
+		}
+		role := wanted[userID]
+		adds = append(adds, RosterChange{Type: RosterChangeAdd, UserID: userID, Role: role})
+		if role.IsLeader() {
+			successors = append(successors, userID)
+		}
+	}
+
+	diff.Changes = append(diff.Changes, removes...)
+	diff.Changes = append(diff.Changes, demotes...)
+	diff.Changes = append(diff.Changes, promotes...)
+	diff.Changes = append(diff.Changes, adds...)
+
+	if owner != nil && wanted[owner.UserID] != models.RosterRoleLeader {
+		diff.Changes = append(diff.Changes, RosterChange{
+			Type:         RosterChangeTransfer,
+			UserID:       successors[0],
+			Role:         models.RosterRoleOwner,
+			PreviousRole: models.RosterRoleLeader,
+		})
+	}
+
+	return diff, nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestDiffRoster(t *testing.T) {
+	owner, leader, member, viewer, newcomer := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	current := []models.Roster{
+		{ID: 1, UserID: owner, Role: models.RosterRoleOwner, IsLeader: true},
+		{ID: 2, UserID: leader, Role: models.RosterRoleLeader, IsLeader: true},
+		{ID: 3, UserID: member, Role: models.RosterRoleMember},
+		{ID: 4, UserID: viewer, Role: models.RosterRoleViewer},
+	}
+
+	t.Run("matching roster has no changes", func(t *testing.T) {
+		diff, err := diffRoster(current, RosterSync{Leaders: []uuid.UUID{owner, leader}, Members: []uuid.UUID{member, viewer, leader}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diff.Changes) != 0 || diff.Unchanged != 4 {
+			t.Fatalf("got %+v, want no changes and 4 unchanged", diff)
+		}
+		if diff.ChangesRoles() {
+			t.Fatal("empty diff should not change roles")
+		}
+	})
+
+	t.Run("owner leaving hands ownership to the remaining leader", func(t *testing.T) {
+		diff, err := diffRoster(current, RosterSync{Leaders: []uuid.UUID{member, leader}, Members: []uuid.UUID{newcomer}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []RosterChange{
+			{Type: RosterChangeRemove, UserID: owner, PreviousRole: models.RosterRoleOwner},
+			{Type: RosterChangeRemove, UserID: viewer, PreviousRole: models.RosterRoleViewer},
+			{Type: RosterChangePromote, UserID: member, Role: models.RosterRoleLeader, PreviousRole: models.RosterRoleMember},
+			{Type: RosterChangeAdd, UserID: newcomer, Role: models.RosterRoleMember},
+			{Type: RosterChangeTransfer, UserID: leader, Role: models.RosterRoleOwner, PreviousRole: models.RosterRoleLeader},
+		}
+		if len(diff.Changes) != len(want) {
+			t.Fatalf("got %d changes %+v, want %d", len(diff.Changes), diff.Changes, len(want))
+		}
+		for i := range want {
+			if diff.Changes[i] != want[i] {
+				t.Errorf("change %d = %+v, want %+v", i, diff.Changes[i], want[i])
+			}
+		}
+		if diff.Unchanged != 1 || !diff.ChangesRoles() {
+			t.Fatalf("unchanged = %d, changesRoles = %v", diff.Unchanged, diff.ChangesRoles())
+		}
+	})
+
+	t.Run("demoted owner steps down before ownership moves", func(t *testing.T) {
+		diff, err := diffRoster(current[:1], RosterSync{Leaders: []uuid.UUID{newcomer}, Members: []uuid.UUID{owner}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diff.Changes) != 3 ||
+			diff.Changes[0].Type != RosterChangeDemote ||
+			diff.Changes[1].Type != RosterChangeAdd ||
+			diff.Changes[2] != (RosterChange{Type: RosterChangeTransfer, UserID: newcomer, Role: models.RosterRoleOwner, PreviousRole: models.RosterRoleLeader}) {
+			t.Fatalf("got %+v", diff.Changes)
+		}
+	})
+
+	t.Run("member-only sync changes no roles", func(t *testing.T) {
+		diff, err := diffRoster(current, RosterSync{Leaders: []uuid.UUID{owner, leader}, Members: []uuid.UUID{newcomer}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff.ChangesRoles() {
+			t.Fatalf("adding and removing members should not change roles: %+v", diff.Changes)
+		}
+	})
+
+	t.Run("a roster needs a leader", func(t *testing.T) {
+		if _, err := diffRoster(current, RosterSync{Members: []uuid.UUID{owner}}); !errors.Is(err, ErrLastLeader) {
+			t.Fatalf("err = %v, want ErrLastLeader", err)
+		}
+	})
+}
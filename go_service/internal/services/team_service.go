@@ -1,6 +1,12 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
 	"go_service/internal/kafka"
 	"go_service/internal/models"
 	"go_service/internal/redisclient"
@@ -10,47 +16,83 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrTeamArchived is returned when changing a team that is archived
+	ErrTeamArchived = errors.New("team is archived")
+	// ErrTeamNotArchived is returned when restoring a team that is active
+	ErrTeamNotArchived = errors.New("team is not archived")
+	// ErrTeamNameTaken is returned when another team already has the name
+	ErrTeamNameTaken = errors.New("team name already in use")
+	// ErrAlreadyMember is returned when adding a user who is already on the roster
+	ErrAlreadyMember = errors.New("user is already a member of the team")
+)
+
 type TeamService struct {
+	db          *gorm.DB
 	repo        *repositories.TeamRepository
-	userService *UserService
-	producer    *kafka.Producer
 	redisClient *redisclient.TeamCache
+	succession  SuccessionPolicy
+	quotas      *QuotaService
 }
 
-func NewTeamService(db *gorm.DB, producer *kafka.Producer, redisClient *redisclient.TeamCache) *TeamService {
+// NewTeamService creates a TeamService. Its events go through the outbox, so
+// it needs no Kafka producer. succession applies when a team loses its last
+// leader; quotas, if not nil, limits roster sizes.
+func NewTeamService(db *gorm.DB, redisClient *redisclient.TeamCache, succession SuccessionPolicy, quotas *QuotaService) *TeamService {
+	if !succession.Valid() {
+		succession = SuccessionBlock
+	}
 	return &TeamService{
+		db:          db,
 		repo:        repositories.NewTeamRepository(db),
-		userService: NewUserService(),
-		producer:    producer,
 		redisClient: redisClient,
+		succession:  succession,
+		quotas:      quotas,
 	}
 }
 
+// WithContext returns a copy of s whose queries run under ctx, the request's
+// context, so the events it records carry who is really behind the request
+func (s *TeamService) WithContext(ctx context.Context) *TeamService {
+	return s.withTx(s.db.WithContext(ctx))
+}
+
+// withTx returns a copy of s whose changes all run in tx
+func (s *TeamService) withTx(tx *gorm.DB) *TeamService {
+	scoped := *s
+	scoped.db = tx
+	scoped.repo = s.repo.WithTx(tx)
+	return &scoped
+}
+
 // Creates a new team and adds members
 func (s *TeamService) CreateTeam(teamName string, userIDs []uuid.UUID, creatorID uuid.UUID) (*models.Team, []uuid.UUID, error) {
 	team := &models.Team{TeamName: teamName}
-	if err := s.repo.CreateTeam(team); err != nil {
-		return nil, nil, err
-	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.CreateTeam(team); err != nil {
+			return err
+		}
 
-	// Add creator as leader
-	leaderRoster := &models.Roster{TeamID: team.ID, UserID: creatorID, IsLeader: true}
-	if err := s.repo.AddMemberToTeam(leaderRoster); err != nil {
+		// The creator owns the team
+		if err := repo.AddMemberToTeam(models.NewRoster(team.ID, creatorID, models.RosterRoleOwner)); err != nil {
+			return err
+		}
+
+		return recordTeamEvent(tx, kafka.EventTeamCreated, team.ID, creatorID, uuid.Nil, map[string]string{"teamName": teamName})
+	})
+	if err != nil {
 		return nil, nil, err
 	}
 
-	addedMembers := []uuid.UUID{creatorID}
 	failedMembers := []uuid.UUID{}
 
 	for _, userID := range userIDs {
 		if userID == creatorID {
 			continue
 		}
-		roster := &models.Roster{TeamID: team.ID, UserID: userID, IsLeader: false}
-		if err := s.repo.AddMemberToTeam(roster); err != nil {
+		if err := s.AddMember(nil, team.ID, userID, creatorID); err != nil {
 			failedMembers = append(failedMembers, userID)
-		} else {
-			addedMembers = append(addedMembers, userID)
 		}
 	}
 
@@ -75,3 +117,245 @@ func (s *TeamService) CreateTeam(teamName string, userIDs []uuid.UUID, creatorID
 
 //     return results, addedCount, existingCount, failedCount, nil
 // }
+
+// AddMember puts userID on the team roster as a regular member and records
+// MEMBER_ADDED in the same transaction. It fails with a QuotaError when the
+// roster is full and with ErrTeamArchived on an archived team. Pass a transaction as tx to make the addition part of a
+// larger change, or nil to run in a transaction of its own.
+func (s *TeamService) AddMember(tx *gorm.DB, teamID int, userID, performedBy uuid.UUID) error {
+	if tx == nil {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			return s.AddMember(tx, teamID, userID, performedBy)
+		})
+	}
+
+	repo := s.repo.WithTx(tx)
+	// Locked so the team cannot be archived until the addition commits
+	team, err := repo.LockTeam(teamID)
+	if err != nil {
+		return err
+	}
+	if team.ArchivedAt != nil {
+		return ErrTeamArchived
+	}
+	member, err := repo.IsMember(teamID, userID)
+	if err != nil {
+		return err
+	}
+	if member {
+		return ErrAlreadyMember
+	}
+	if err := s.checkMembers(tx, teamID, 1); err != nil {
+		return err
+	}
+
+	if err := repo.AddMemberToTeam(models.NewRoster(teamID, userID, models.RosterRoleMember)); err != nil {
+		return err
+	}
+	return recordTeamEvent(tx, kafka.EventMemberAdded, teamID, performedBy, userID, nil)
+}
+
+// ListTeams pages through teams with their roster counts
+func (s *TeamService) ListTeams(q repositories.TeamListQuery) (*repositories.TeamPage, error) {
+	return s.repo.ListTeams(q)
+}
+
+// GetTeam returns a team with its roster counts
+func (s *TeamService) GetTeam(teamID int, viewerID uuid.UUID) (*repositories.TeamSummary, error) {
+	return s.repo.GetTeamSummary(teamID, viewerID)
+}
+
+// RosterAt returns teamID's roster as it was at the given time
+func (s *TeamService) RosterAt(teamID int, at time.Time) ([]models.MembershipInterval, error) {
+	return s.repo.RosterAt(teamID, at)
+}
+
+// MembershipsAt returns the teams userID was on at the given time
+func (s *TeamService) MembershipsAt(userID uuid.UUID, at time.Time) ([]models.MembershipInterval, error) {
+	return s.repo.MembershipsAt(userID, at)
+}
+
+// ListActivity returns a page of a team's activity feed
+func (s *TeamService) ListActivity(q repositories.ActivityQuery) (*repositories.ActivityPage, error) {
+	return s.repo.ListActivity(q)
+}
+
+// TeamUpdate lists the team settings to change; nil fields are left alone
+type TeamUpdate struct {
+	TeamName   *string
+	JoinPolicy *models.JoinPolicy
+}
+
+// UpdateTeam renames a team and/or changes its join policy, emitting TEAM_UPDATED
+// with the previous and new values of whatever changed
+func (s *TeamService) UpdateTeam(teamID int, update TeamUpdate, performedBy uuid.UUID) (*models.Team, error) {
+	team, err := s.repo.FindTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.ArchivedAt != nil {
+		return nil, ErrTeamArchived
+	}
+
+	columns := map[string]interface{}{}
+	details := map[string]string{}
+
+	if update.TeamName != nil && *update.TeamName != team.TeamName {
+		taken, err := s.repo.TeamNameTaken(*update.TeamName, team.ID)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, ErrTeamNameTaken
+		}
+		columns["teamName"] = *update.TeamName
+		details["previousName"] = team.TeamName
+		details["teamName"] = *update.TeamName
+	}
+	if update.JoinPolicy != nil && *update.JoinPolicy != team.JoinPolicy {
+		columns["joinPolicy"] = *update.JoinPolicy
+		details["previousJoinPolicy"] = string(team.JoinPolicy)
+		details["joinPolicy"] = string(*update.JoinPolicy)
+	}
+
+	if len(columns) == 0 {
+		return team, nil
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).UpdateTeam(team, columns); err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventTeamUpdated, team.ID, performedBy, uuid.Nil, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+// ArchiveTeam hides a team and moves its roster aside so it can be restored later
+func (s *TeamService) ArchiveTeam(ctx context.Context, teamID int, performedBy uuid.UUID) (*models.Team, error) {
+	var team *models.Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Roster changes lock the team too, so none lands on the archived team
+		repo := s.repo.WithTx(tx)
+		var err error
+		if team, err = repo.LockTeam(teamID); err != nil {
+			return err
+		}
+		if team.ArchivedAt != nil {
+			return ErrTeamArchived
+		}
+
+		members, err := repo.ArchiveTeam(team, time.Now())
+		if err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventTeamArchived, team.ID, performedBy, uuid.Nil, map[string]string{
+			"teamName":    team.TeamName,
+			"memberCount": strconv.Itoa(len(members)),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateMembers(ctx, team.ID)
+	return team, nil
+}
+
+// RestoreTeam brings an archived team and its roster back
+func (s *TeamService) RestoreTeam(ctx context.Context, teamID int, performedBy uuid.UUID) (*models.Team, error) {
+	var team *models.Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		var err error
+		if team, err = repo.LockTeam(teamID); err != nil {
+			return err
+		}
+		if team.ArchivedAt == nil {
+			return ErrTeamNotArchived
+		}
+
+		members, err := repo.RestoreTeam(team)
+		if err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventTeamRestored, team.ID, performedBy, uuid.Nil, map[string]string{
+			"teamName":    team.TeamName,
+			"memberCount": strconv.Itoa(len(members)),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateMembers(ctx, team.ID)
+	return team, nil
+}
+
+// DeleteTeam removes a team and its rosters for good
+func (s *TeamService) DeleteTeam(ctx context.Context, teamID int, performedBy uuid.UUID) (*models.Team, error) {
+	team, err := s.repo.FindTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Sub-teams move up to the team's parent, which must not move meanwhile
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", teamHierarchyLockID).Error; err != nil {
+			return err
+		}
+		repo := s.repo.WithTx(tx)
+		if team, err = repo.FindTeam(teamID); err != nil {
+			return err
+		}
+
+		members, err := repo.DeleteTeam(team)
+		if err != nil {
+			return err
+		}
+		return recordTeamEvent(tx, kafka.EventTeamDeleted, team.ID, performedBy, uuid.Nil, map[string]string{
+			"teamName":    team.TeamName,
+			"memberCount": strconv.Itoa(len(members)),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateMembers(ctx, team.ID)
+	return team, nil
+}
+
+// invalidateMembers drops the cached roster; a stale cache only costs a DB read, so errors are logged
+func (s *TeamService) invalidateMembers(ctx context.Context, teamID int) {
+	if s.redisClient == nil {
+		return
+	}
+	if err := s.redisClient.InvalidateMembers(ctx, uint64(teamID)); err != nil {
+		log.Printf("Failed to invalidate member cache for team %d: %v", teamID, err)
+	}
+}
+
+// recordTeamEvent stores a team event in tx's outbox
+func recordTeamEvent(tx *gorm.DB, eventType string, teamID int, performedBy, targetUserID uuid.UUID, details map[string]string) error {
+	return AddTeamEvent(tx, kafka.NewTeamEvent(eventType, uint64(teamID), performedBy, targetUserID, details))
+}
+
+// checkMembers applies the member limit, if quotas are enforced
+func (s *TeamService) checkMembers(tx *gorm.DB, teamID, adding int) error {
+	if s.quotas == nil {
+		return nil
+	}
+	return s.quotas.CheckMembers(tx, teamID, adding)
+}
+
+// previewMembers applies the member limit without locking the team
+func (s *TeamService) previewMembers(db *gorm.DB, teamID, adding int) error {
+	if s.quotas == nil {
+		return nil
+	}
+	return s.quotas.PreviewMembers(db, teamID, adding)
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go_service/internal/models"
+	"go_service/internal/testutil"
+
+	"github.com/google/uuid"
+)
+
+// testArchivedTeam returns a team service and an archived team with a member
+func testArchivedTeam(t *testing.T) (*TeamService, *models.Team, uuid.UUID) {
+	t.Helper()
+	db := testutil.DB(t)
+	teams := NewTeamService(db, nil, SuccessionBlock, nil)
+	team := testutil.Team(t, db, models.JoinPolicyInviteOnly)
+	member, manager := uuid.New(), uuid.New()
+	if err := teams.AddMember(nil, team.ID, member, manager); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := teams.ArchiveTeam(context.Background(), team.ID, manager); err != nil {
+		t.Fatal(err)
+	}
+	return teams, team, member
+}
+
+func TestAddMemberToArchivedTeam(t *testing.T) {
+	teams, team, _ := testArchivedTeam(t)
+
+	if err := teams.AddMember(nil, team.ID, uuid.New(), uuid.New()); !errors.Is(err, ErrTeamArchived) {
+		t.Fatalf("AddMember = %v, want %v", err, ErrTeamArchived)
+	}
+}
+
+func TestChangeRoleOnArchivedTeam(t *testing.T) {
+	teams, team, _ := testArchivedTeam(t)
+
+	// The roster is archived, so the member cannot be found or promoted
+	if _, _, err := teams.ChangeRole(team.ID, uuid.New(), models.RosterRoleLeader, uuid.New()); !errors.Is(err, ErrTeamArchived) {
+		t.Fatalf("ChangeRole = %v, want %v", err, ErrTeamArchived)
+	}
+}
+
+func TestRestoreAfterRefusedAdd(t *testing.T) {
+	teams, team, member := testArchivedTeam(t)
+
+	if err := teams.AddMember(nil, team.ID, member, uuid.New()); !errors.Is(err, ErrTeamArchived) {
+		t.Fatalf("AddMember = %v, want %v", err, ErrTeamArchived)
+	}
+	if _, err := teams.RestoreTeam(context.Background(), team.ID, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries int64
+	if err := teams.db.Model(&models.Roster{}).Where("\"teamId\" = ? AND \"userId\" = ?", team.ID, member).Count(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if entries != 1 {
+		t.Errorf("member has %d roster entries after restore, want 1", entries)
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+const defaultUserServiceURL = "http://localhost:4000/users"
+
+// UserDirectory looks up and creates users in the system of record
+type UserDirectory interface {
+	// GetUserByID returns ErrUserNotFound if there is no such user
+	GetUserByID(ctx context.Context, userID string) (*User, error)
+	// GetUsersByIDs returns the users that exist, keyed by ID; unknown IDs are omitted
+	GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]*User, error)
+	CreateUser(ctx context.Context, username, email, password, role string) (*User, error)
+}
+
+// UserDirectoryConfig configures the GraphQL user directory
+type UserDirectoryConfig struct {
+	URL     string
+	Timeout time.Duration
+	// BatchSize caps how many users are fetched in a single request
+	BatchSize int
+}
+
+// UserDirectoryConfigFromEnv reads USER_SERVICE_URL, USER_SERVICE_TIMEOUT and USER_SERVICE_BATCH_SIZE
+func UserDirectoryConfigFromEnv() UserDirectoryConfig {
+	cfg := UserDirectoryConfig{
+		URL:       defaultUserServiceURL,
+		Timeout:   5 * time.Second,
+		BatchSize: 100,
+	}
+
+	if v := os.Getenv("USER_SERVICE_URL"); v != "" {
+		cfg.URL = v
+	}
+	cfg.Timeout = envDuration("USER_SERVICE_TIMEOUT", cfg.Timeout)
+	cfg.BatchSize = envInt("USER_SERVICE_BATCH_SIZE", cfg.BatchSize)
+
+	return cfg
+}
@@ -2,13 +2,21 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/machinebox/graphql"
 )
 
+// ErrUserNotFound is returned when the user service has no such user
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrUserRejected is returned when the user service refuses to create a user, e.g. a duplicate email
+var ErrUserRejected = errors.New("user creation failed")
+
 // User represents a user from the user service
 type User struct {
 	ID       string `json:"userId"`
@@ -30,38 +38,53 @@ type CreateUserResponse struct {
 	User    *User  `json:"user"`
 }
 
-// UserService handles communication with the user service
+// UserService is the UserDirectory backed by the GraphQL user service
 type UserService struct {
-	client  *graphql.Client
-	baseURL string
+	client    *graphql.Client
+	baseURL   string
+	timeout   time.Duration
+	batchSize int
 }
 
+var _ UserDirectory = (*UserService)(nil)
+
 // NewUserService creates a new user service client
-func NewUserService() *UserService {
-	baseURL := "http://localhost:4000/users"
+func NewUserService(cfg UserDirectoryConfig) *UserService {
+	if cfg.URL == "" {
+		cfg.URL = defaultUserServiceURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
 
-	log.Printf("Initializing UserService with URL: %s", baseURL)
-	client := graphql.NewClient(baseURL)
+	log.Printf("Initializing UserService with URL: %s", cfg.URL)
+	client := graphql.NewClient(cfg.URL)
 
 	return &UserService{
-		client:  client,
-		baseURL: baseURL,
+		client:    client,
+		baseURL:   cfg.URL,
+		timeout:   cfg.Timeout,
+		batchSize: cfg.BatchSize,
 	}
 }
 
+const userFields = `
+                userId
+                username
+                email
+                role`
+
 // GetUserByID fetches a user by their ID
-func (s *UserService) GetUserByID(userID string) (*UserResponse, error) {
+func (s *UserService) GetUserByID(ctx context.Context, userID string) (*User, error) {
 	log.Printf("Fetching user with ID: %s", userID)
 
 	// Create GraphQL request
-	//đẩy query sang file const
 	req := graphql.NewRequest(`
         query GetUser($userId: ID!) {
-            user(userId: $userId) {
-                userId
-                username
-                email
-                role
+            user(userId: $userId) {` + userFields + `
             }
         }
     `)
@@ -70,31 +93,92 @@ func (s *UserService) GetUserByID(userID string) (*UserResponse, error) {
 	req.Var("userId", userID)
 
 	// Add timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
 	// Execute request
 	var response UserResponse
-	log.Printf("Sending GraphQL request to %s", s.baseURL)
-
 	if err := s.client.Run(ctx, req, &response); err != nil {
 		log.Printf("GraphQL request failed: %v", err)
 		return nil, fmt.Errorf("failed to fetch user: %w", err)
 	}
 
-	log.Printf("GraphQL response received: %+v", response)
-
 	// Check if user was found
 	if response.User == nil || response.User.ID == "" {
 		log.Printf("User not found with ID: %s", userID)
-		return nil, fmt.Errorf("user not found with ID: %s", userID)
+		return nil, fmt.Errorf("%w with ID: %s", ErrUserNotFound, userID)
+	}
+
+	return response.User, nil
+}
+
+// GetUsersByIDs fetches many users at once. The schema only exposes a
+// single-user field, so each chunk is sent as one query with an aliased
+// user(userId:) selection per ID.
+func (s *UserService) GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]*User, error) {
+	users := make(map[string]*User, len(userIDs))
+
+	// Drop duplicates so each ID is only requested once
+	seen := make(map[string]bool, len(userIDs))
+	unique := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	for start := 0; start < len(unique); start += s.batchSize {
+		end := start + s.batchSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		if err := s.fetchBatch(ctx, unique[start:end], users); err != nil {
+			return nil, err
+		}
+	}
+
+	return users, nil
+}
+
+func (s *UserService) fetchBatch(ctx context.Context, ids []string, users map[string]*User) error {
+	var params, fields strings.Builder
+	for i := range ids {
+		if i > 0 {
+			params.WriteString(", ")
+		}
+		fmt.Fprintf(&params, "$id%d: ID!", i)
+		fmt.Fprintf(&fields, "\n            u%d: user(userId: $id%d) {%s\n            }", i, i, userFields)
+	}
+
+	req := graphql.NewRequest(fmt.Sprintf("query GetUsers(%s) {%s\n        }", params.String(), fields.String()))
+	for i, id := range ids {
+		req.Var(fmt.Sprintf("id%d", i), id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("Fetching %d users in one request", len(ids))
+
+	var response map[string]*User
+	if err := s.client.Run(ctx, req, &response); err != nil {
+		log.Printf("GraphQL batch request failed: %v", err)
+		return fmt.Errorf("failed to fetch users: %w", err)
+	}
+
+	for _, user := range response {
+		if user != nil && user.ID != "" {
+			users[user.ID] = user
+		}
 	}
 
-	return &response, nil
+	return nil
 }
 
 // CreateUser creates a new user using GraphQL mutation
-func (s *UserService) CreateUser(username, email, password, role string) (*UserResponse, error) {
+func (s *UserService) CreateUser(ctx context.Context, username, email, password, role string) (*User, error) {
 	// Create GraphQL request
 	req := graphql.NewRequest(`
         mutation CreateUser($input: CreateUserInput!) {
@@ -102,11 +186,7 @@ func (s *UserService) CreateUser(username, email, password, role string) (*UserR
                 code
                 success
                 message
-                user {
-                    userId
-                    username
-                    email
-                    role
+                user {` + userFields + `
                 }
             }
         }
@@ -121,7 +201,7 @@ func (s *UserService) CreateUser(username, email, password, role string) (*UserR
 	})
 
 	// Add timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
 	// Execute request
@@ -137,11 +217,8 @@ func (s *UserService) CreateUser(username, email, password, role string) (*UserR
 	// Check if user was created successfully
 	if !response.CreateUser.Success {
 		log.Printf("Failed to create user: %s", response.CreateUser.Message)
-		return nil, fmt.Errorf("user creation failed: %s", response.CreateUser.Message)
+		return nil, fmt.Errorf("%w: %s", ErrUserRejected, response.CreateUser.Message)
 	}
 
-	// Create UserResponse from the createUser response
-	return &UserResponse{
-		User: response.CreateUser.User,
-	}, nil
+	return response.CreateUser.User, nil
 }
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestGetUsersByIDsSingleRequest checks that a batch is one aliased query and
+// that unknown IDs are left out of the result
+func TestGetUsersByIDsSingleRequest(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		var body struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if strings.Count(body.Query, "user(userId:") != len(body.Variables) {
+			t.Errorf("expected one aliased field per variable, query: %s", body.Query)
+		}
+
+		data := map[string]interface{}{}
+		for name, id := range body.Variables {
+			alias := "u" + strings.TrimPrefix(name, "id")
+			if id == "missing" {
+				data[alias] = nil
+				continue
+			}
+			data[alias] = map[string]string{"userId": id, "username": "name-" + id}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer srv.Close()
+
+	users := NewUserService(UserDirectoryConfig{URL: srv.URL, Timeout: time.Second, BatchSize: 10})
+	got, err := users.GetUsersByIDs(context.Background(), []string{"a", "b", "missing", "a"})
+	if err != nil {
+		t.Fatalf("GetUsersByIDs: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+	if len(got) != 2 || got["a"].Username != "name-a" || got["b"].Username != "name-b" {
+		t.Errorf("unexpected users: %+v", got)
+	}
+}
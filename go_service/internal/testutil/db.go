@@ -0,0 +1,61 @@
+// Package testutil holds fixtures shared by the database-backed tests.
+package testutil
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"go_service/internal/database"
+	"go_service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	dbOnce sync.Once
+	dbConn *gorm.DB
+	dbErr  error
+)
+
+// Conn returns the connection to the Postgres database named by
+// TEST_DATABASE_URL, migrating its schema on first use. The test is skipped
+// when the variable is not set: run `make test-db` to start a database and
+// run every test against it. Prefer DB, which leaves nothing behind.
+func Conn(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	dbOnce.Do(func() {
+		dbConn, dbErr = database.Connect(dsn)
+	})
+	if dbErr != nil {
+		t.Fatalf("connect: %v", dbErr)
+	}
+	return dbConn
+}
+
+// DB returns a transaction on the test database, rolled back when the test
+// ends; see Conn
+func DB(t *testing.T) *gorm.DB {
+	t.Helper()
+	tx := Conn(t).Begin()
+	if tx.Error != nil {
+		t.Fatalf("begin: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+// Team creates an empty team with a unique name and the given join policy
+func Team(t *testing.T, db *gorm.DB, policy models.JoinPolicy) *models.Team {
+	t.Helper()
+	team := &models.Team{TeamName: "test-" + uuid.NewString(), JoinPolicy: policy}
+	if err := db.Create(team).Error; err != nil {
+		t.Fatalf("create team: %v", err)
+	}
+	return team
+}